@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// account one account to collect from, by assuming RoleARN when set
+type account struct {
+	Name       string `json:"name"`
+	RoleARN    string `json:"role_arn"`
+	ExternalID string `json:"external_id,omitempty"`
+}
+
+// accountsFile what --accounts reads
+type accountsFile struct {
+	Accounts []*account `json:"accounts"`
+}
+
+// targetAccounts accounts listed in --accounts and given by --assume-role,
+// as "arn" or "name=arn". Without either, only the account of the
+// credentials in use.
+func targetAccounts(c *cli.Context) ([]*account, error) {
+	accounts := make([]*account, 0)
+	if path := c.GlobalString("accounts"); path != "" {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		f := &accountsFile{}
+		if err := json.Unmarshal(b, f); err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		accounts = append(accounts, f.Accounts...)
+	}
+	for _, v := range c.GlobalStringSlice("assume-role") {
+		a := &account{RoleARN: v}
+		if i := strings.Index(v, "="); i > 0 && !strings.HasPrefix(v, "arn:") {
+			a.Name, a.RoleARN = v[:i], v[i+1:]
+		}
+		accounts = append(accounts, a)
+	}
+	if len(accounts) == 0 {
+		return []*account{&account{}}, nil
+	}
+	names := make(map[string]bool)
+	for _, a := range accounts {
+		if a.RoleARN == "" {
+			return nil, fmt.Errorf("account %q has no role_arn", a.Name)
+		}
+		if a.Name == "" {
+			a.Name = accountIDFromARN(a.RoleARN)
+		}
+		if names[a.Name] {
+			return nil, fmt.Errorf("account %q is given twice", a.Name)
+		}
+		names[a.Name] = true
+	}
+	return accounts, nil
+}
+
+// accountIDFromARN account ID in arn:partition:service:region:account:resource
+func accountIDFromARN(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 6 {
+		return arn
+	}
+	return parts[4]
+}
@@ -0,0 +1,321 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/atsushi-ishibashi/aws-state-report/util"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/tealeg/xlsx"
+	"github.com/urfave/cli"
+)
+
+func NewAllCommand() cli.Command {
+	return cli.Command{
+		Name:  "all",
+		Usage: "export network, security groups and iam into one workbook with an index sheet",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "src",
+				Usage: "file name to export",
+				Value: "all",
+			},
+			cli.StringFlag{
+				Name:  "output",
+				Usage: "path to export to, directories created as needed. overrides --src",
+			},
+			cli.BoolFlag{
+				Name:  "parallel",
+				Usage: "run the network, sg and iam collectors at the same time",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if format := formatName(c); format != "xlsx" || c.GlobalString("template") != "" {
+				return util.ErrorRed("all writes xlsx only, drop --format and --template")
+			}
+			name := outputName(c)
+			targets, err := newTargets(c, true)
+			if err != nil {
+				return util.ErrorRed(err.Error())
+			}
+			groups, err := groupTargets(c, targets, name, "xlsx")
+			if err != nil {
+				return util.ErrorRed(err.Error())
+			}
+			reports := make([]*allReport, 0, len(groups))
+			for _, g := range groups {
+				r := newAllReport()
+				r.collect(g.targets, c.Bool("parallel"))
+				reports = append(reports, r)
+			}
+			printPageStats(targets)
+			errs := make([]error, 0)
+			for _, r := range reports {
+				if err := r.flattenErrs(); err != nil && !continueOnError(c) {
+					return util.ErrorRed(err.Error())
+				}
+				errs = append(errs, r.errs()...)
+			}
+			if err := saveSnapshot(c, targets); err != nil {
+				return util.ErrorRed(err.Error())
+			}
+			for i, r := range reports {
+				if err := r.convertXlsx(groups[i].filename(name)); err != nil {
+					return util.ErrorRed(err.Error())
+				}
+			}
+			return partialReportError(errs)
+		},
+	}
+}
+
+// allReport network, sg and iam reports of the same targets, and who the
+// accounts are
+type allReport struct {
+	network  *Network
+	sg       *SG
+	iam      *IAM
+	accounts []*accountInfo
+	Errs     []error
+}
+
+// accountInfo an account of the report. Name is the name given in
+// --accounts, empty for the account of the profile.
+type accountInfo struct {
+	Name  string
+	ID    string
+	Alias string
+}
+
+func newAllReport() *allReport {
+	return &allReport{
+		network:  &Network{Errs: make([]error, 0)},
+		sg:       &SG{Errs: make([]error, 0)},
+		iam:      &IAM{Errs: make([]error, 0)},
+		accounts: make([]*accountInfo, 0),
+		Errs:     make([]error, 0),
+	}
+}
+
+// collect runs every collector over targets, one after another or, with
+// concurrent, at the same time. IAM and the account are collected once per
+// account.
+func (r *allReport) collect(targets []*target, concurrent bool) {
+	perAccount := accountTargets(targets)
+	collectors := []func(){
+		func() {
+			for _, t := range targets {
+				r.network.collect(t)
+			}
+		},
+		func() {
+			for _, t := range targets {
+				r.sg.collect(t)
+			}
+		},
+		func() {
+			for _, t := range perAccount {
+				r.iam.collect(t)
+			}
+		},
+		func() {
+			for _, t := range perAccount {
+				r.collectAccount(t)
+			}
+		},
+	}
+	workers := 1
+	if concurrent {
+		workers = len(collectors)
+	}
+	parallel(workers, len(collectors), func(i int) {
+		collectors[i]()
+	})
+}
+
+// accountTargets the first target of every account
+func accountTargets(targets []*target) []*target {
+	result := make([]*target, 0)
+	seen := make(map[string]bool)
+	for _, t := range targets {
+		if !seen[t.Account] {
+			seen[t.Account] = true
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+func (r *allReport) collectAccount(t *target) {
+	a := &accountInfo{Name: t.Account}
+	if result, err := t.manager.FetchCallerIdentity(); err != nil {
+		r.Errs = append(r.Errs, accountError(t, newFetchError("GetCallerIdentity", "", err)))
+	} else {
+		a.ID = aws.StringValue(result.Account)
+	}
+	if result, err := t.manager.FetchAccountAliases(); err != nil {
+		r.Errs = append(r.Errs, accountError(t, newFetchError("ListAccountAliases", "", err)))
+	} else if len(result.AccountAliases) > 0 {
+		a.Alias = aws.StringValue(result.AccountAliases[0])
+	}
+	r.accounts = append(r.accounts, a)
+}
+
+// errs what failed in any of the collectors
+func (r *allReport) errs() []error {
+	errs := make([]error, 0)
+	errs = append(errs, r.network.Errs...)
+	errs = append(errs, r.sg.Errs...)
+	errs = append(errs, r.iam.Errs...)
+	return append(errs, r.Errs...)
+}
+
+func (r *allReport) flattenErrs() error {
+	return flattenErrors(r.errs())
+}
+
+// account what is known of the account named name
+func (r *allReport) account(name string) *accountInfo {
+	for _, a := range r.accounts {
+		if a.Name == name {
+			return a
+		}
+	}
+	return &accountInfo{Name: name}
+}
+
+// convertXlsx writes one workbook: the index sheet, then the sheets of the
+// network, sg and iam reports, with interfaces linking to their subnets
+func (r *allReport) convertXlsx(filename string) error {
+	file := xlsx.NewFile()
+	if err := r.addXlsxSheets(file); err != nil {
+		return err
+	}
+	path, err := createPath(filename, "xlsx")
+	if err != nil {
+		return err
+	}
+	return file.Save(path)
+}
+
+// addXlsxSheets adds the index sheet and the sheets of every report to file
+func (r *allReport) addXlsxSheets(file *xlsx.File) error {
+	index, err := addSheet(file, "index")
+	if err != nil {
+		return err
+	}
+	networkSheets, subnets := r.network.addXlsxSheets(file)
+	sgSheets := r.sg.addXlsxSheets(file, subnets)
+	iamSheets := r.iam.addXlsxSheets(file)
+	errs := r.errs()
+	errSheet := addErrorsSheet(file, errs)
+	r.writeIndex(index, networkSheets, sgSheets, iamSheets, errSheet, len(errs))
+	return nil
+}
+
+// allCounts resources collected in one account and region
+type allCounts struct {
+	vpcs, routeTables, subnets            int
+	securityGroups, interfaces, instances int
+}
+
+func (r *allReport) counts() ([]scope, map[scope]*allCounts) {
+	scopes := make([]scope, 0)
+	counts := make(map[scope]*allCounts)
+	get := func(s scope) *allCounts {
+		if _, ok := counts[s]; !ok {
+			scopes = append(scopes, s)
+			counts[s] = &allCounts{}
+		}
+		return counts[s]
+	}
+	for _, v := range r.network.Vpcs {
+		n := get(scope{Account: v.Account, Region: v.Region})
+		n.vpcs++
+		n.routeTables += len(v.RouteTables)
+		n.subnets += len(v.Subnets)
+	}
+	interfaces := make(map[string]bool)
+	instances := make(map[string]bool)
+	for _, v := range r.sg.SecurityGroups {
+		n := get(scope{Account: v.Account, Region: v.Region})
+		n.securityGroups++
+		for _, ni := range v.NetworkInterfaces {
+			if !interfaces[ni.ID] {
+				interfaces[ni.ID] = true
+				n.interfaces++
+			}
+			if ni.Ec2Instance != nil && !instances[ni.Ec2Instance.ID] {
+				instances[ni.Ec2Instance.ID] = true
+				n.instances++
+			}
+		}
+	}
+	return scopes, counts
+}
+
+// indexSection sheets of one report, listed in the index
+type indexSection struct {
+	title  string
+	sheets []string
+}
+
+// writeIndex fills the index sheet: when the report was made, what was
+// collected per account and region, and links to every other sheet
+func (r *allReport) writeIndex(sheet *xlsx.Sheet, networkSheets, sgSheets, iamSheets []string, errSheet string, errs int) {
+	row := 0
+	writeRow := func(header bool, values ...string) {
+		for i, v := range values {
+			sheet.Cell(row, i).Value = v
+			sheet.Cell(row, i).SetStyle(borderWithAlign("lrtb", header))
+		}
+		row++
+	}
+	writeRow(true, "aws-state-report")
+	writeRow(false, "Generated", time.Now().UTC().Format(time.RFC3339))
+	row++
+
+	writeRow(true, "Account", "Account ID", "Alias", "Region", "VPCs", "Route Tables", "Subnets", "Security Groups", "Network Interfaces", "Instances")
+	scopes, counts := r.counts()
+	for _, s := range scopes {
+		a, n := r.account(s.Account), counts[s]
+		writeRow(false, a.Name, a.ID, a.Alias, s.Region,
+			fmt.Sprint(n.vpcs), fmt.Sprint(n.routeTables), fmt.Sprint(n.subnets),
+			fmt.Sprint(n.securityGroups), fmt.Sprint(n.interfaces), fmt.Sprint(n.instances))
+	}
+	row++
+
+	writeRow(true, "Account", "Account ID", "Alias", "Users", "Groups", "Roles", "Policies")
+	iamParts := make(map[string]*IAM)
+	for _, p := range r.iam.byAccount() {
+		iamParts[p.account()] = p
+	}
+	for _, a := range r.accounts {
+		p, ok := iamParts[a.Name]
+		if !ok {
+			p = &IAM{}
+		}
+		writeRow(false, a.Name, a.ID, a.Alias,
+			fmt.Sprint(len(p.Users)), fmt.Sprint(len(p.Groups)), fmt.Sprint(len(p.Roles)), fmt.Sprint(len(p.Policies)))
+	}
+	row++
+
+	sections := []*indexSection{
+		{title: "Network", sheets: networkSheets},
+		{title: "Security Groups", sheets: sgSheets},
+		{title: "IAM", sheets: iamSheets},
+	}
+	if errSheet != "" {
+		sections = append(sections, &indexSection{title: fmt.Sprintf("Errors (%d)", errs), sheets: []string{errSheet}})
+	}
+	for _, sec := range sections {
+		writeRow(true, sec.title)
+		for _, name := range sec.sheets {
+			sheet.Cell(row, 0).SetFormula(hyperlink(name, 0, 0, name))
+			sheet.Cell(row, 0).SetStyle(borderWithAlign("lr", false))
+			row++
+		}
+		sheet.Cell(row, 0).SetStyle(borderWithAlign("t", false))
+		row++
+	}
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/tealeg/xlsx"
+)
+
+func TestAllXlsxSheetsClashingNames(t *testing.T) {
+	r := newAllReport()
+	r.network = collectFixtureNetwork(t)
+	r.sg = collectFixtureSG(t)
+	r.iam = collectFixtureIAM(t)
+	r.accounts = append(r.accounts, &accountInfo{})
+	vpc := r.network.Vpcs[0]
+	r.network.Vpcs = nil
+	for i, name := range []string{"index", "user", "Errors"} {
+		v := *vpc
+		v.ID = fmt.Sprintf("vpc-%d", i)
+		v.TagName = name
+		r.network.Vpcs = append(r.network.Vpcs, &v)
+	}
+	r.network.stackError(errors.New("failed"))
+	file := xlsx.NewFile()
+	if err := r.addXlsxSheets(file); err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(file.Sheets))
+	for _, s := range file.Sheets {
+		names = append(names, s.Name)
+	}
+	want := "index,index~2,user,Errors,Connectivity,instance,networkinterface,security-group,policy,group,user~2,role,errors~2"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("sheets = %s, want %s", got, want)
+	}
+	linked := regexp.MustCompile(`#'((?:[^']|'')*)'!`)
+	links := make([]string, 0)
+	for row := 0; row < 100; row++ {
+		if m := linked.FindStringSubmatch(file.Sheet["index"].Cell(row, 0).Formula()); m != nil {
+			links = append(links, strings.Replace(m[1], "''", "'", -1))
+		}
+	}
+	if got := strings.Join(links, ","); got != strings.Join(names[1:], ",") {
+		t.Errorf("index links = %s, want %s", got, strings.Join(names[1:], ","))
+	}
+}
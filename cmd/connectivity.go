@@ -0,0 +1,869 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/tealeg/xlsx"
+)
+
+// constructPeeringConnections describes the peering connections of the
+// region, whichever side of them its VPCs are on
+func (nt *Network) constructPeeringConnections() *Network {
+	result, err := nt.manager.FetchVpcPeeringConnections()
+	if err != nil {
+		return nt.stackError(newFetchError("DescribeVpcPeeringConnections", "", err))
+	}
+	nt.PeeringConnections = parseDescribeVpcPeeringConnectionsOutput(result)
+	return nt
+}
+
+// constructTransitGateways describes the transit gateway attachments of the
+// region and, for the transit gateways the account owns, their route tables
+// and routes. A route table with more routes than one search returns keeps
+// those it got, with an error saying the rest are missing.
+func (nt *Network) constructTransitGateways() *Network {
+	result, err := nt.manager.FetchTransitGatewayAttachments()
+	if err != nil {
+		return nt.stackError(newFetchError("DescribeTransitGatewayAttachments", "", err))
+	}
+	nt.TransitGateways = parseDescribeTransitGatewayAttachmentsOutput(result)
+	rtResult, err := nt.manager.FetchTransitGatewayRouteTables()
+	if err != nil {
+		return nt.stackError(newFetchError("DescribeTransitGatewayRouteTables", "", err))
+	}
+	byTgw := parseDescribeTransitGatewayRouteTablesOutput(rtResult)
+	tgwIDs := make([]string, 0, len(byTgw))
+	for tgwID := range byTgw {
+		tgwIDs = append(tgwIDs, tgwID)
+	}
+	sort.Strings(tgwIDs)
+	rts := make([]*TransitGatewayRouteTable, 0)
+	for _, tgwID := range tgwIDs {
+		tgwRts := byTgw[tgwID]
+		tgw := nt.transitGateway(tgwID)
+		if tgw == nil {
+			tgw = &TransitGateway{ID: tgwID, Attachments: make([]*TransitGatewayAttachment, 0)}
+			nt.TransitGateways = append(nt.TransitGateways, tgw)
+		}
+		tgw.RouteTables = tgwRts
+		rts = append(rts, tgwRts...)
+	}
+	errs := make([]error, len(rts))
+	parallel(nt.workers, len(rts), func(i int) {
+		rt := rts[i]
+		result, err := nt.manager.FetchTransitGatewayRoutes(rt.ID)
+		if err != nil {
+			errs[i] = newFetchError("SearchTransitGatewayRoutes", rt.ID, err)
+			return
+		}
+		rt.Routes = parseSearchTransitGatewayRoutesOutput(result)
+		// SearchTransitGatewayRoutes does not page, the routes past the
+		// first search are missing
+		if aws.BoolValue(result.AdditionalRoutesAvailable) {
+			errs[i] = newFetchError("SearchTransitGatewayRoutes", rt.ID, fmt.Errorf("more routes than the %d listed", len(rt.Routes)))
+		}
+	})
+	return nt.stackErrors(errs)
+}
+
+func (nt *Network) transitGateway(id string) *TransitGateway {
+	for _, tgw := range nt.TransitGateways {
+		if tgw.ID == id {
+			return tgw
+		}
+	}
+	return nil
+}
+
+// addPeeringConnection adds pcx unless it was collected already, from the
+// account or region on its other side
+func (nt *Network) addPeeringConnection(pcx *PeeringConnection) {
+	for _, v := range nt.PeeringConnections {
+		if v.ID == pcx.ID {
+			return
+		}
+	}
+	nt.PeeringConnections = append(nt.PeeringConnections, pcx)
+}
+
+// addTransitGateway adds tgw, or merges it into the one of the same ID
+// collected from another account: attachments are seen by their own
+// account, route tables by the owner of the transit gateway
+func (nt *Network) addTransitGateway(tgw *TransitGateway) {
+	merged := nt.transitGateway(tgw.ID)
+	if merged == nil {
+		nt.TransitGateways = append(nt.TransitGateways, tgw)
+		return
+	}
+	if merged.OwnerID == "" {
+		merged.OwnerID = tgw.OwnerID
+	}
+	for _, at := range tgw.Attachments {
+		if merged.attachment(at.ID) == nil {
+			merged.Attachments = append(merged.Attachments, at)
+		}
+	}
+	if len(merged.RouteTables) == 0 {
+		merged.RouteTables = tgw.RouteTables
+	}
+}
+
+func (tgw *TransitGateway) attachment(id string) *TransitGatewayAttachment {
+	for _, at := range tgw.Attachments {
+		if at.ID == id {
+			return at
+		}
+	}
+	return nil
+}
+
+func (tgw *TransitGateway) routeTable(id string) *TransitGatewayRouteTable {
+	for _, rt := range tgw.RouteTables {
+		if rt.ID == id {
+			return rt
+		}
+	}
+	return nil
+}
+
+// AttachmentResources resource IDs of the attachments of r, the attachment
+// IDs for those not collected
+func (tgw *TransitGateway) AttachmentResources(r *TransitGatewayRoute) []string {
+	resources := make([]string, 0, len(r.AttachmentIDs))
+	for _, id := range r.AttachmentIDs {
+		if at := tgw.attachment(id); at != nil && at.ResourceID != "" {
+			resources = append(resources, at.ResourceID)
+		} else {
+			resources = append(resources, id)
+		}
+	}
+	return resources
+}
+
+// deadPeeringStatus statuses of a peering connection that carries no traffic
+// and never will
+var deadPeeringStatus = map[string]bool{
+	"deleted":  true,
+	"deleting": true,
+	"rejected": true,
+	"failed":   true,
+	"expired":  true,
+}
+
+// deadAttachmentState states of a transit gateway attachment that carries no
+// traffic and never will
+var deadAttachmentState = map[string]bool{
+	"deleted":  true,
+	"deleting": true,
+	"rejected": true,
+	"failed":   true,
+}
+
+// connectivityVpc a VPC of the connectivity matrix, collected or only known
+// as the other side of a connection
+type connectivityVpc struct {
+	ID      string
+	Name    string
+	Account string
+	Region  string
+	// Collected whether the VPC has a section of its own
+	Collected bool
+}
+
+// Label name or ID of the VPC, with its account and region when it was not
+// collected
+func (cv *connectivityVpc) Label() string {
+	name := cv.Name
+	if name == "" {
+		name = cv.ID
+	}
+	if cv.Collected {
+		return name
+	}
+	if loc := location(cv.Account, cv.Region); loc != "" {
+		return fmt.Sprintf("%s (%s)", name, loc)
+	}
+	return name
+}
+
+// connection one way traffic goes from the VPC From to the VPC To
+type connection struct {
+	From string
+	To   string
+	// Via peering connection or transit gateway ID
+	Via string
+	// Type routerPeeringConnection or routerTransitGateway
+	Type string
+	// RouteTableID transit gateway route table routing the traffic, empty
+	// when the route tables of the transit gateway were not collected
+	RouteTableID string
+	// Status of a peering connection
+	Status string
+	// Anchor what links to the connection go to
+	Anchor string
+}
+
+// Text Via, with what routes it or the status of a peering connection that
+// is not active yet
+func (c *connection) Text() string {
+	switch {
+	case c.Type == routerTransitGateway && c.RouteTableID != "":
+		return fmt.Sprintf("%s via %s", c.Via, c.RouteTableID)
+	case c.Type == routerTransitGateway:
+		return c.Via + " (routes unknown)"
+	case c.Status != "active":
+		return fmt.Sprintf("%s (%s)", c.Via, c.Status)
+	}
+	return c.Via
+}
+
+// connectivity the VPCs and the connections between them, through peering
+// connections and transit gateways
+type connectivity struct {
+	Vpcs        []*connectivityVpc
+	Connections []*connection
+}
+
+// Between connections from the VPC from to the VPC to
+func (c *connectivity) Between(from, to string) []*connection {
+	conns := make([]*connection, 0)
+	for _, v := range c.Connections {
+		if v.From == from && v.To == to {
+			conns = append(conns, v)
+		}
+	}
+	return conns
+}
+
+func (c *connectivity) addVpc(cv *connectivityVpc) {
+	for _, v := range c.Vpcs {
+		if v.ID == cv.ID {
+			return
+		}
+	}
+	c.Vpcs = append(c.Vpcs, cv)
+}
+
+func (c *connectivity) addConnection(conn *connection) {
+	for _, v := range c.Connections {
+		if *v == *conn {
+			return
+		}
+	}
+	c.Connections = append(c.Connections, conn)
+}
+
+// Connectivity how the VPCs reach each other. A peering connection connects
+// its two VPCs both ways. A transit gateway connects a VPC to another when
+// the route table associated with the attachment of the first has an active
+// route to the attachment of the other, or both ways when its route tables
+// were not collected.
+func (nt *Network) Connectivity() *connectivity {
+	c := &connectivity{
+		Vpcs:        make([]*connectivityVpc, 0),
+		Connections: make([]*connection, 0),
+	}
+	for _, v := range nt.Vpcs {
+		c.addVpc(&connectivityVpc{ID: v.ID, Name: v.TagName, Account: v.Account, Region: v.Region, Collected: true})
+	}
+	for _, pcx := range nt.PeeringConnections {
+		if deadPeeringStatus[pcx.Status] {
+			continue
+		}
+		for _, side := range []*PeeringVpc{pcx.Requester, pcx.Accepter} {
+			c.addVpc(&connectivityVpc{ID: side.VpcID, Account: side.Account, Region: side.Region})
+		}
+		for _, ends := range [][2]*PeeringVpc{{pcx.Requester, pcx.Accepter}, {pcx.Accepter, pcx.Requester}} {
+			c.addConnection(&connection{
+				From:   ends[0].VpcID,
+				To:     ends[1].VpcID,
+				Via:    pcx.ID,
+				Type:   routerPeeringConnection,
+				Status: pcx.Status,
+				Anchor: anchor("pcx", pcx.Account, pcx.Region, pcx.ID),
+			})
+		}
+	}
+	for _, tgw := range nt.TransitGateways {
+		ats := make([]*TransitGatewayAttachment, 0)
+		for _, at := range tgw.Attachments {
+			if at.ResourceType == "vpc" && !deadAttachmentState[at.State] {
+				ats = append(ats, at)
+				c.addVpc(&connectivityVpc{ID: at.ResourceID, Account: at.ResourceOwner, Region: tgw.Region})
+			}
+		}
+		for _, from := range ats {
+			for _, to := range ats {
+				if from.ResourceID == to.ResourceID {
+					continue
+				}
+				conn := &connection{
+					From:   from.ResourceID,
+					To:     to.ResourceID,
+					Via:    tgw.ID,
+					Type:   routerTransitGateway,
+					Anchor: anchor("tgw", tgw.Account, tgw.Region, tgw.ID),
+				}
+				if len(tgw.RouteTables) == 0 {
+					c.addConnection(conn)
+					continue
+				}
+				rt := tgw.routeTable(from.RouteTableID)
+				if rt == nil || !rt.routesTo(to.ID) {
+					continue
+				}
+				conn.RouteTableID = rt.ID
+				c.addConnection(conn)
+			}
+		}
+	}
+	return c
+}
+
+// routesTo whether rt has an active route to the attachment id
+func (rt *TransitGatewayRouteTable) routesTo(id string) bool {
+	for _, r := range rt.Routes {
+		if r.State != "active" {
+			continue
+		}
+		for _, v := range r.AttachmentIDs {
+			if v == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func parseDescribeVpcPeeringConnectionsOutput(output *ec2.DescribeVpcPeeringConnectionsOutput) []*PeeringConnection {
+	pcxs := make([]*PeeringConnection, 0)
+	for _, v := range output.VpcPeeringConnections {
+		pcx := &PeeringConnection{
+			ID:        aws.StringValue(v.VpcPeeringConnectionId),
+			TagName:   extractTagName(v.Tags),
+			Requester: parsePeeringVpc(v.RequesterVpcInfo),
+			Accepter:  parsePeeringVpc(v.AccepterVpcInfo),
+		}
+		if v.Status != nil {
+			pcx.Status = aws.StringValue(v.Status.Code)
+			pcx.StatusMessage = aws.StringValue(v.Status.Message)
+		}
+		pcxs = append(pcxs, pcx)
+	}
+	return pcxs
+}
+
+func parsePeeringVpc(info *ec2.VpcPeeringConnectionVpcInfo) *PeeringVpc {
+	pv := &PeeringVpc{CidrBlocks: make([]string, 0)}
+	if info == nil {
+		return pv
+	}
+	pv.VpcID = aws.StringValue(info.VpcId)
+	pv.Account = aws.StringValue(info.OwnerId)
+	pv.Region = aws.StringValue(info.Region)
+	for _, cb := range info.CidrBlockSet {
+		pv.CidrBlocks = append(pv.CidrBlocks, aws.StringValue(cb.CidrBlock))
+	}
+	if len(pv.CidrBlocks) == 0 && info.CidrBlock != nil {
+		pv.CidrBlocks = append(pv.CidrBlocks, aws.StringValue(info.CidrBlock))
+	}
+	for _, cb := range info.Ipv6CidrBlockSet {
+		pv.CidrBlocks = append(pv.CidrBlocks, aws.StringValue(cb.Ipv6CidrBlock))
+	}
+	return pv
+}
+
+// parseDescribeTransitGatewayAttachmentsOutput transit gateways with their
+// attachments, in the order first seen. Deleted attachments are left out.
+func parseDescribeTransitGatewayAttachmentsOutput(output *ec2.DescribeTransitGatewayAttachmentsOutput) []*TransitGateway {
+	tgws := make([]*TransitGateway, 0)
+	byID := make(map[string]*TransitGateway)
+	for _, v := range output.TransitGatewayAttachments {
+		if aws.StringValue(v.State) == "deleted" {
+			continue
+		}
+		tgwID := aws.StringValue(v.TransitGatewayId)
+		tgw, ok := byID[tgwID]
+		if !ok {
+			tgw = &TransitGateway{
+				ID:          tgwID,
+				OwnerID:     aws.StringValue(v.TransitGatewayOwnerId),
+				Attachments: make([]*TransitGatewayAttachment, 0),
+			}
+			byID[tgwID] = tgw
+			tgws = append(tgws, tgw)
+		}
+		at := &TransitGatewayAttachment{
+			ID:            aws.StringValue(v.TransitGatewayAttachmentId),
+			TagName:       extractTagName(v.Tags),
+			ResourceType:  aws.StringValue(v.ResourceType),
+			ResourceID:    aws.StringValue(v.ResourceId),
+			ResourceOwner: aws.StringValue(v.ResourceOwnerId),
+			State:         aws.StringValue(v.State),
+		}
+		if v.Association != nil && aws.StringValue(v.Association.State) == "associated" {
+			at.RouteTableID = aws.StringValue(v.Association.TransitGatewayRouteTableId)
+		}
+		tgw.Attachments = append(tgw.Attachments, at)
+	}
+	return tgws
+}
+
+// parseDescribeTransitGatewayRouteTablesOutput route tables by transit
+// gateway ID. Deleted ones are left out.
+func parseDescribeTransitGatewayRouteTablesOutput(output *ec2.DescribeTransitGatewayRouteTablesOutput) map[string][]*TransitGatewayRouteTable {
+	rts := make(map[string][]*TransitGatewayRouteTable)
+	for _, v := range output.TransitGatewayRouteTables {
+		if aws.StringValue(v.State) == "deleted" {
+			continue
+		}
+		tgwID := aws.StringValue(v.TransitGatewayId)
+		rts[tgwID] = append(rts[tgwID], &TransitGatewayRouteTable{
+			ID:                 aws.StringValue(v.TransitGatewayRouteTableId),
+			TagName:            extractTagName(v.Tags),
+			DefaultAssociation: aws.BoolValue(v.DefaultAssociationRouteTable),
+			DefaultPropagation: aws.BoolValue(v.DefaultPropagationRouteTable),
+			Routes:             make([]*TransitGatewayRoute, 0),
+		})
+	}
+	return rts
+}
+
+func parseSearchTransitGatewayRoutesOutput(output *ec2.SearchTransitGatewayRoutesOutput) []*TransitGatewayRoute {
+	routes := make([]*TransitGatewayRoute, 0)
+	for _, v := range output.Routes {
+		r := &TransitGatewayRoute{
+			Destination:   aws.StringValue(v.DestinationCidrBlock),
+			Type:          aws.StringValue(v.Type),
+			State:         aws.StringValue(v.State),
+			AttachmentIDs: make([]string, 0),
+		}
+		if r.Destination == "" {
+			r.Destination = aws.StringValue(v.PrefixListId)
+		}
+		for _, at := range v.TransitGatewayAttachments {
+			r.AttachmentIDs = append(r.AttachmentIDs, aws.StringValue(at.TransitGatewayAttachmentId))
+		}
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+// HasConnection whether id is one of the peering connections or transit
+// gateways collected, which routes to it link to
+func (nt *Network) HasConnection(id string) bool {
+	for _, pcx := range nt.PeeringConnections {
+		if pcx.ID == id {
+			return true
+		}
+	}
+	return nt.transitGateway(id) != nil
+}
+
+// ConnectionAnchor anchor of the peering connection or transit gateway id
+func (nt *Network) ConnectionAnchor(id string) string {
+	for _, pcx := range nt.PeeringConnections {
+		if pcx.ID == id {
+			return anchor("pcx", pcx.Account, pcx.Region, pcx.ID)
+		}
+	}
+	if tgw := nt.transitGateway(id); tgw != nil {
+		return anchor("tgw", tgw.Account, tgw.Region, tgw.ID)
+	}
+	return ""
+}
+
+// Label VPC ID, account and region of one side of a peering connection,
+// with its CIDRs on the next line
+func (pv *PeeringVpc) Label() string {
+	label := pv.VpcID
+	if loc := location(pv.Account, pv.Region); loc != "" {
+		label += fmt.Sprintf(" (%s)", loc)
+	}
+	return label + "\n" + strings.Join(pv.CidrBlocks, ", ")
+}
+
+// Label names of the VPC and the route table of the route
+func (ru *RouteUse) Label() string {
+	vpcName := ru.Vpc.TagName
+	if vpcName == "" {
+		vpcName = ru.Vpc.ID
+	}
+	rtName := ru.RouteTable.TagName
+	if rtName == "" {
+		rtName = ru.RouteTable.ID
+	}
+	return fmt.Sprintf("%s / %s", vpcName, rtName)
+}
+
+// Anchor anchor of the route table of the route
+func (ru *RouteUse) Anchor() string {
+	return anchor("rtb", ru.Vpc.Account, ru.Vpc.Region, ru.RouteTable.ID)
+}
+
+// connectivityTable a table of the connectivity section, the same in every
+// format
+type connectivityTable struct {
+	Title string
+	// Anchor what links to the table go to, if anything does
+	Anchor string
+	Header []string
+	Rows   []*connectivityRow
+}
+
+type connectivityRow struct {
+	// Anchor what links to the row go to, if anything does
+	Anchor string
+	Cells  []*connectivityCell
+}
+
+// connectivityCell text of a cell, linking to the anchor Link if not empty
+type connectivityCell struct {
+	Text string
+	Link string
+}
+
+// connectivityTables the connectivity matrix between the VPCs, the peering
+// connections and the transit gateways, with the route tables routing to
+// each. Nil when there are no peering connections or transit gateways.
+func (nt *Network) connectivityTables() []*connectivityTable {
+	if len(nt.PeeringConnections) == 0 && len(nt.TransitGateways) == 0 {
+		return nil
+	}
+	c := nt.Connectivity()
+	matrix := &connectivityTable{Title: "Connectivity", Header: []string{"From \\ To"}}
+	for _, to := range c.Vpcs {
+		matrix.Header = append(matrix.Header, to.Label())
+	}
+	for _, from := range c.Vpcs {
+		head := &connectivityCell{Text: from.Label()}
+		if from.Collected {
+			head.Link = anchor("vpc", from.Account, from.Region, from.ID)
+		}
+		row := &connectivityRow{Cells: []*connectivityCell{head}}
+		for _, to := range c.Vpcs {
+			cell := &connectivityCell{}
+			if from.ID == to.ID {
+				cell.Text = "-"
+			}
+			conns := c.Between(from.ID, to.ID)
+			texts := make([]string, 0, len(conns))
+			for _, conn := range conns {
+				texts = append(texts, conn.Text())
+			}
+			if len(conns) > 0 {
+				cell.Text = strings.Join(texts, "\n")
+			}
+			if len(conns) == 1 {
+				cell.Link = conns[0].Anchor
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		matrix.Rows = append(matrix.Rows, row)
+	}
+	tables := []*connectivityTable{matrix}
+	routeCells := func(ru *RouteUse) []*connectivityCell {
+		if ru == nil {
+			return []*connectivityCell{{}, {}}
+		}
+		return []*connectivityCell{
+			{Text: ru.Label(), Link: ru.Anchor()},
+			{Text: ru.Route.DestinationName() + routeStateMark(ru.Route)},
+		}
+	}
+	if len(nt.PeeringConnections) > 0 {
+		pcxs := &connectivityTable{
+			Title:  "Peering Connections",
+			Header: []string{"ID", "Name", "Status", "Requester", "Accepter", "Route Table", "Destination"},
+		}
+		for _, pcx := range nt.PeeringConnections {
+			status := pcx.Status
+			if pcx.StatusMessage != "" {
+				status += "\n" + pcx.StatusMessage
+			}
+			uses := nt.RoutesTo(pcx.ID)
+			first := &connectivityRow{
+				Anchor: anchor("pcx", pcx.Account, pcx.Region, pcx.ID),
+				Cells: []*connectivityCell{
+					{Text: pcx.ID}, {Text: pcx.TagName}, {Text: status},
+					{Text: pcx.Requester.Label()}, {Text: pcx.Accepter.Label()},
+				},
+			}
+			if len(uses) == 0 {
+				first.Cells = append(first.Cells, routeCells(nil)...)
+				pcxs.Rows = append(pcxs.Rows, first)
+				continue
+			}
+			for i, ru := range uses {
+				row := first
+				if i > 0 {
+					row = &connectivityRow{Cells: []*connectivityCell{{}, {}, {}, {}, {}}}
+				}
+				row.Cells = append(row.Cells, routeCells(ru)...)
+				pcxs.Rows = append(pcxs.Rows, row)
+			}
+		}
+		tables = append(tables, pcxs)
+	}
+	for _, tgw := range nt.TransitGateways {
+		title := fmt.Sprintf("Transit Gateway %s", tgw.ID)
+		if tgw.OwnerID != "" {
+			title += fmt.Sprintf(" (owner %s)", tgw.OwnerID)
+		}
+		ats := &connectivityTable{
+			Title:  title,
+			Anchor: anchor("tgw", tgw.Account, tgw.Region, tgw.ID),
+			Header: []string{"Attachment", "Name", "Type", "Resource", "Resource Owner", "State", "Route Table"},
+		}
+		for _, at := range tgw.Attachments {
+			ats.Rows = append(ats.Rows, &connectivityRow{Cells: []*connectivityCell{
+				{Text: at.ID}, {Text: at.TagName}, {Text: at.ResourceType}, {Text: at.ResourceID},
+				{Text: at.ResourceOwner}, {Text: at.State}, {Text: at.RouteTableID},
+			}})
+		}
+		tables = append(tables, ats)
+		for _, rt := range tgw.RouteTables {
+			title := strings.TrimSpace(fmt.Sprintf("Transit Gateway Route Table %s %s", rt.ID, rt.TagName))
+			defaults := make([]string, 0)
+			if rt.DefaultAssociation {
+				defaults = append(defaults, "default association")
+			}
+			if rt.DefaultPropagation {
+				defaults = append(defaults, "default propagation")
+			}
+			if len(defaults) > 0 {
+				title += fmt.Sprintf(" (%s)", strings.Join(defaults, ", "))
+			}
+			routes := &connectivityTable{Title: title, Header: []string{"Destination", "Type", "State", "Attachments"}}
+			for _, r := range rt.Routes {
+				routes.Rows = append(routes.Rows, &connectivityRow{Cells: []*connectivityCell{
+					{Text: r.Destination}, {Text: r.Type}, {Text: r.State},
+					{Text: strings.Join(tgw.AttachmentResources(r), "\n")},
+				}})
+			}
+			tables = append(tables, routes)
+		}
+		if uses := nt.RoutesTo(tgw.ID); len(uses) > 0 {
+			used := &connectivityTable{
+				Title:  fmt.Sprintf("Route Tables Routing to %s", tgw.ID),
+				Header: []string{"Route Table", "Destination"},
+			}
+			for _, ru := range uses {
+				used.Rows = append(used.Rows, &connectivityRow{Cells: routeCells(ru)})
+			}
+			tables = append(tables, used)
+		}
+	}
+	return tables
+}
+
+// routeStateMark the state of r in parentheses when it is known and not
+// active, e.g. blackhole
+func routeStateMark(r *Route) string {
+	if r.State == "" || r.State == "active" {
+		return ""
+	}
+	return " (" + r.State + ")"
+}
+
+// xlsxLink cell to link to the anchor to once everything is written
+type xlsxLink struct {
+	cell   *xlsx.Cell
+	anchor string
+}
+
+// addConnectivitySheet writes tables to a sheet named name, recording in
+// locations where the tables and rows with an anchor are written and adding
+// the cells that link to links
+func addConnectivitySheet(file *xlsx.File, name string, tables []*connectivityTable, locations map[string]xlsxLocation, links *[]xlsxLink) (string, error) {
+	sheet, err := addSheet(file, name)
+	if err != nil {
+		return "", err
+	}
+	row := 0
+	for _, t := range tables {
+		titleCell := sheet.Cell(row, 0)
+		titleCell.Value = t.Title
+		titleCell.Merge(len(t.Header)-1, 0)
+		titleCell.SetStyle(borderWithAlign("lrtb", true))
+		if t.Anchor != "" {
+			locations[t.Anchor] = xlsxLocation{sheet: sheet.Name, row: row, col: 0}
+		}
+		row++
+		for i, h := range t.Header {
+			sheet.Cell(row, i).Value = h
+			sheet.Cell(row, i).SetStyle(borderWithAlign("lrtb", true))
+		}
+		row++
+		for _, r := range t.Rows {
+			if r.Anchor != "" {
+				locations[r.Anchor] = xlsxLocation{sheet: sheet.Name, row: row, col: 0}
+			}
+			for i, c := range r.Cells {
+				cell := sheet.Cell(row, i)
+				cell.Value = c.Text
+				cell.SetStyle(borderWithAlign("lrtb", false))
+				if c.Link != "" {
+					*links = append(*links, xlsxLink{cell: cell, anchor: c.Link})
+				}
+			}
+			row++
+		}
+		row++
+	}
+	return sheet.Name, nil
+}
+
+// addConnectivityToPdf writes tables, each table and row with an anchor the
+// target of the links to it
+func addConnectivityToPdf(pdf *pdfDoc, tables []*connectivityTable) {
+	pdf.heading("Connectivity", 0)
+	for _, t := range tables {
+		widths := make([]float64, 0, len(t.Header))
+		for range t.Header {
+			widths = append(widths, 190/float64(len(t.Header)))
+		}
+		pdf.fit(pdfLineHeight * 4)
+		if t.Anchor != "" {
+			pdf.anchor(t.Anchor)
+		}
+		pdf.CellFormat(0, pdfLineHeight+2, pdf.tr(t.Title), "1", 1, "C", false, 0, "")
+		header := make([]pdfCell, 0, len(t.Header))
+		for _, h := range t.Header {
+			header = append(header, pdfCell{text: h})
+		}
+		pdf.SetFontStyle("B")
+		pdf.row(widths, header)
+		pdf.SetFontStyle("")
+		for _, r := range t.Rows {
+			cells := make([]pdfCell, 0, len(r.Cells))
+			for _, c := range r.Cells {
+				cell := pdfCell{text: c.Text}
+				if c.Link != "" {
+					cell.link = pdf.link(c.Link)
+				}
+				cells = append(cells, cell)
+			}
+			pdf.fit(pdf.rowHeight(widths, cells))
+			if r.Anchor != "" {
+				pdf.anchor(r.Anchor)
+			}
+			pdf.row(widths, cells)
+		}
+		pdf.Ln(pdfLineHeight)
+	}
+}
+
+// addConnectivityToJSON adds the peering connections, transit gateways and
+// connectivity to report
+func (nt *Network) addConnectivityToJSON(report *jsonNetworkReport) {
+	routeTableIDs := func(id string) []string {
+		ids := make([]string, 0)
+		for _, ru := range nt.RoutesTo(id) {
+			if len(ids) == 0 || ids[len(ids)-1] != ru.RouteTable.ID {
+				ids = append(ids, ru.RouteTable.ID)
+			}
+		}
+		return ids
+	}
+	peeringVpc := func(pv *PeeringVpc) *jsonPeeringVpc {
+		return &jsonPeeringVpc{VpcID: pv.VpcID, Account: pv.Account, Region: pv.Region, CidrBlocks: nonNil(pv.CidrBlocks)}
+	}
+	for _, pcx := range nt.PeeringConnections {
+		report.PeeringConnections = append(report.PeeringConnections, &jsonPeeringConnection{
+			ID:            pcx.ID,
+			Name:          pcx.TagName,
+			Status:        pcx.Status,
+			StatusMessage: pcx.StatusMessage,
+			Requester:     peeringVpc(pcx.Requester),
+			Accepter:      peeringVpc(pcx.Accepter),
+			RouteTableIDs: routeTableIDs(pcx.ID),
+		})
+	}
+	for _, tgw := range nt.TransitGateways {
+		jtgw := &jsonTransitGateway{
+			ID:            tgw.ID,
+			OwnerID:       tgw.OwnerID,
+			Attachments:   make([]*jsonTransitGatewayAttachment, 0),
+			RouteTables:   make([]*jsonTransitGatewayRouteTable, 0),
+			RouteTableIDs: routeTableIDs(tgw.ID),
+		}
+		for _, at := range tgw.Attachments {
+			jtgw.Attachments = append(jtgw.Attachments, &jsonTransitGatewayAttachment{
+				ID:            at.ID,
+				Name:          at.TagName,
+				ResourceType:  at.ResourceType,
+				ResourceID:    at.ResourceID,
+				ResourceOwner: at.ResourceOwner,
+				State:         at.State,
+				RouteTableID:  at.RouteTableID,
+			})
+		}
+		for _, rt := range tgw.RouteTables {
+			jrt := &jsonTransitGatewayRouteTable{
+				ID:                 rt.ID,
+				Name:               rt.TagName,
+				DefaultAssociation: rt.DefaultAssociation,
+				DefaultPropagation: rt.DefaultPropagation,
+				Routes:             make([]*jsonTransitGatewayRoute, 0),
+			}
+			for _, r := range rt.Routes {
+				jrt.Routes = append(jrt.Routes, &jsonTransitGatewayRoute{
+					Destination:   r.Destination,
+					Type:          r.Type,
+					State:         r.State,
+					AttachmentIDs: nonNil(r.AttachmentIDs),
+				})
+			}
+			jtgw.RouteTables = append(jtgw.RouteTables, jrt)
+		}
+		report.TransitGateways = append(report.TransitGateways, jtgw)
+	}
+	for _, conn := range nt.Connectivity().Connections {
+		report.Connectivity = append(report.Connectivity, &jsonConnection{
+			FromVpcID:    conn.From,
+			ToVpcID:      conn.To,
+			Via:          conn.Via,
+			Type:         conn.Type,
+			Status:       conn.Status,
+			RouteTableID: conn.RouteTableID,
+		})
+	}
+}
+
+// connectivityCSVTables peering_connections, transit_gateway_attachments,
+// transit_gateway_routes and connectivity. The route tables routing to a
+// peering connection or transit gateway are those of routes.csv whose target
+// it is.
+func (nt *Network) connectivityCSVTables() (*csvTable, *csvTable, *csvTable, *csvTable) {
+	pcxs := newCSVTable("peering_connections", "account", "region", "peering_connection_id", "name", "status", "status_message",
+		"requester_vpc_id", "requester_account", "requester_region", "requester_cidr_blocks",
+		"accepter_vpc_id", "accepter_account", "accepter_region", "accepter_cidr_blocks")
+	ats := newCSVTable("transit_gateway_attachments", "account", "region", "transit_gateway_id", "owner_id", "attachment_id", "name", "resource_type", "resource_id", "resource_owner", "state", "transit_gateway_route_table_id")
+	routes := newCSVTable("transit_gateway_routes", "account", "region", "transit_gateway_id", "transit_gateway_route_table_id", "name", "destination", "type", "state", "attachment_ids", "resource_ids")
+	conns := newCSVTable("connectivity", "from_vpc_id", "to_vpc_id", "via", "type", "status", "transit_gateway_route_table_id")
+	for _, pcx := range nt.PeeringConnections {
+		r, a := pcx.Requester, pcx.Accepter
+		pcxs.add(pcx.Account, pcx.Region, pcx.ID, pcx.TagName, pcx.Status, pcx.StatusMessage,
+			r.VpcID, r.Account, r.Region, strings.Join(r.CidrBlocks, " "),
+			a.VpcID, a.Account, a.Region, strings.Join(a.CidrBlocks, " "))
+	}
+	for _, tgw := range nt.TransitGateways {
+		for _, at := range tgw.Attachments {
+			ats.add(tgw.Account, tgw.Region, tgw.ID, tgw.OwnerID, at.ID, at.TagName, at.ResourceType, at.ResourceID, at.ResourceOwner, at.State, at.RouteTableID)
+		}
+		for _, rt := range tgw.RouteTables {
+			for _, r := range rt.Routes {
+				routes.add(tgw.Account, tgw.Region, tgw.ID, rt.ID, rt.TagName, r.Destination, r.Type, r.State, strings.Join(r.AttachmentIDs, " "), strings.Join(tgw.AttachmentResources(r), " "))
+			}
+		}
+	}
+	for _, conn := range nt.Connectivity().Connections {
+		conns.add(conn.From, conn.To, conn.Via, conn.Type, conn.Status, conn.RouteTableID)
+	}
+	return pcxs, ats, routes, conns
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/atsushi-ishibashi/aws-state-report/svc"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/tealeg/xlsx"
+)
+
+func TestNetworkConnectivity(t *testing.T) {
+	nt := collectFixtureNetwork(t)
+	c := nt.Connectivity()
+	if conns := c.Between("vpc-0a1b2c3d", "vpc-0osaka"); len(conns) != 1 || conns[0].Via != "pcx-0osaka" {
+		t.Errorf("sample to osaka = %v", conns)
+	}
+	if conns := c.Between("vpc-0a1b2c3d", "vpc-0partner"); len(conns) != 0 {
+		t.Errorf("sample to partner over a deleted peering connection = %v", conns)
+	}
+	conns := c.Between("vpc-0a1b2c3d", "vpc-0partner2")
+	if len(conns) != 1 || conns[0].Via != "tgw-0a1b2c3d" || conns[0].RouteTableID != "tgw-rtb-0shared" {
+		t.Errorf("sample to partner2 = %v", conns)
+	}
+	if tgw := nt.transitGateway("tgw-0a1b2c3d"); tgw == nil || tgw.OwnerID != "123456789012" {
+		t.Errorf("tgw-0a1b2c3d = %v", tgw)
+	}
+}
+
+func TestNetworkConnectivityCSV(t *testing.T) {
+	nt := collectFixtureNetwork(t)
+	dir := t.TempDir()
+	if err := nt.convertCSV(dir); err != nil {
+		t.Fatal(err)
+	}
+	conns := csvRows(t, dir, "connectivity")
+	if row := findRow(conns, map[string]string{"from_vpc_id": "vpc-0a1b2c3d", "to_vpc_id": "vpc-0partner2"}); row == nil || row["via"] != "tgw-0a1b2c3d" || row["transit_gateway_route_table_id"] != "tgw-rtb-0shared" {
+		t.Errorf("sample to partner2 = %v", row)
+	}
+	if row := findRow(conns, map[string]string{"to_vpc_id": "vpc-0partner"}); row != nil {
+		t.Errorf("deleted peering connection listed: %v", row)
+	}
+}
+
+// transitGatewayManager serves the fixtures, with every route table having
+// more routes than listed and, when noAttachments, no attachments
+type transitGatewayManager struct {
+	svc.Manager
+	noAttachments bool
+}
+
+func (m *transitGatewayManager) FetchTransitGatewayAttachments() (*ec2.DescribeTransitGatewayAttachmentsOutput, error) {
+	if m.noAttachments {
+		return &ec2.DescribeTransitGatewayAttachmentsOutput{}, nil
+	}
+	return m.Manager.FetchTransitGatewayAttachments()
+}
+
+func (m *transitGatewayManager) FetchTransitGatewayRoutes(routeTableID string) (*ec2.SearchTransitGatewayRoutesOutput, error) {
+	result, err := m.Manager.FetchTransitGatewayRoutes(routeTableID)
+	if err != nil {
+		return nil, err
+	}
+	result.AdditionalRoutesAvailable = aws.Bool(true)
+	return result, nil
+}
+
+func TestTransitGatewayRoutesTruncated(t *testing.T) {
+	target := fixtureTarget(t)
+	target.manager = &transitGatewayManager{Manager: target.manager}
+	nt := &Network{Errs: make([]error, 0)}
+	nt.collect(target)
+	if len(nt.Errs) != 1 || !strings.Contains(nt.Errs[0].Error(), "tgw-rtb-0shared") {
+		t.Fatalf("errs = %v", nt.Errs)
+	}
+	if tgw := nt.transitGateway("tgw-0a1b2c3d"); tgw == nil || len(tgw.RouteTables) != 1 || len(tgw.RouteTables[0].Routes) != 4 {
+		t.Errorf("routes listed before the cut are dropped: %v", tgw)
+	}
+}
+
+func TestTransitGatewayOwnerUnknown(t *testing.T) {
+	target := fixtureTarget(t)
+	target.Account = "prod"
+	target.manager = &transitGatewayManager{Manager: target.manager, noAttachments: true}
+	nt := &Network{Errs: make([]error, 0)}
+	nt.collect(target)
+	tgw := nt.transitGateway("tgw-0a1b2c3d")
+	if tgw == nil || tgw.OwnerID != "" {
+		t.Fatalf("tgw-0a1b2c3d = %v, want no owner", tgw)
+	}
+	for _, table := range nt.connectivityTables() {
+		if strings.Contains(table.Title, "owner") {
+			t.Errorf("title %q names an owner", table.Title)
+		}
+	}
+}
+
+func TestConnectivitySheetClashingName(t *testing.T) {
+	nt := collectFixtureNetwork(t)
+	nt.Vpcs[0].TagName = "connectivity"
+	file := xlsx.NewFile()
+	sheets, _ := nt.addXlsxSheets(file)
+	if got := strings.Join(sheets, ","); got != "connectivity,Connectivity~2" {
+		t.Fatalf("sheets = %s", got)
+	}
+	linked := false
+	for row := 0; row < 100; row++ {
+		if strings.Contains(file.Sheet["connectivity"].Cell(row, 1).Formula(), "'Connectivity~2'!") {
+			linked = true
+		}
+	}
+	if !linked {
+		t.Error("no route links to the Connectivity~2 sheet")
+	}
+}
+
+func TestRouteStateMark(t *testing.T) {
+	tests := []struct {
+		state, want string
+	}{
+		{"active", ""},
+		{"", ""},
+		{"blackhole", " (blackhole)"},
+		{"filtered", " (filtered)"},
+	}
+	for _, tt := range tests {
+		if got := routeStateMark(&Route{State: tt.state}); got != tt.want {
+			t.Errorf("routeStateMark(%q) = %q, want %q", tt.state, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// csvTable one CSV file of a report. Tables of a report share column names
+// for the same IDs (account, region, vpc_id, group_id, ...) so they join.
+type csvTable struct {
+	name   string
+	header []string
+	rows   [][]string
+}
+
+func newCSVTable(name string, header ...string) *csvTable {
+	return &csvTable{name: name, header: header, rows: make([][]string, 0)}
+}
+
+func (t *csvTable) add(values ...string) {
+	t.rows = append(t.rows, values)
+}
+
+// csvErrors table of errs, nil when there are none
+func csvErrors(errs []error) *csvTable {
+	if len(errs) == 0 {
+		return nil
+	}
+	t := newCSVTable("errors", "account", "region", "operation", "resource", "code", "message")
+	for _, v := range jsonErrors(errs) {
+		t.add(v.Account, v.Region, v.Operation, v.Resource, v.Code, v.Message)
+	}
+	return t
+}
+
+// writeCSV writes <dir>/<name>.csv for every table that is not nil
+func writeCSV(dir string, tables ...*csvTable) error {
+	if dir == stdout {
+		return errors.New("csv is a directory of files and cannot be written to stdout, give --output a path")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, t := range tables {
+		if t == nil {
+			continue
+		}
+		if err := t.write(filepath.Join(dir, t.name+".csv")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *csvTable) write(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write(t.header)
+	w.WriteAll(t.rows)
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func formatPort(p int64) string {
+	return strconv.FormatInt(p, 10)
+}
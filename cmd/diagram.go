@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// diagramVpc one VPC of a topology diagram: subnets grouped by the route
+// table they use, and the routes leaving the VPC
+type diagramVpc struct {
+	vpc    *Vpc
+	groups []*diagramGroup
+}
+
+// diagramGroup subnets using rt, either associated explicitly or, for the
+// main route table, implicitly. rt is nil for subnets whose route table is
+// not known.
+type diagramGroup struct {
+	rt      *RouteTable
+	subnets []*Subnet
+}
+
+func newDiagramVpc(v *Vpc) *diagramVpc {
+	dv := &diagramVpc{vpc: v, groups: make([]*diagramGroup, 0)}
+	for _, rt := range v.RouteTables {
+		g := &diagramGroup{rt: rt, subnets: make([]*Subnet, 0)}
+		for _, sn := range v.Subnets {
+			if sn.AssociatedRouteTable == rt {
+				g.subnets = append(g.subnets, sn)
+			}
+		}
+		dv.groups = append(dv.groups, g)
+	}
+	if unknown := unknownRouteTableSubnets(v); len(unknown) > 0 {
+		dv.groups = append(dv.groups, &diagramGroup{subnets: unknown})
+	}
+	return dv
+}
+
+var nodeIDUnsafe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// nodeID identifier of a node or cluster, scoped to the account and region
+// of the VPC. Only letters, digits and underscores, so Mermaid takes it too.
+func (dv *diagramVpc) nodeID(id string) string {
+	parts := make([]string, 0)
+	for _, v := range []string{dv.vpc.Account, dv.vpc.Region, id} {
+		if v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return nodeIDUnsafe.ReplaceAllString(strings.Join(parts, "_"), "_")
+}
+
+func (dv *diagramVpc) label() string {
+	name := dv.vpc.TagName
+	if name == "" {
+		name = dv.vpc.ID
+	}
+	return fmt.Sprintf("%s\n%s %s\n%s", name, dv.vpc.ID, strings.Join(dv.vpc.CidrBlocks(), " "), location(dv.vpc.Account, dv.vpc.Region))
+}
+
+func routeTableLabel(rt *RouteTable) string {
+	if rt == nil {
+		return "Route Table Unknown"
+	}
+	label := fmt.Sprintf("Route Table: %s\n%s", rt.TagName, rt.ID)
+	if rt.IsMain() {
+		label += " (main)"
+	}
+	return label
+}
+
+func subnetLabel(sn *Subnet) string {
+	return fmt.Sprintf("%s\n%s\n%s", subnetName(sn), sn.ID, strings.Join(sn.CidrBlocks(), "\n"))
+}
+
+// targetLabel label of the node of gateway or target id, with its name when
+// it is one of the gateways of the VPC
+func (dv *diagramVpc) targetLabel(id string) string {
+	if name, ok := dv.vpc.gatewayName(id); ok && name != "" {
+		return name + "\n" + id
+	}
+	return id
+}
+
+// externalRoutes routes of rt to a gateway, interface, peering connection or
+// other target, local routes left out
+func externalRoutes(rt *RouteTable) []*Route {
+	routes := make([]*Route, 0)
+	for _, r := range rt.Routes {
+		if r.Router != "" && r.RouterType != routerLocal {
+			routes = append(routes, r)
+		}
+	}
+	return routes
+}
+
+// routeLabel label of the edge of r: the destination, and whether the target
+// is gone
+func routeLabel(r *Route) string {
+	if r.IsBlackhole() {
+		return r.DestinationName() + " (blackhole)"
+	}
+	return r.DestinationName()
+}
+
+// convertDot writes <filename>.dot, or stdout, a Graphviz digraph with a
+// cluster per VPC and per route table
+func (nt *Network) convertDot(filename string) error {
+	var buf bytes.Buffer
+	buf.WriteString("digraph network {\n")
+	buf.WriteString("  rankdir=LR;\n")
+	buf.WriteString("  node [shape=box, fontsize=10];\n")
+	buf.WriteString("  edge [fontsize=9];\n")
+	targets := make(map[string]bool)
+	edges := make([]string, 0)
+	for _, v := range nt.Vpcs {
+		dv := newDiagramVpc(v)
+		fmt.Fprintf(&buf, "  subgraph %q {\n", "cluster_"+dv.nodeID(v.ID))
+		fmt.Fprintf(&buf, "    label=%q;\n", dv.label())
+		for i, g := range dv.groups {
+			groupID := dv.nodeID(fmt.Sprintf("%s_group%d", v.ID, i))
+			fmt.Fprintf(&buf, "    subgraph %q {\n", "cluster_"+groupID)
+			fmt.Fprintf(&buf, "      label=%q;\n", routeTableLabel(g.rt))
+			if g.rt != nil {
+				fmt.Fprintf(&buf, "      %q [label=%q, shape=note];\n", dv.nodeID(g.rt.ID), g.rt.ID)
+			}
+			for _, sn := range g.subnets {
+				fmt.Fprintf(&buf, "      %q [label=%q];\n", dv.nodeID(sn.ID), subnetLabel(sn))
+			}
+			buf.WriteString("    }\n")
+			if g.rt == nil {
+				continue
+			}
+			for _, gw := range g.rt.AssociationGateways {
+				source := dv.nodeID(gw)
+				if !targets[source] {
+					targets[source] = true
+					edges = append(edges, fmt.Sprintf("  %q [label=%q, shape=ellipse];\n", source, dv.targetLabel(gw)))
+				}
+				edges = append(edges, fmt.Sprintf("  %q -> %q [label=%q, style=dotted];\n", source, dv.nodeID(g.rt.ID), "edge association"))
+			}
+			for _, r := range externalRoutes(g.rt) {
+				target := dv.nodeID(r.Router)
+				if !targets[target] {
+					targets[target] = true
+					edges = append(edges, fmt.Sprintf("  %q [label=%q, shape=ellipse];\n", target, dv.targetLabel(r.Router)))
+				}
+				if r.IsBlackhole() {
+					edges = append(edges, fmt.Sprintf("  %q -> %q [label=%q, color=red, fontcolor=red, style=dashed];\n", dv.nodeID(g.rt.ID), target, routeLabel(r)))
+					continue
+				}
+				edges = append(edges, fmt.Sprintf("  %q -> %q [label=%q];\n", dv.nodeID(g.rt.ID), target, routeLabel(r)))
+			}
+		}
+		buf.WriteString("  }\n")
+	}
+	for _, e := range edges {
+		buf.WriteString(e)
+	}
+	buf.WriteString("}\n")
+	return writeOutput(filename, "dot", buf.Bytes())
+}
+
+// mermaidLabel s as a quoted Mermaid label
+func mermaidLabel(s string) string {
+	s = strings.Replace(s, `"`, "#quot;", -1)
+	return `"` + strings.Replace(s, "\n", "<br>", -1) + `"`
+}
+
+// convertMermaid writes <filename>.mmd, or stdout, a Mermaid flowchart with a
+// subgraph per VPC and per route table
+func (nt *Network) convertMermaid(filename string) error {
+	var buf bytes.Buffer
+	buf.WriteString("flowchart LR\n")
+	targets := make(map[string]bool)
+	edges := make([]string, 0)
+	for _, v := range nt.Vpcs {
+		dv := newDiagramVpc(v)
+		fmt.Fprintf(&buf, "  subgraph %s[%s]\n", dv.nodeID(v.ID), mermaidLabel(dv.label()))
+		for i, g := range dv.groups {
+			groupID := dv.nodeID(fmt.Sprintf("%s_group%d", v.ID, i))
+			fmt.Fprintf(&buf, "    subgraph %s[%s]\n", groupID, mermaidLabel(routeTableLabel(g.rt)))
+			if g.rt != nil {
+				fmt.Fprintf(&buf, "      %s>%s]\n", dv.nodeID(g.rt.ID), mermaidLabel(g.rt.ID))
+			}
+			for _, sn := range g.subnets {
+				fmt.Fprintf(&buf, "      %s[%s]\n", dv.nodeID(sn.ID), mermaidLabel(subnetLabel(sn)))
+			}
+			buf.WriteString("    end\n")
+			if g.rt == nil {
+				continue
+			}
+			for _, gw := range g.rt.AssociationGateways {
+				source := dv.nodeID(gw)
+				if !targets[source] {
+					targets[source] = true
+					edges = append(edges, fmt.Sprintf("  %s((%s))\n", source, mermaidLabel(dv.targetLabel(gw))))
+				}
+				edges = append(edges, fmt.Sprintf("  %s -. %s .- %s\n", source, mermaidLabel("edge association"), dv.nodeID(g.rt.ID)))
+			}
+			for _, r := range externalRoutes(g.rt) {
+				target := dv.nodeID(r.Router)
+				if !targets[target] {
+					targets[target] = true
+					edges = append(edges, fmt.Sprintf("  %s((%s))\n", target, mermaidLabel(dv.targetLabel(r.Router))))
+				}
+				if r.IsBlackhole() {
+					edges = append(edges, fmt.Sprintf("  %s -. %s .-> %s\n", dv.nodeID(g.rt.ID), mermaidLabel(routeLabel(r)), target))
+					continue
+				}
+				edges = append(edges, fmt.Sprintf("  %s -- %s --> %s\n", dv.nodeID(g.rt.ID), mermaidLabel(routeLabel(r)), target))
+			}
+		}
+		buf.WriteString("  end\n")
+	}
+	for _, e := range edges {
+		buf.WriteString(e)
+	}
+	return writeOutput(filename, "mmd", buf.Bytes())
+}
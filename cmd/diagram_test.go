@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// diagramOf the fixture network, its VPC named with quotes, converted by
+// convert and read back from <name>.<ext>
+func diagramOf(t *testing.T, convert func(nt *Network, filename string) error, ext string) string {
+	t.Helper()
+	nt := collectFixtureNetwork(t)
+	nt.Vpcs[0].TagName = `sample "vpc"`
+	filename := filepath.Join(t.TempDir(), "network")
+	if err := convert(nt, filename); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filename + "." + ext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestNetworkConvertDot(t *testing.T) {
+	dot := diagramOf(t, (*Network).convertDot, "dot")
+	cluster := `  subgraph "cluster_ap_northeast_1_vpc_0a1b2c3d" {
+    label="sample \"vpc\"\nvpc-0a1b2c3d 10.0.0.0/16 2406:da14:abc:de00::/56\nap-northeast-1";
+    subgraph "cluster_ap_northeast_1_vpc_0a1b2c3d_group0" {
+      label="Route Table: sample-main\nrtb-0main (main)";
+      "ap_northeast_1_rtb_0main" [label="rtb-0main", shape=note];
+      "ap_northeast_1_subnet_0private" [label="sample-private-c (implicit)\nsubnet-0private\n10.0.1.0/24\n2406:da14:abc:de01::/64"];
+    }
+    subgraph "cluster_ap_northeast_1_vpc_0a1b2c3d_group1" {
+      label="Route Table: sample-public\nrtb-0public";
+      "ap_northeast_1_rtb_0public" [label="rtb-0public", shape=note];
+      "ap_northeast_1_subnet_0public" [label="sample-public-a\nsubnet-0public\n10.0.0.0/24\n2406:da14:abc:de00::/64"];
+    }
+    subgraph "cluster_ap_northeast_1_vpc_0a1b2c3d_group2" {
+      label="Route Table: sample-ingress\nrtb-0ingress";
+      "ap_northeast_1_rtb_0ingress" [label="rtb-0ingress", shape=note];
+    }
+  }
+`
+	if !strings.Contains(dot, cluster) {
+		t.Errorf("vpc cluster missing, got:\n%s", dot)
+	}
+	for _, want := range []string{
+		`  "ap_northeast_1_nat_0a1b2c3d" [label="sample-nat\nnat-0a1b2c3d", shape=ellipse];`,
+		`  "ap_northeast_1_rtb_0main" -> "ap_northeast_1_nat_0a1b2c3d" [label="0.0.0.0/0"];`,
+		`  "ap_northeast_1_rtb_0main" -> "ap_northeast_1_eigw_0a1b2c3d" [label="::/0"];`,
+		`  "ap_northeast_1_rtb_0main" -> "ap_northeast_1_pcx_0a1b2c3d" [label="192.168.0.0/16 (blackhole)", color=red, fontcolor=red, style=dashed];`,
+		`  "ap_northeast_1_rtb_0main" -> "ap_northeast_1_vpce_0s3" [label="pl-61a54008 (com.amazonaws.ap-northeast-1.s3)"];`,
+		`  "ap_northeast_1_igw_0a1b2c3d" -> "ap_northeast_1_rtb_0ingress" [label="edge association", style=dotted];`,
+	} {
+		if !strings.Contains(dot, want+"\n") {
+			t.Errorf("missing %s", want)
+		}
+	}
+	// a target reached from two route tables is one node
+	if n := strings.Count(dot, "\n  \"ap_northeast_1_igw_0a1b2c3d\" [label="); n != 1 {
+		t.Errorf("internet gateway declared %d times", n)
+	}
+	if !strings.HasPrefix(dot, "digraph network {\n") || !strings.HasSuffix(dot, "\n}\n") {
+		t.Errorf("not one digraph:\n%s", dot)
+	}
+}
+
+func TestNetworkConvertMermaid(t *testing.T) {
+	mmd := diagramOf(t, (*Network).convertMermaid, "mmd")
+	subgraph := `  subgraph ap_northeast_1_vpc_0a1b2c3d["sample #quot;vpc#quot;<br>vpc-0a1b2c3d 10.0.0.0/16 2406:da14:abc:de00::/56<br>ap-northeast-1"]
+    subgraph ap_northeast_1_vpc_0a1b2c3d_group0["Route Table: sample-main<br>rtb-0main (main)"]
+      ap_northeast_1_rtb_0main>"rtb-0main"]
+      ap_northeast_1_subnet_0private["sample-private-c (implicit)<br>subnet-0private<br>10.0.1.0/24<br>2406:da14:abc:de01::/64"]
+    end
+    subgraph ap_northeast_1_vpc_0a1b2c3d_group1["Route Table: sample-public<br>rtb-0public"]
+      ap_northeast_1_rtb_0public>"rtb-0public"]
+      ap_northeast_1_subnet_0public["sample-public-a<br>subnet-0public<br>10.0.0.0/24<br>2406:da14:abc:de00::/64"]
+    end
+    subgraph ap_northeast_1_vpc_0a1b2c3d_group2["Route Table: sample-ingress<br>rtb-0ingress"]
+      ap_northeast_1_rtb_0ingress>"rtb-0ingress"]
+    end
+  end
+`
+	if !strings.HasPrefix(mmd, "flowchart LR\n"+subgraph) {
+		t.Errorf("vpc subgraph missing, got:\n%s", mmd)
+	}
+	for _, want := range []string{
+		`  ap_northeast_1_nat_0a1b2c3d(("sample-nat<br>nat-0a1b2c3d"))`,
+		`  ap_northeast_1_rtb_0main -- "0.0.0.0/0" --> ap_northeast_1_nat_0a1b2c3d`,
+		`  ap_northeast_1_rtb_0main -- "::/0" --> ap_northeast_1_eigw_0a1b2c3d`,
+		`  ap_northeast_1_rtb_0main -. "192.168.0.0/16 (blackhole)" .-> ap_northeast_1_pcx_0a1b2c3d`,
+		`  ap_northeast_1_igw_0a1b2c3d -. "edge association" .- ap_northeast_1_rtb_0ingress`,
+	} {
+		if !strings.Contains(mmd, want+"\n") {
+			t.Errorf("missing %s", want)
+		}
+	}
+	if n := strings.Count(mmd, "ap_northeast_1_igw_0a1b2c3d(("); n != 1 {
+		t.Errorf("internet gateway declared %d times", n)
+	}
+}
+
+func TestMermaidLabel(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"rtb-0main", `"rtb-0main"`},
+		{`say "hi"`, `"say #quot;hi#quot;"`},
+		{"one\ntwo", `"one<br>two"`},
+	}
+	for _, tt := range tests {
+		if got := mermaidLabel(tt.in); got != tt.want {
+			t.Errorf("mermaidLabel(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/atsushi-ishibashi/aws-state-report/util"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/tealeg/xlsx"
+	"github.com/urfave/cli"
+)
+
+// exitPartialReport exit status when --continue-on-error rendered a report
+// with some calls failed
+const exitPartialReport = 2
+
+// fetchError a failed API call and what it was for
+type fetchError struct {
+	Account   string
+	Region    string
+	Operation string
+	// Resource ID or name the call was for, empty for list calls
+	Resource string
+	// Code AWS error code, empty when the call did not reach AWS
+	Code string
+	Err  error
+}
+
+func newFetchError(operation, resource string, err error) *fetchError {
+	fe := &fetchError{Operation: operation, Resource: resource, Err: err}
+	if aerr, ok := err.(awserr.Error); ok {
+		fe.Code = aerr.Code()
+	}
+	return fe
+}
+
+func (e *fetchError) Error() string {
+	call := e.Operation
+	if e.Resource != "" {
+		call = fmt.Sprintf("%s(%s)", e.Operation, e.Resource)
+	}
+	where := make([]string, 0)
+	for _, v := range []string{e.Account, e.Region} {
+		if v != "" {
+			where = append(where, v)
+		}
+	}
+	if len(where) == 0 {
+		return fmt.Sprintf("%s: %s", call, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: %s", strings.Join(where, "/"), call, e.Err)
+}
+
+// message error message without the code AWS already put in front of it
+func (e *fetchError) message() string {
+	if aerr, ok := e.Err.(awserr.Error); ok {
+		return aerr.Message()
+	}
+	return e.Err.Error()
+}
+
+func continueOnError(c *cli.Context) bool {
+	return c.GlobalBool("continue-on-error")
+}
+
+// flattenErrors one error listing errs a line each, nil when there are none
+func flattenErrors(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	var errStr string
+	for _, e := range errs {
+		errStr = errStr + e.Error() + "\n"
+	}
+	return errors.New(errStr)
+}
+
+// partialReportError nil when nothing failed, otherwise prints what failed
+// and returns an error exiting with exitPartialReport
+func partialReportError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	for _, err := range errs {
+		util.PrintlnRed(err.Error())
+	}
+	return cli.NewExitError(util.SprintRed(fmt.Sprintf("report is partial, failed API calls: %d", len(errs))), exitPartialReport)
+}
+
+// addErrorsSheet lists errs in an "errors" sheet, one failed call per row,
+// and returns its name, empty when there were no errors
+func addErrorsSheet(file *xlsx.File, errs []error) string {
+	if len(errs) == 0 {
+		return ""
+	}
+	sheet, err := addSheet(file, "errors")
+	if err != nil {
+		util.PrintlnRed(err.Error())
+		return ""
+	}
+	for i, v := range []string{"Account", "Region", "Operation", "Resource", "Code", "Message"} {
+		sheet.Cell(0, i).Value = v
+		sheet.Cell(0, i).SetStyle(borderWithAlign("lrtb", true))
+	}
+	for i, err := range errs {
+		values := []string{"", "", "", "", "", err.Error()}
+		if fe, ok := err.(*fetchError); ok {
+			values = []string{fe.Account, fe.Region, fe.Operation, fe.Resource, fe.Code, fe.message()}
+		}
+		for j, v := range values {
+			sheet.Cell(i+1, j).Value = v
+			sheet.Cell(i+1, j).SetStyle(borderWithAlign("lrtb", false))
+		}
+	}
+	return sheet.Name
+}
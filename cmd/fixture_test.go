@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/atsushi-ishibashi/aws-state-report/svc"
+)
+
+// fixtureDir canned responses the report tests collect from
+const fixtureDir = "../fixtures/sample"
+
+// fixtureTarget one region served from fixtureDir
+func fixtureTarget(t *testing.T) *target {
+	t.Helper()
+	mng, err := svc.NewFixtureManager(fixtureDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &target{Region: defaultRegion, manager: mng, workers: 2}
+}
+
+// csvRows rows of <dir>/<name>.csv as maps from column to value
+func csvRows(t *testing.T, dir, name string) []map[string]string {
+	t.Helper()
+	f, err := os.Open(filepath.Join(dir, name+".csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := make([]map[string]string, 0, len(records))
+	for _, rec := range records[1:] {
+		row := make(map[string]string)
+		for i, col := range records[0] {
+			row[col] = rec[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// findRow first of rows whose columns have the values of match, nil if none
+func findRow(rows []map[string]string, match map[string]string) map[string]string {
+	for _, row := range rows {
+		ok := true
+		for k, v := range match {
+			if row[k] != v {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return row
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// htmlData what the page template renders; Report is the command's own data,
+// rendered by its "toc" and "body" templates
+type htmlData struct {
+	Title       string
+	GeneratedAt string
+	Errors      []*jsonError
+	Report      interface{}
+}
+
+var anchorUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// anchor element ID of one resource, unique across accounts and regions
+func anchor(kind string, parts ...string) string {
+	return anchorUnsafe.ReplaceAllString(kind+"-"+strings.Join(parts, "-"), "_")
+}
+
+// prettyDocument policy document decoded and indented when it is JSON
+func prettyDocument(s string) string {
+	return indentJSON(policyDocument(s))
+}
+
+// indentJSON s indented when it is JSON, as it is otherwise
+func indentJSON(s string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), "", "  "); err != nil {
+		return s
+	}
+	return buf.String()
+}
+
+var htmlFuncs = template.FuncMap{
+	"anchor":         anchor,
+	"join":           strings.Join,
+	"prettyDocument": prettyDocument,
+}
+
+// writeHTML renders <filename>.html, or stdout, with the page template
+// around the "toc" and "body" templates defined in tmpl. funcs adds to
+// htmlFuncs.
+func writeHTML(filename, title, tmpl string, report interface{}, errs []error, funcs template.FuncMap) error {
+	t, err := template.New("page").Funcs(htmlFuncs).Funcs(funcs).Parse(htmlPage)
+	if err != nil {
+		return err
+	}
+	if _, err := t.Parse(tmpl); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	data := &htmlData{
+		Title:       title,
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Errors:      jsonErrors(errs),
+		Report:      report,
+	}
+	if err := t.Execute(&buf, data); err != nil {
+		return err
+	}
+	return writeOutput(filename, "html", buf.Bytes())
+}
+
+const htmlPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; margin: 0 2em 2em; }
+header { position: sticky; top: 0; background: #fff; padding: 1em 0; border-bottom: 1px solid #ccc; }
+header h1 { display: inline; font-size: 1.4em; margin-right: 1em; }
+#search { width: 20em; padding: 0.3em; }
+nav ul { columns: 3; }
+h2 { margin-top: 1.5em; border-bottom: 1px solid #ccc; }
+details.item { border: 1px solid #ccc; margin: 0.5em 0; padding: 0.3em 0.6em; }
+details.item > summary { cursor: pointer; font-weight: bold; }
+details.item:target { border-color: #c60; }
+table { border-collapse: collapse; margin: 0.5em 0; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.5em; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; }
+pre { margin: 0; font-size: 12px; }
+.meta { color: #666; font-weight: normal; }
+.hidden { display: none; }
+tr.blackhole td { color: #9c0006; background: #ffc7ce; }
+tr:target td { background: #fff3cd; }
+td.lines { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<input id="search" type="search" placeholder="search">
+<span class="meta">generated {{.GeneratedAt}}</span>
+</header>
+<nav>
+{{template "toc" .Report}}
+{{- if .Errors}}
+<p><a href="#errors">Errors ({{len .Errors}})</a></p>
+{{- end}}
+</nav>
+{{template "body" .Report}}
+{{- if .Errors}}
+<h2 id="errors">Errors</h2>
+<table>
+<tr><th>Account</th><th>Region</th><th>Operation</th><th>Resource</th><th>Code</th><th>Message</th></tr>
+{{- range .Errors}}
+<tr><td>{{.Account}}</td><td>{{.Region}}</td><td>{{.Operation}}</td><td>{{.Resource}}</td><td>{{.Code}}</td><td>{{.Message}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+<script>
+(function() {
+  var items = document.querySelectorAll("details.item");
+  document.getElementById("search").addEventListener("input", function(e) {
+    var q = e.target.value.toLowerCase();
+    for (var i = 0; i < items.length; i++) {
+      var hit = q === "" || items[i].textContent.toLowerCase().indexOf(q) >= 0;
+      items[i].classList.toggle("hidden", !hit);
+      items[i].open = q !== "" && hit;
+    }
+  });
+  function openTarget() {
+    var el = location.hash && document.getElementById(location.hash.slice(1));
+    for (; el; el = el.parentElement) {
+      if (el.tagName === "DETAILS") { el.open = true; el.classList.remove("hidden"); }
+    }
+  }
+  window.addEventListener("hashchange", openTarget);
+  openTarget();
+})();
+</script>
+</body>
+</html>
+`
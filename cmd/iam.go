@@ -3,6 +3,7 @@ package cmd
 import (
 	"bytes"
 	"fmt"
+	"html/template"
 	"math"
 	"net/url"
 
@@ -23,24 +24,57 @@ func NewIAMCommand() cli.Command {
 				Usage: "file name to export",
 				Value: "iam",
 			},
+			cli.StringFlag{
+				Name:  "output",
+				Usage: "path to export to, directories created as needed. - for stdout (json, html). overrides --src",
+			},
+			cli.StringFlag{
+				Name:  "format",
+				Usage: "output format: xlsx, json, csv, html or pdf. overrides the global --format",
+			},
 		},
 		Action: func(c *cli.Context) error {
-			if err := util.ConfigAWS(c); err != nil {
+			format := formatName(c)
+			r, err := reportRenderer(c, format, "iam")
+			if err != nil {
 				return util.ErrorRed(err.Error())
 			}
-			mng, err := svc.NewManager()
+			name := outputName(c)
+			targets, err := newTargets(c, false)
 			if err != nil {
 				return util.ErrorRed(err.Error())
 			}
-			iam := &IAM{
-				manager: mng,
-				Errs:    make([]error, 0),
+			groups, err := groupTargets(c, targets, name, format)
+			if err != nil {
+				return util.ErrorRed(err.Error())
+			}
+			iams := make([]*IAM, 0, len(groups))
+			for _, g := range groups {
+				iam := &IAM{
+					Errs: make([]error, 0),
+				}
+				for _, t := range g.targets {
+					iam.collect(t)
+				}
+				iams = append(iams, iam)
+			}
+			printPageStats(targets)
+			errs := make([]error, 0)
+			for _, iam := range iams {
+				if err := iam.flattenErrs(); err != nil && !continueOnError(c) {
+					return util.ErrorRed(err.Error())
+				}
+				errs = append(errs, iam.Errs...)
 			}
-			if err := iam.recursiveConstruct(); err != nil {
+			if err := saveSnapshot(c, targets); err != nil {
 				return util.ErrorRed(err.Error())
 			}
-			iam.convertXlsx(c.String("src"))
-			return nil
+			for i, iam := range iams {
+				if err := r.(IAMRenderer).RenderIAM(iam, groups[i].filename(name)); err != nil {
+					return util.ErrorRed(err.Error())
+				}
+			}
+			return partialReportError(errs)
 		},
 	}
 }
@@ -50,10 +84,75 @@ type IAM struct {
 	Users    []*User
 	Groups   []*Group
 	Roles    []*Role
-	manager  *svc.Manager
+	manager  svc.Manager
+	workers  int
 	Errs     []error
 }
 
+// collect adds the principals and policies of one target to iam
+func (iam *IAM) collect(t *target) {
+	part := &IAM{
+		manager: t.manager,
+		workers: t.workers,
+		Errs:    make([]error, 0),
+	}
+	part.recursiveConstruct()
+	for _, v := range part.Policies {
+		v.Account = t.Account
+	}
+	for _, v := range part.Users {
+		v.Account = t.Account
+	}
+	for _, v := range part.Groups {
+		v.Account = t.Account
+	}
+	for _, v := range part.Roles {
+		v.Account = t.Account
+	}
+	iam.Policies = append(iam.Policies, part.Policies...)
+	iam.Users = append(iam.Users, part.Users...)
+	iam.Groups = append(iam.Groups, part.Groups...)
+	iam.Roles = append(iam.Roles, part.Roles...)
+	for _, err := range part.Errs {
+		iam.stackError(accountError(t, err))
+	}
+}
+
+// byAccount iam split into one per account, in the order collected
+func (iam *IAM) byAccount() []*IAM {
+	accounts := make([]string, 0)
+	m := make(map[string]*IAM)
+	part := func(account string) *IAM {
+		if p, ok := m[account]; ok {
+			return p
+		}
+		accounts = append(accounts, account)
+		m[account] = &IAM{Errs: make([]error, 0)}
+		return m[account]
+	}
+	for _, v := range iam.Policies {
+		p := part(v.Account)
+		p.Policies = append(p.Policies, v)
+	}
+	for _, v := range iam.Groups {
+		p := part(v.Account)
+		p.Groups = append(p.Groups, v)
+	}
+	for _, v := range iam.Users {
+		p := part(v.Account)
+		p.Users = append(p.Users, v)
+	}
+	for _, v := range iam.Roles {
+		p := part(v.Account)
+		p.Roles = append(p.Roles, v)
+	}
+	result := make([]*IAM, 0, len(accounts))
+	for _, a := range accounts {
+		result = append(result, m[a])
+	}
+	return result
+}
+
 func (iam *IAM) recursiveConstruct() error {
 	iam.constructPolicies().
 		constructGroups().
@@ -62,38 +161,67 @@ func (iam *IAM) recursiveConstruct() error {
 	return iam.flattenErrs()
 }
 
+// constructPolicies lists the attached managed policies and gets the
+// document of each. A policy whose document could not be got is kept
+// without one.
 func (iam *IAM) constructPolicies() *IAM {
 	result, err := iam.manager.FetchPolicies()
 	if err != nil {
-		return iam.stackError(err)
+		return iam.stackError(newFetchError("ListPolicies", "", err))
 	}
 	iam.Policies = parseListPoliciesOutputToPolicies(result)
+	errs := make([]error, len(result.Policies))
+	parallel(iam.workers, len(result.Policies), func(i int) {
+		v := result.Policies[i]
+		output, err := iam.manager.FetchPolicyVersion(v.Arn, v.DefaultVersionId)
+		if err != nil {
+			errs[i] = newFetchError("GetPolicyVersion", *v.Arn, err)
+			return
+		}
+		if output.PolicyVersion != nil && output.PolicyVersion.Document != nil {
+			iam.Policies[i].Detail = *output.PolicyVersion.Document
+		}
+	})
+	for _, err := range errs {
+		if err != nil {
+			iam.stackError(err)
+		}
+	}
 	return iam
 }
 
 func (iam *IAM) constructGroups() *IAM {
 	fgResult, err := iam.manager.FetchGroups()
 	if err != nil {
-		return iam.stackError(err)
+		return iam.stackError(newFetchError("ListGroups", "", err))
 	}
-	groups := make([]*Group, 0)
-	for _, v := range fgResult.Groups {
+	found := make([]*Group, len(fgResult.Groups))
+	errs := make([]error, len(fgResult.Groups))
+	parallel(iam.workers, len(fgResult.Groups), func(i int) {
+		v := fgResult.Groups[i]
 		output, err := iam.manager.FetchGroupPolicies(v.GroupName)
 		if err != nil {
-			iam.stackError(err)
-			continue
+			errs[i] = newFetchError("ListGroupPolicies", *v.GroupName, err)
+			return
 		}
 		pns := parseListGroupPoliciesOutput(output)
 		moutput, err := iam.manager.FetchGroupManagedPolicies(v.GroupName)
 		if err != nil {
-			iam.stackError(err)
-			continue
+			errs[i] = newFetchError("ListAttachedGroupPolicies", *v.GroupName, err)
+			return
 		}
 		pns = append(pns, parseListAttachedGroupPoliciesOutput(moutput)...)
-		g := &Group{
+		found[i] = &Group{
 			Name:        *v.GroupName,
 			PolicyNames: pns,
 		}
+	})
+	groups := make([]*Group, 0)
+	for i, g := range found {
+		if errs[i] != nil {
+			iam.stackError(errs[i])
+			continue
+		}
 		groups = append(groups, g)
 	}
 	iam.Groups = groups
@@ -103,30 +231,40 @@ func (iam *IAM) constructGroups() *IAM {
 func (iam *IAM) constructUsers() *IAM {
 	result, err := iam.manager.FetchUsers()
 	if err != nil {
-		return iam.stackError(err)
+		return iam.stackError(newFetchError("ListUsers", "", err))
 	}
-	users := make([]*User, 0)
-	for _, v := range result.Users {
+	found := make([]*User, len(result.Users))
+	errs := make([]error, len(result.Users))
+	parallel(iam.workers, len(result.Users), func(i int) {
+		v := result.Users[i]
 		output, err := iam.manager.FetchUserPolicies(v.UserName)
 		if err != nil {
-			iam.stackError(err)
-			continue
+			errs[i] = newFetchError("ListUserPolicies", *v.UserName, err)
+			return
 		}
 		pns := parseListUserPoliciesOutput(output)
 		moutput, err := iam.manager.FetchUserManagedPolicies(v.UserName)
 		if err != nil {
-			iam.stackError(err)
-			continue
+			errs[i] = newFetchError("ListAttachedUserPolicies", *v.UserName, err)
+			return
 		}
 		pns = append(pns, parseListAttachedUserPoliciesOutput(moutput)...)
 		u := &User{Name: *v.UserName}
 		u.PolicyNames = pns
 		ugOutput, err := iam.manager.FetchUserGroups(v.UserName)
 		if err != nil {
-			iam.stackError(err)
-			continue
+			errs[i] = newFetchError("ListGroupsForUser", *v.UserName, err)
+			return
 		}
 		u.GroupNames = parseListGroupsForUserOutput(ugOutput)
+		found[i] = u
+	})
+	users := make([]*User, 0)
+	for i, u := range found {
+		if errs[i] != nil {
+			iam.stackError(errs[i])
+			continue
+		}
 		users = append(users, u)
 	}
 	iam.Users = users
@@ -136,20 +274,22 @@ func (iam *IAM) constructUsers() *IAM {
 func (iam *IAM) constructRoles() *IAM {
 	result, err := iam.manager.FetchRoles()
 	if err != nil {
-		return iam.stackError(err)
+		return iam.stackError(newFetchError("ListRoles", "", err))
 	}
-	roles := make([]*Role, 0)
-	for _, v := range result.Roles {
+	found := make([]*Role, len(result.Roles))
+	errs := make([]error, len(result.Roles))
+	parallel(iam.workers, len(result.Roles), func(i int) {
+		v := result.Roles[i]
 		output, err := iam.manager.FetchRolePolicies(v.RoleName)
 		if err != nil {
-			iam.stackError(err)
-			continue
+			errs[i] = newFetchError("ListRolePolicies", *v.RoleName, err)
+			return
 		}
 		pns := parseListRolePoliciesOutput(output)
 		moutput, err := iam.manager.FetchRoleManagedPolicies(v.RoleName)
 		if err != nil {
-			iam.stackError(err)
-			continue
+			errs[i] = newFetchError("ListAttachedRolePolicies", *v.RoleName, err)
+			return
 		}
 		pns = append(pns, parseListAttachedRolePoliciesOutput(moutput)...)
 		role := &Role{
@@ -157,34 +297,287 @@ func (iam *IAM) constructRoles() *IAM {
 			AssumeEntity: *v.AssumeRolePolicyDocument,
 		}
 		role.PolicyNames = pns
+		found[i] = role
+	})
+	roles := make([]*Role, 0)
+	for i, role := range found {
+		if errs[i] != nil {
+			iam.stackError(errs[i])
+			continue
+		}
 		roles = append(roles, role)
 	}
 	iam.Roles = roles
 	return iam
 }
 
+func (iam *IAM) convertJSON(filename string) error {
+	report := &jsonIAMReport{
+		jsonHeader: newJSONHeader("iam", iam.Errs),
+		Policies:   make([]*jsonPolicy, 0),
+		Groups:     make([]*jsonGroup, 0),
+		Users:      make([]*jsonUser, 0),
+		Roles:      make([]*jsonRole, 0),
+	}
+	for _, v := range iam.Policies {
+		report.Policies = append(report.Policies, &jsonPolicy{
+			Account:  v.Account,
+			Name:     v.Name,
+			Document: policyDocument(v.Detail),
+		})
+	}
+	for _, v := range iam.Groups {
+		report.Groups = append(report.Groups, &jsonGroup{
+			Account:     v.Account,
+			Name:        v.Name,
+			PolicyNames: nonNil(v.PolicyNames),
+		})
+	}
+	for _, v := range iam.Users {
+		report.Users = append(report.Users, &jsonUser{
+			Account:     v.Account,
+			Name:        v.Name,
+			GroupNames:  nonNil(v.GroupNames),
+			PolicyNames: nonNil(v.PolicyNames),
+		})
+	}
+	for _, v := range iam.Roles {
+		report.Roles = append(report.Roles, &jsonRole{
+			Account:          v.Account,
+			Name:             v.Name,
+			AssumeRolePolicy: policyDocument(v.AssumeEntity),
+			PolicyNames:      nonNil(v.PolicyNames),
+		})
+	}
+	return writeJSON(filename, report)
+}
+
+// convertCSV writes policies, groups, users, roles, iam_attachments (one row
+// per principal and policy) and user_groups into dir
+func (iam *IAM) convertCSV(dir string) error {
+	policies := newCSVTable("policies", "account", "policy_name", "document")
+	groups := newCSVTable("groups", "account", "group_name")
+	users := newCSVTable("users", "account", "user_name")
+	roles := newCSVTable("roles", "account", "role_name", "assume_role_policy")
+	attachments := newCSVTable("iam_attachments", "account", "principal", "type", "policy_name")
+	userGroups := newCSVTable("user_groups", "account", "user_name", "group_name")
+	for _, v := range iam.Policies {
+		policies.add(v.Account, v.Name, policyDocument(v.Detail))
+	}
+	for _, v := range iam.Groups {
+		groups.add(v.Account, v.Name)
+		for _, p := range v.PolicyNames {
+			attachments.add(v.Account, v.Name, "group", p)
+		}
+	}
+	for _, v := range iam.Users {
+		users.add(v.Account, v.Name)
+		for _, p := range v.PolicyNames {
+			attachments.add(v.Account, v.Name, "user", p)
+		}
+		for _, g := range v.GroupNames {
+			userGroups.add(v.Account, v.Name, g)
+		}
+	}
+	for _, v := range iam.Roles {
+		roles.add(v.Account, v.Name, policyDocument(v.AssumeEntity))
+		for _, p := range v.PolicyNames {
+			attachments.add(v.Account, v.Name, "role", p)
+		}
+	}
+	return writeCSV(dir, policies, groups, users, roles, attachments, userGroups, csvErrors(iam.Errs))
+}
+
+func (iam *IAM) convertHTML(filename string) error {
+	policies := make(map[[2]string]bool)
+	for _, v := range iam.Policies {
+		policies[[2]string{v.Account, v.Name}] = true
+	}
+	groups := make(map[[2]string]bool)
+	for _, v := range iam.Groups {
+		groups[[2]string{v.Account, v.Name}] = true
+	}
+	funcs := template.FuncMap{
+		// policyAnchor anchor of a managed policy, empty for inline ones
+		"policyAnchor": func(account, name string) string {
+			if !policies[[2]string{account, name}] {
+				return ""
+			}
+			return anchor("policy", account, name)
+		},
+		// groupAnchor anchor of a group, empty when it was not collected
+		"groupAnchor": func(account, name string) string {
+			if !groups[[2]string{account, name}] {
+				return ""
+			}
+			return anchor("group", account, name)
+		},
+	}
+	return writeHTML(filename, "iam", iamHTML, iam, iam.Errs, funcs)
+}
+
+const iamHTML = `
+{{define "toc"}}
+<strong>Users</strong>
+<ul>
+{{- range .Users}}
+<li><a href="#{{anchor "user" .Account .Name}}">{{.Name}}</a> <span class="meta">{{.Account}}</span></li>
+{{- end}}
+</ul>
+<strong>Groups</strong>
+<ul>
+{{- range .Groups}}
+<li><a href="#{{anchor "group" .Account .Name}}">{{.Name}}</a> <span class="meta">{{.Account}}</span></li>
+{{- end}}
+</ul>
+<strong>Roles</strong>
+<ul>
+{{- range .Roles}}
+<li><a href="#{{anchor "role" .Account .Name}}">{{.Name}}</a> <span class="meta">{{.Account}}</span></li>
+{{- end}}
+</ul>
+<p><a href="#policies">Policies ({{len .Policies}})</a></p>
+{{end}}
+
+{{define "body"}}
+<h2>Users</h2>
+{{- range $u := .Users}}
+<details class="item" id="{{anchor "user" .Account .Name}}">
+<summary>{{.Name}} <span class="meta">{{.Account}}</span></summary>
+<table>
+<tr><th>Groups</th><td>{{range .GroupNames}}{{$a := groupAnchor $u.Account .}}{{if $a}}<a href="#{{$a}}">{{.}}</a>{{else}}{{.}}{{end}}<br>{{end}}</td></tr>
+<tr><th>Policies</th><td>{{range .PolicyNames}}{{$a := policyAnchor $u.Account .}}{{if $a}}<a href="#{{$a}}">{{.}}</a>{{else}}{{.}}{{end}}<br>{{end}}</td></tr>
+</table>
+</details>
+{{- end}}
+
+<h2>Groups</h2>
+{{- range $g := .Groups}}
+<details class="item" id="{{anchor "group" .Account .Name}}">
+<summary>{{.Name}} <span class="meta">{{.Account}}</span></summary>
+<table>
+<tr><th>Policies</th><td>{{range .PolicyNames}}{{$a := policyAnchor $g.Account .}}{{if $a}}<a href="#{{$a}}">{{.}}</a>{{else}}{{.}}{{end}}<br>{{end}}</td></tr>
+</table>
+</details>
+{{- end}}
+
+<h2>Roles</h2>
+{{- range $r := .Roles}}
+<details class="item" id="{{anchor "role" .Account .Name}}">
+<summary>{{.Name}} <span class="meta">{{.Account}}</span></summary>
+<table>
+<tr><th>Policies</th><td>{{range .PolicyNames}}{{$a := policyAnchor $r.Account .}}{{if $a}}<a href="#{{$a}}">{{.}}</a>{{else}}{{.}}{{end}}<br>{{end}}</td></tr>
+<tr><th>Assume Entity</th><td><pre>{{prettyDocument .AssumeEntity}}</pre></td></tr>
+</table>
+</details>
+{{- end}}
+
+<h2 id="policies">Policies</h2>
+{{- range .Policies}}
+<details class="item" id="{{anchor "policy" .Account .Name}}">
+<summary>{{.Name}} <span class="meta">{{.Account}}</span></summary>
+<pre>{{prettyDocument .Detail}}</pre>
+</details>
+{{- end}}
+{{end}}
+`
+
+// convertPdf writes <filename>.pdf with users, groups and roles linking to
+// their groups and managed policies, then the policy documents
+func (iam *IAM) convertPdf(filename string) error {
+	d := newPdfDoc()
+	// anchors of the policies and groups written, the only ones to link to
+	rendered := make(map[string]bool)
+	for _, v := range iam.Policies {
+		rendered[anchor("policy", v.Account, v.Name)] = true
+	}
+	for _, v := range iam.Groups {
+		rendered[anchor("group", v.Account, v.Name)] = true
+	}
+	// linkRows one row per name, linking to the rendered ones
+	linkRows := func(kind, title, account string, names []string) [][]pdfCell {
+		rows := make([][]pdfCell, 0, len(names))
+		for _, v := range names {
+			cell := pdfCell{text: v}
+			if key := anchor(kind, account, v); rendered[key] {
+				cell.link = d.link(key)
+			}
+			rows = append(rows, []pdfCell{{text: title}, cell})
+		}
+		return rows
+	}
+	policyRows := func(account string, names []string) [][]pdfCell {
+		return linkRows("policy", "Policy", account, names)
+	}
+
+	d.heading("Users", 0)
+	for _, v := range iam.Users {
+		d.heading(fmt.Sprintf("%s  %s", v.Name, v.Account), 1)
+		d.anchor(anchor("user", v.Account, v.Name))
+		groupRows := linkRows("group", "Group", v.Account, v.GroupNames)
+		d.table([]float64{40, 150}, nil, append(groupRows, policyRows(v.Account, v.PolicyNames)...))
+	}
+
+	d.AddPage()
+	d.heading("Groups", 0)
+	for _, v := range iam.Groups {
+		d.heading(fmt.Sprintf("%s  %s", v.Name, v.Account), 1)
+		d.anchor(anchor("group", v.Account, v.Name))
+		d.table([]float64{40, 150}, nil, policyRows(v.Account, v.PolicyNames))
+	}
+
+	d.AddPage()
+	d.heading("Roles", 0)
+	for _, v := range iam.Roles {
+		d.heading(fmt.Sprintf("%s  %s", v.Name, v.Account), 1)
+		d.anchor(anchor("role", v.Account, v.Name))
+		d.table([]float64{40, 150}, nil, policyRows(v.Account, v.PolicyNames))
+		d.text("Assume Entity")
+		d.SetFont("Courier", "", 8)
+		d.text(prettyDocument(v.AssumeEntity))
+		d.SetFont("Arial", "", 9)
+		d.Ln(pdfLineHeight)
+	}
+
+	d.AddPage()
+	d.heading("Policies", 0)
+	for _, v := range iam.Policies {
+		d.heading(fmt.Sprintf("%s  %s", v.Name, v.Account), 1)
+		d.anchor(anchor("policy", v.Account, v.Name))
+		d.SetFont("Courier", "", 8)
+		d.text(prettyDocument(v.Detail))
+		d.SetFont("Arial", "", 9)
+		d.Ln(pdfLineHeight)
+	}
+
+	if len(iam.Errs) > 0 {
+		d.AddPage()
+		d.heading("Errors", 0)
+		for _, err := range iam.Errs {
+			d.text(err.Error())
+		}
+	}
+	path, err := createPath(filename, "pdf")
+	if err != nil {
+		return err
+	}
+	return d.OutputFileAndClose(path)
+}
+
 func (iam *IAM) stackError(err error) *IAM {
 	iam.Errs = append(iam.Errs, err)
 	return iam
 }
 
 func (iam *IAM) flattenErrs() error {
-	if len(iam.Errs) == 0 {
-		return nil
-	}
-	var errStr string
-	for _, e := range iam.Errs {
-		errStr = errStr + e.Error() + "\n"
-	}
-	return fmt.Errorf(errStr)
+	return flattenErrors(iam.Errs)
 }
 
 func parseListPoliciesOutputToPolicies(output *iam.ListPoliciesOutput) []*Policy {
 	pls := make([]*Policy, 0)
 	for _, v := range output.Policies {
 		p := &Policy{
-			Name:   *v.PolicyName,
-			Detail: *v.Description,
+			Name: *v.PolicyName,
 		}
 		pls = append(pls, p)
 	}
@@ -192,7 +585,7 @@ func parseListPoliciesOutputToPolicies(output *iam.ListPoliciesOutput) []*Policy
 }
 
 func parseListUserPoliciesOutput(output *iam.ListUserPoliciesOutput) []string {
-	pns := make([]string, len(output.PolicyNames))
+	pns := make([]string, 0, len(output.PolicyNames))
 	for _, v := range output.PolicyNames {
 		pns = append(pns, *v)
 	}
@@ -200,7 +593,7 @@ func parseListUserPoliciesOutput(output *iam.ListUserPoliciesOutput) []string {
 }
 
 func parseListAttachedUserPoliciesOutput(output *iam.ListAttachedUserPoliciesOutput) []string {
-	pns := make([]string, len(output.AttachedPolicies))
+	pns := make([]string, 0, len(output.AttachedPolicies))
 	for _, v := range output.AttachedPolicies {
 		pns = append(pns, *v.PolicyName)
 	}
@@ -208,7 +601,7 @@ func parseListAttachedUserPoliciesOutput(output *iam.ListAttachedUserPoliciesOut
 }
 
 func parseListGroupsForUserOutput(output *iam.ListGroupsForUserOutput) []string {
-	gs := make([]string, len(output.Groups))
+	gs := make([]string, 0, len(output.Groups))
 	for _, v := range output.Groups {
 		gs = append(gs, *v.GroupName)
 	}
@@ -216,7 +609,7 @@ func parseListGroupsForUserOutput(output *iam.ListGroupsForUserOutput) []string
 }
 
 func parseListGroupPoliciesOutput(output *iam.ListGroupPoliciesOutput) []string {
-	pns := make([]string, len(output.PolicyNames))
+	pns := make([]string, 0, len(output.PolicyNames))
 	for _, v := range output.PolicyNames {
 		pns = append(pns, *v)
 	}
@@ -224,7 +617,7 @@ func parseListGroupPoliciesOutput(output *iam.ListGroupPoliciesOutput) []string
 }
 
 func parseListAttachedGroupPoliciesOutput(output *iam.ListAttachedGroupPoliciesOutput) []string {
-	pns := make([]string, len(output.AttachedPolicies))
+	pns := make([]string, 0, len(output.AttachedPolicies))
 	for _, v := range output.AttachedPolicies {
 		pns = append(pns, *v.PolicyName)
 	}
@@ -232,7 +625,7 @@ func parseListAttachedGroupPoliciesOutput(output *iam.ListAttachedGroupPoliciesO
 }
 
 func parseListRolePoliciesOutput(output *iam.ListRolePoliciesOutput) []string {
-	pns := make([]string, len(output.PolicyNames))
+	pns := make([]string, 0, len(output.PolicyNames))
 	for _, v := range output.PolicyNames {
 		pns = append(pns, *v)
 	}
@@ -240,21 +633,86 @@ func parseListRolePoliciesOutput(output *iam.ListRolePoliciesOutput) []string {
 }
 
 func parseListAttachedRolePoliciesOutput(output *iam.ListAttachedRolePoliciesOutput) []string {
-	pns := make([]string, len(output.AttachedPolicies))
+	pns := make([]string, 0, len(output.AttachedPolicies))
 	for _, v := range output.AttachedPolicies {
 		pns = append(pns, *v.PolicyName)
 	}
 	return pns
 }
 
-func (iam *IAM) convertXlsx(filename string) {
-	file := xlsx.NewFile()
+// iamSheets the sheets of one account
+type iamSheets struct {
+	policy *xlsx.Sheet
+	group  *xlsx.Sheet
+	user   *xlsx.Sheet
+	role   *xlsx.Sheet
+}
 
-	//policy
-	policySheet, err := file.AddSheet("policy")
+// addIAMSheets adds the sheets of one account to file, their names starting
+// with account unless it is empty
+func addIAMSheets(file *xlsx.File, account string) (*iamSheets, error) {
+	sheets := make([]*xlsx.Sheet, 0, 4)
+	for _, kind := range []string{"policy", "group", "user", "role"} {
+		sheet, err := addSheet(file, sheetName(account, kind))
+		if err != nil {
+			return nil, err
+		}
+		sheets = append(sheets, sheet)
+	}
+	return &iamSheets{policy: sheets[0], group: sheets[1], user: sheets[2], role: sheets[3]}, nil
+}
+
+func (iam *IAM) convertXlsx(filename string) error {
+	file := xlsx.NewFile()
+	iam.addXlsxSheets(file)
+	addErrorsSheet(file, iam.Errs)
+	path, err := createPath(filename, "xlsx")
 	if err != nil {
-		util.PrintlnRed(err.Error())
+		return err
 	}
+	return file.Save(path)
+}
+
+// addXlsxSheets adds the policy, group, user and role sheets of every
+// account to file and returns their names
+func (iam *IAM) addXlsxSheets(file *xlsx.File) []string {
+	names := make([]string, 0)
+	parts := iam.byAccount()
+	multiAccount := len(parts) > 1
+	for _, p := range parts {
+		var account string
+		if multiAccount {
+			account = p.account()
+		}
+		sheets, err := addIAMSheets(file, account)
+		if err != nil {
+			util.PrintlnRed(err.Error())
+			continue
+		}
+		names = append(names, sheets.policy.Name, sheets.group.Name, sheets.user.Name, sheets.role.Name)
+		p.convertAccountToXlsx(sheets)
+	}
+	return names
+}
+
+// account account iam was collected from
+func (iam *IAM) account() string {
+	switch {
+	case len(iam.Policies) > 0:
+		return iam.Policies[0].Account
+	case len(iam.Groups) > 0:
+		return iam.Groups[0].Account
+	case len(iam.Users) > 0:
+		return iam.Users[0].Account
+	case len(iam.Roles) > 0:
+		return iam.Roles[0].Account
+	}
+	return ""
+}
+
+func (iam *IAM) convertAccountToXlsx(sheets *iamSheets) {
+	//policy
+	policySheet := sheets.policy
 	policyLocation := make(map[string][2]int)
 	currentPolicyRow := 0
 	for _, v := range iam.Policies {
@@ -277,10 +735,7 @@ func (iam *IAM) convertXlsx(filename string) {
 	}
 
 	//group
-	groupSheet, err := file.AddSheet("group")
-	if err != nil {
-		util.PrintlnRed(err.Error())
-	}
+	groupSheet := sheets.group
 	groupLocation := make(map[string][2]int)
 	currentGroupRow := 0
 	for _, v := range iam.Groups {
@@ -293,7 +748,7 @@ func (iam *IAM) convertXlsx(filename string) {
 			if !ok {
 				continue
 			}
-			groupSheet.Cell(currentGroupRow, 0).SetFormula(hyperlink("policy", loc[0], loc[1], up))
+			groupSheet.Cell(currentGroupRow, 0).SetFormula(hyperlink(sheets.policy.Name, loc[0], loc[1], up))
 			groupSheet.Cell(currentGroupRow, 0).SetStyle(borderWithAlign("lr", false))
 			currentGroupRow++
 		}
@@ -302,10 +757,7 @@ func (iam *IAM) convertXlsx(filename string) {
 	}
 
 	//user
-	userSheet, err := file.AddSheet("user")
-	if err != nil {
-		util.PrintlnRed(err.Error())
-	}
+	userSheet := sheets.user
 	currentUserRow := 0
 	for _, v := range iam.Users {
 		userSheet.Cell(currentUserRow, 0).Value = v.Name
@@ -323,7 +775,7 @@ func (iam *IAM) convertXlsx(filename string) {
 			if !ok {
 				continue
 			}
-			userSheet.Cell(currentUserRow+ugNo, 0).SetFormula(hyperlink("group", loc[0], loc[1], gn))
+			userSheet.Cell(currentUserRow+ugNo, 0).SetFormula(hyperlink(sheets.group.Name, loc[0], loc[1], gn))
 			userSheet.Cell(currentUserRow+ugNo, 0).SetStyle(borderWithAlign("lr", false))
 			ugNo++
 		}
@@ -333,7 +785,7 @@ func (iam *IAM) convertXlsx(filename string) {
 			if !ok {
 				continue
 			}
-			userSheet.Cell(currentUserRow+upnNo, 1).SetFormula(hyperlink("policy", loc[0], loc[1], up))
+			userSheet.Cell(currentUserRow+upnNo, 1).SetFormula(hyperlink(sheets.policy.Name, loc[0], loc[1], up))
 			userSheet.Cell(currentUserRow+upnNo, 1).SetStyle(borderWithAlign("lr", false))
 			upnNo++
 		}
@@ -349,10 +801,7 @@ func (iam *IAM) convertXlsx(filename string) {
 	}
 
 	//role
-	roleSheet, err := file.AddSheet("role")
-	if err != nil {
-		util.PrintlnRed(err.Error())
-	}
+	roleSheet := sheets.role
 	currentRoleRow := 0
 	for _, v := range iam.Roles {
 		roleSheet.Cell(currentRoleRow, 0).Value = v.Name
@@ -380,7 +829,7 @@ func (iam *IAM) convertXlsx(filename string) {
 			if !ok {
 				continue
 			}
-			roleSheet.Cell(currentRoleRow+pnNo, 1).SetFormula(hyperlink("policy", loc[0], loc[1], up))
+			roleSheet.Cell(currentRoleRow+pnNo, 1).SetFormula(hyperlink(sheets.policy.Name, loc[0], loc[1], up))
 			roleSheet.Cell(currentRoleRow+pnNo, 1).SetStyle(borderWithAlign("lr", false))
 			pnNo++
 		}
@@ -394,7 +843,4 @@ func (iam *IAM) convertXlsx(filename string) {
 		roleSheet.Cell(currentRoleRow, 1).SetStyle(borderWithAlign("t", false))
 		currentRoleRow++
 	}
-	if err := file.Save(fmt.Sprintf("./%s.xlsx", filename)); err != nil {
-		iam.stackError(err)
-	}
 }
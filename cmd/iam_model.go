@@ -1,22 +1,26 @@
 package cmd
 
 type Policy struct {
-	Name   string
-	Detail string
+	Account string
+	Name    string
+	Detail  string
 }
 
 type User struct {
+	Account     string
 	Name        string
 	PolicyNames []string
 	GroupNames  []string
 }
 
 type Group struct {
+	Account     string
 	Name        string
 	PolicyNames []string
 }
 
 type Role struct {
+	Account      string
 	Name         string
 	PolicyNames  []string
 	AssumeEntity string
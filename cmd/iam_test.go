@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atsushi-ishibashi/aws-state-report/svc"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/tealeg/xlsx"
+)
+
+func collectFixtureIAM(t *testing.T) *IAM {
+	t.Helper()
+	iam := &IAM{Errs: make([]error, 0)}
+	iam.collect(fixtureTarget(t))
+	if err := iam.flattenErrs(); err != nil {
+		t.Fatal(err)
+	}
+	return iam
+}
+
+func TestIAMCollect(t *testing.T) {
+	iam := collectFixtureIAM(t)
+	if len(iam.Users) != 1 || len(iam.Groups) != 1 || len(iam.Roles) != 1 || len(iam.Policies) != 1 {
+		t.Fatalf("users, groups, roles, policies = %d, %d, %d, %d", len(iam.Users), len(iam.Groups), len(iam.Roles), len(iam.Policies))
+	}
+	if got := strings.Join(iam.Users[0].GroupNames, ","); got != "operators" {
+		t.Errorf("alice groups = %q", got)
+	}
+	if got := strings.Join(iam.Roles[0].PolicyNames, ","); got != "SampleReadOnly" {
+		t.Errorf("role policies = %q", got)
+	}
+}
+
+// versionFailingManager fails every GetPolicyVersion
+type versionFailingManager struct {
+	svc.Manager
+}
+
+func (m *versionFailingManager) FetchPolicyVersion(arn, version *string) (*iam.GetPolicyVersionOutput, error) {
+	return nil, errors.New("AccessDenied")
+}
+
+func TestIAMCollectPolicyVersionError(t *testing.T) {
+	target := fixtureTarget(t)
+	target.manager = &versionFailingManager{Manager: target.manager}
+	iam := &IAM{Errs: make([]error, 0)}
+	iam.collect(target)
+	if len(iam.Policies) != 1 || iam.Policies[0].Name != "SampleReadOnly" || iam.Policies[0].Detail != "" {
+		t.Fatalf("policies = %v, want SampleReadOnly without a document", iam.Policies)
+	}
+	if len(iam.Groups) != 1 || len(iam.Roles) != 1 {
+		t.Errorf("groups, roles = %d, %d", len(iam.Groups), len(iam.Roles))
+	}
+	if len(iam.Errs) != 1 {
+		t.Fatalf("errs = %v", iam.Errs)
+	}
+	var fe *fetchError
+	if !errors.As(iam.Errs[0], &fe) || fe.Operation != "GetPolicyVersion" || !strings.HasSuffix(fe.Resource, "policy/SampleReadOnly") {
+		t.Errorf("err = %#v", iam.Errs[0])
+	}
+}
+
+func TestIAMConvertCSV(t *testing.T) {
+	iam := collectFixtureIAM(t)
+	dir := t.TempDir()
+	if err := iam.convertCSV(dir); err != nil {
+		t.Fatal(err)
+	}
+	if findRow(csvRows(t, dir, "user_groups"), map[string]string{"user_name": "alice", "group_name": "operators"}) == nil {
+		t.Error("alice in operators missing")
+	}
+	attachments := csvRows(t, dir, "iam_attachments")
+	for _, principal := range []string{"operators", "sample-ec2-role"} {
+		if findRow(attachments, map[string]string{"principal": principal, "policy_name": "SampleReadOnly"}) == nil {
+			t.Errorf("%s attachment missing", principal)
+		}
+	}
+}
+
+func TestIAMXlsxSheets(t *testing.T) {
+	iam := collectFixtureIAM(t)
+	file := xlsx.NewFile()
+	sheets := iam.addXlsxSheets(file)
+	if strings.Join(sheets, ",") != "policy,group,user,role" {
+		t.Fatalf("sheets = %v", sheets)
+	}
+	if v := file.Sheet["user"].Cell(0, 0).Value; v != "alice" {
+		t.Errorf("user = %q", v)
+	}
+	if f := file.Sheet["user"].Cell(2, 0).Formula(); !strings.Contains(f, "'group'!A1") {
+		t.Errorf("alice group link = %q", f)
+	}
+	if f := file.Sheet["group"].Cell(1, 0).Formula(); !strings.Contains(f, "'policy'!A1") {
+		t.Errorf("operators policy link = %q", f)
+	}
+}
+
+func TestIAMConvertHTMLUncollectedGroup(t *testing.T) {
+	iam := collectFixtureIAM(t)
+	iam.Users[0].GroupNames = append(iam.Users[0].GroupNames, "uncollected")
+	filename := filepath.Join(t.TempDir(), "iam")
+	if err := iam.convertHTML(filename); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filename + ".html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(b)
+	if !strings.Contains(html, `href="#`+anchor("group", "", "operators")+`"`) {
+		t.Error("link to operators missing")
+	}
+	if strings.Contains(html, `href="#`+anchor("group", "", "uncollected")+`"`) {
+		t.Error("links to the uncollected group")
+	}
+}
+
+func TestIAMConvertPdf(t *testing.T) {
+	iam := collectFixtureIAM(t)
+	iam.Users[0].GroupNames = append(iam.Users[0].GroupNames, "uncollected")
+	if err := iam.convertPdf(filepath.Join(t.TempDir(), "iam")); err != nil {
+		t.Fatal(err)
+	}
+}
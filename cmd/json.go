@@ -0,0 +1,384 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// jsonSchemaVersion version of the JSON reports. Bump it when a field is
+// removed or changes meaning; adding fields does not need a bump.
+const jsonSchemaVersion = 2
+
+// jsonHeader fields every JSON report starts with
+type jsonHeader struct {
+	SchemaVersion int          `json:"schema_version"`
+	Report        string       `json:"report"`
+	GeneratedAt   time.Time    `json:"generated_at"`
+	Errors        []*jsonError `json:"errors,omitempty"`
+}
+
+func newJSONHeader(report string, errs []error) jsonHeader {
+	return jsonHeader{
+		SchemaVersion: jsonSchemaVersion,
+		Report:        report,
+		GeneratedAt:   time.Now().UTC(),
+		Errors:        jsonErrors(errs),
+	}
+}
+
+// jsonError a failed API call, listed with --continue-on-error
+type jsonError struct {
+	Account   string `json:"account,omitempty"`
+	Region    string `json:"region,omitempty"`
+	Operation string `json:"operation,omitempty"`
+	Resource  string `json:"resource,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message"`
+}
+
+func jsonErrors(errs []error) []*jsonError {
+	if len(errs) == 0 {
+		return nil
+	}
+	result := make([]*jsonError, 0, len(errs))
+	for _, err := range errs {
+		if fe, ok := err.(*fetchError); ok {
+			result = append(result, &jsonError{
+				Account:   fe.Account,
+				Region:    fe.Region,
+				Operation: fe.Operation,
+				Resource:  fe.Resource,
+				Code:      fe.Code,
+				Message:   fe.message(),
+			})
+		} else {
+			result = append(result, &jsonError{Message: err.Error()})
+		}
+	}
+	return result
+}
+
+// jsonNetworkReport network report. Route tables, subnets, gateways and
+// endpoints refer to their VPC by vpc_id, subnets to the route table they
+// use by route_table_id, explicitly associated or, with
+// implicit_association, the main one. A route's target is the id of the
+// gateway, endpoint, peering connection or transit gateway it goes to, and
+// those list the route tables routing to them by route_table_ids.
+// connectivity lists which VPC reaches which, through what.
+type jsonNetworkReport struct {
+	jsonHeader
+	Vpcs                       []*jsonVpc               `json:"vpcs"`
+	RouteTables                []*jsonRouteTable        `json:"route_tables"`
+	Subnets                    []*jsonSubnet            `json:"subnets"`
+	InternetGateways           []*jsonInternetGateway   `json:"internet_gateways"`
+	EgressOnlyInternetGateways []*jsonInternetGateway   `json:"egress_only_internet_gateways"`
+	NatGateways                []*jsonNatGateway        `json:"nat_gateways"`
+	VpcEndpoints               []*jsonVpcEndpoint       `json:"vpc_endpoints"`
+	VpnGateways                []*jsonVpnGateway        `json:"vpn_gateways"`
+	PeeringConnections         []*jsonPeeringConnection `json:"peering_connections"`
+	TransitGateways            []*jsonTransitGateway    `json:"transit_gateways"`
+	Connectivity               []*jsonConnection        `json:"connectivity"`
+}
+
+type jsonVpc struct {
+	ID                   string   `json:"id"`
+	Account              string   `json:"account,omitempty"`
+	Region               string   `json:"region"`
+	Name                 string   `json:"name"`
+	CidrBlock            string   `json:"cidr_block"`
+	AssociatedCidrBlocks []string `json:"associated_cidr_blocks"`
+	Ipv6CidrBlocks       []string `json:"ipv6_cidr_blocks"`
+	RouteTableIDs        []string `json:"route_table_ids"`
+	SubnetIDs            []string `json:"subnet_ids"`
+}
+
+type jsonRouteTable struct {
+	ID     string       `json:"id"`
+	VpcID  string       `json:"vpc_id"`
+	Name   string       `json:"name"`
+	Main   bool         `json:"main"`
+	Routes []*jsonRoute `json:"routes"`
+	// Subnets explicitly associated subnets
+	Subnets []string `json:"subnet_ids"`
+	// Gateways internet or virtual private gateways of an edge association
+	Gateways []string `json:"gateway_ids"`
+}
+
+type jsonRoute struct {
+	// Destination IPv4 CIDR, IPv6 CIDR or prefix list ID
+	Destination     string   `json:"destination"`
+	PrefixListName  string   `json:"prefix_list_name,omitempty"`
+	PrefixListCidrs []string `json:"prefix_list_cidrs,omitempty"`
+	// Target ID of the gateway, interface, instance or other target, "local"
+	// within the VPC
+	Target string `json:"target"`
+	// TargetType e.g. internet-gateway, nat-gateway, transit-gateway, instance
+	TargetType string `json:"target_type"`
+	State      string `json:"state"`
+	Origin     string `json:"origin"`
+	Blackhole  bool   `json:"blackhole"`
+}
+
+type jsonSubnet struct {
+	ID             string   `json:"id"`
+	VpcID          string   `json:"vpc_id"`
+	Name           string   `json:"name"`
+	CidrBlock      string   `json:"cidr_block"`
+	Ipv6CidrBlocks []string `json:"ipv6_cidr_blocks"`
+	RouteTableID   string   `json:"route_table_id,omitempty"`
+	// ImplicitAssociation the subnet uses the main route table
+	ImplicitAssociation bool `json:"implicit_association"`
+}
+
+type jsonInternetGateway struct {
+	ID    string `json:"id"`
+	VpcID string `json:"vpc_id"`
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+type jsonNatGateway struct {
+	ID    string `json:"id"`
+	VpcID string `json:"vpc_id"`
+	Name  string `json:"name"`
+	State string `json:"state"`
+	// ConnectivityType public or private
+	ConnectivityType string                   `json:"connectivity_type"`
+	SubnetID         string                   `json:"subnet_id"`
+	Addresses        []*jsonNatGatewayAddress `json:"addresses"`
+}
+
+type jsonNatGatewayAddress struct {
+	AllocationID       string `json:"allocation_id,omitempty"`
+	PublicIP           string `json:"public_ip,omitempty"`
+	PrivateIP          string `json:"private_ip"`
+	NetworkInterfaceID string `json:"network_interface_id"`
+}
+
+type jsonVpcEndpoint struct {
+	ID    string `json:"id"`
+	VpcID string `json:"vpc_id"`
+	Name  string `json:"name"`
+	// Type Gateway, Interface or GatewayLoadBalancer
+	Type          string   `json:"type"`
+	ServiceName   string   `json:"service_name"`
+	State         string   `json:"state"`
+	SubnetIDs     []string `json:"subnet_ids"`
+	RouteTableIDs []string `json:"route_table_ids"`
+	Policy        string   `json:"policy,omitempty"`
+}
+
+type jsonVpnGateway struct {
+	ID            string               `json:"id"`
+	VpcID         string               `json:"vpc_id"`
+	Name          string               `json:"name"`
+	State         string               `json:"state"`
+	Type          string               `json:"type"`
+	AmazonSideAsn int64                `json:"amazon_side_asn"`
+	Connections   []*jsonVpnConnection `json:"vpn_connections"`
+}
+
+type jsonVpnConnection struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	State string `json:"state"`
+	Type  string `json:"type"`
+	// CustomerGateway nil if it could not be described
+	CustomerGatewayID string               `json:"customer_gateway_id"`
+	CustomerGateway   *jsonCustomerGateway `json:"customer_gateway,omitempty"`
+}
+
+type jsonCustomerGateway struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	State      string `json:"state"`
+	Type       string `json:"type"`
+	IPAddress  string `json:"ip_address"`
+	BgpAsn     string `json:"bgp_asn"`
+	DeviceName string `json:"device_name,omitempty"`
+}
+
+type jsonPeeringConnection struct {
+	ID            string          `json:"id"`
+	Name          string          `json:"name"`
+	Status        string          `json:"status"`
+	StatusMessage string          `json:"status_message,omitempty"`
+	Requester     *jsonPeeringVpc `json:"requester"`
+	Accepter      *jsonPeeringVpc `json:"accepter"`
+	RouteTableIDs []string        `json:"route_table_ids"`
+}
+
+type jsonPeeringVpc struct {
+	VpcID      string   `json:"vpc_id"`
+	Account    string   `json:"account"`
+	Region     string   `json:"region"`
+	CidrBlocks []string `json:"cidr_blocks"`
+}
+
+type jsonTransitGateway struct {
+	ID          string                          `json:"id"`
+	OwnerID     string                          `json:"owner_id"`
+	Attachments []*jsonTransitGatewayAttachment `json:"attachments"`
+	// RouteTables empty unless the owner of the transit gateway was collected
+	RouteTables   []*jsonTransitGatewayRouteTable `json:"transit_gateway_route_tables"`
+	RouteTableIDs []string                        `json:"route_table_ids"`
+}
+
+type jsonTransitGatewayAttachment struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// ResourceType vpc, vpn, direct-connect-gateway, peering or connect
+	ResourceType  string `json:"resource_type"`
+	ResourceID    string `json:"resource_id"`
+	ResourceOwner string `json:"resource_owner"`
+	State         string `json:"state"`
+	// RouteTableID associated transit gateway route table
+	RouteTableID string `json:"transit_gateway_route_table_id,omitempty"`
+}
+
+type jsonTransitGatewayRouteTable struct {
+	ID                 string                     `json:"id"`
+	Name               string                     `json:"name"`
+	DefaultAssociation bool                       `json:"default_association"`
+	DefaultPropagation bool                       `json:"default_propagation"`
+	Routes             []*jsonTransitGatewayRoute `json:"routes"`
+}
+
+type jsonTransitGatewayRoute struct {
+	// Destination CIDR or prefix list ID
+	Destination   string   `json:"destination"`
+	Type          string   `json:"type"`
+	State         string   `json:"state"`
+	AttachmentIDs []string `json:"attachment_ids"`
+}
+
+// jsonConnection from_vpc_id reaches to_vpc_id through the peering
+// connection or transit gateway via
+type jsonConnection struct {
+	FromVpcID string `json:"from_vpc_id"`
+	ToVpcID   string `json:"to_vpc_id"`
+	Via       string `json:"via"`
+	// Type vpc-peering-connection or transit-gateway
+	Type   string `json:"type"`
+	Status string `json:"status,omitempty"`
+	// RouteTableID transit gateway route table routing it, empty when the
+	// route tables were not collected
+	RouteTableID string `json:"transit_gateway_route_table_id,omitempty"`
+}
+
+// jsonSGReport sg report. Security groups list their interfaces by
+// network_interface_ids, interfaces their groups by security_group_ids and
+// their instance by instance_id.
+type jsonSGReport struct {
+	jsonHeader
+	SecurityGroups    []*jsonSecurityGroup    `json:"security_groups"`
+	NetworkInterfaces []*jsonNetworkInterface `json:"network_interfaces"`
+	Instances         []*jsonInstance         `json:"instances"`
+}
+
+type jsonSecurityGroup struct {
+	ID                  string      `json:"id"`
+	Account             string      `json:"account,omitempty"`
+	Region              string      `json:"region"`
+	GroupName           string      `json:"group_name"`
+	Name                string      `json:"name"`
+	Description         string      `json:"description"`
+	Ingress             []*jsonRule `json:"ingress"`
+	Egress              []*jsonRule `json:"egress"`
+	NetworkInterfaceIDs []string    `json:"network_interface_ids"`
+}
+
+type jsonRule struct {
+	// Protocol "-1" for all protocols
+	Protocol         string   `json:"protocol"`
+	FromPort         int64    `json:"from_port"`
+	ToPort           int64    `json:"to_port"`
+	CidrRanges       []string `json:"cidr_ranges"`
+	Ipv6CidrRanges   []string `json:"ipv6_cidr_ranges"`
+	PrefixListIDs    []string `json:"prefix_list_ids"`
+	SecurityGroupIDs []string `json:"security_group_ids"`
+}
+
+type jsonNetworkInterface struct {
+	ID               string   `json:"id"`
+	Account          string   `json:"account,omitempty"`
+	Region           string   `json:"region"`
+	Description      string   `json:"description"`
+	SubnetID         string   `json:"subnet_id,omitempty"`
+	InstanceID       string   `json:"instance_id,omitempty"`
+	SecurityGroupIDs []string `json:"security_group_ids"`
+}
+
+type jsonInstance struct {
+	ID               string `json:"id"`
+	Account          string `json:"account,omitempty"`
+	Region           string `json:"region"`
+	Name             string `json:"name"`
+	AvailabilityZone string `json:"availability_zone"`
+	InstanceType     string `json:"instance_type"`
+	PrivateIP        string `json:"private_ip"`
+	PublicIP         string `json:"public_ip,omitempty"`
+	KeyName          string `json:"key_name,omitempty"`
+}
+
+// jsonIAMReport iam report. Principals refer to policies and users to
+// groups by name, within the same account.
+type jsonIAMReport struct {
+	jsonHeader
+	Policies []*jsonPolicy `json:"policies"`
+	Groups   []*jsonGroup  `json:"groups"`
+	Users    []*jsonUser   `json:"users"`
+	Roles    []*jsonRole   `json:"roles"`
+}
+
+type jsonPolicy struct {
+	Account string `json:"account,omitempty"`
+	Name    string `json:"name"`
+	// Document default version of the policy document
+	Document string `json:"document"`
+}
+
+type jsonGroup struct {
+	Account     string   `json:"account,omitempty"`
+	Name        string   `json:"name"`
+	PolicyNames []string `json:"policy_names"`
+}
+
+type jsonUser struct {
+	Account     string   `json:"account,omitempty"`
+	Name        string   `json:"name"`
+	GroupNames  []string `json:"group_names"`
+	PolicyNames []string `json:"policy_names"`
+}
+
+type jsonRole struct {
+	Account          string   `json:"account,omitempty"`
+	Name             string   `json:"name"`
+	AssumeRolePolicy string   `json:"assume_role_policy"`
+	PolicyNames      []string `json:"policy_names"`
+}
+
+// policyDocument document as IAM returns it, URL encoded, decoded
+func policyDocument(s string) string {
+	if d, err := url.QueryUnescape(s); err == nil {
+		return d
+	}
+	return s
+}
+
+// nonNil s, or an empty slice so it encodes as [] rather than null
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func writeJSON(filename string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutput(filename, "json", append(b, '\n'))
+}
@@ -2,12 +2,15 @@ package cmd
 
 import (
 	"fmt"
+	"html/template"
 	"math"
+	"strconv"
+	"strings"
 
 	"github.com/atsushi-ishibashi/aws-state-report/svc"
 	"github.com/atsushi-ishibashi/aws-state-report/util"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/jung-kurt/gofpdf"
 	"github.com/tealeg/xlsx"
 	"github.com/urfave/cli"
 )
@@ -15,90 +18,235 @@ import (
 func NewNetworkCommand() cli.Command {
 	return cli.Command{
 		Name:  "network",
-		Usage: "export vpcs, route tables and subnets information",
+		Usage: "export vpcs, route tables, subnets, gateways, endpoints and connectivity information",
 		Flags: []cli.Flag{
 			cli.StringFlag{
 				Name:  "src",
 				Usage: "file name to export",
 				Value: "network",
 			},
+			cli.StringFlag{
+				Name:  "output",
+				Usage: "path to export to, directories created as needed. - for stdout (json, html, dot, mermaid). overrides --src",
+			},
 			cli.BoolFlag{
 				Name:  "pdf-mode",
-				Usage: "output in pdf file.",
+				Usage: "output in pdf file. same as --format pdf",
+			},
+			cli.StringFlag{
+				Name:  "format",
+				Usage: "output format: xlsx, json, csv, html, dot, mermaid or pdf. overrides the global --format",
 			},
 		},
 		Action: func(c *cli.Context) error {
-			if err := util.ConfigAWS(c); err != nil {
+			format := formatName(c)
+			if c.Bool("pdf-mode") {
+				format = "pdf"
+			}
+			r, err := reportRenderer(c, format, "network")
+			if err != nil {
 				return util.ErrorRed(err.Error())
 			}
-			mng, err := svc.NewManager()
+			name := outputName(c)
+			targets, err := newTargets(c, true)
 			if err != nil {
 				return util.ErrorRed(err.Error())
 			}
-			ntw := &Network{
-				manager: mng,
-				Errs:    make([]error, 0),
+			groups, err := groupTargets(c, targets, name, format)
+			if err != nil {
+				return util.ErrorRed(err.Error())
 			}
-			if err := ntw.recursiveConstruct(); err != nil {
+			ntws := make([]*Network, 0, len(groups))
+			for _, g := range groups {
+				ntw := &Network{
+					Errs: make([]error, 0),
+				}
+				for _, t := range g.targets {
+					ntw.collect(t)
+				}
+				ntws = append(ntws, ntw)
+			}
+			printPageStats(targets)
+			errs := make([]error, 0)
+			for _, ntw := range ntws {
+				if err := ntw.flattenErrs(); err != nil && !continueOnError(c) {
+					return util.ErrorRed(err.Error())
+				}
+				errs = append(errs, ntw.Errs...)
+			}
+			if err := saveSnapshot(c, targets); err != nil {
 				return util.ErrorRed(err.Error())
 			}
-			if c.Bool("pdf-mode") {
-				ntw.convertPdf()
-			} else {
-				ntw.convertXlsx(c.String("src"))
+			for i, ntw := range ntws {
+				if err := r.(NetworkRenderer).RenderNetwork(ntw, groups[i].filename(name)); err != nil {
+					return util.ErrorRed(err.Error())
+				}
 			}
-			return nil
+			return partialReportError(errs)
 		},
 	}
 }
 
 type Network struct {
-	Vpcs    []*Vpc
-	manager *svc.Manager
-	Errs    []error
+	Vpcs []*Vpc
+	// PeeringConnections and TransitGateways connect the VPCs to each other
+	// and to VPCs that were not collected
+	PeeringConnections []*PeeringConnection
+	TransitGateways    []*TransitGateway
+	manager            svc.Manager
+	workers            int
+	Errs               []error
+}
+
+// collect adds the VPCs of one target to nt
+func (nt *Network) collect(t *target) {
+	part := &Network{
+		manager: t.manager,
+		workers: t.workers,
+		Errs:    make([]error, 0),
+	}
+	part.recursiveConstruct()
+	for _, v := range part.Vpcs {
+		v.Account = t.Account
+		v.Region = t.Region
+	}
+	nt.Vpcs = append(nt.Vpcs, part.Vpcs...)
+	for _, pcx := range part.PeeringConnections {
+		pcx.Account = t.Account
+		pcx.Region = t.Region
+		nt.addPeeringConnection(pcx)
+	}
+	for _, tgw := range part.TransitGateways {
+		tgw.Account = t.Account
+		tgw.Region = t.Region
+		nt.addTransitGateway(tgw)
+	}
+	for _, err := range part.Errs {
+		nt.stackError(targetError(t, err))
+	}
+}
+
+// scopes accounts and regions of the VPCs, in the order collected
+func (nt *Network) scopes() []scope {
+	scopes := make([]scope, 0)
+	for _, v := range nt.Vpcs {
+		scopes = appendScope(scopes, scope{Account: v.Account, Region: v.Region})
+	}
+	return scopes
 }
 
 func (nt *Network) recursiveConstruct() error {
 	nt.constructVpcs().
 		constructRouteTables().
+		constructPrefixLists().
 		constructSubnets().
-		associateRouteTableSubnet()
+		associateRouteTableSubnet().
+		constructInternetGateways().
+		constructNatGateways().
+		constructVpcEndpoints().
+		constructVpnGateways().
+		constructPeeringConnections().
+		constructTransitGateways()
 	return nt.flattenErrs()
 }
 
 func (nt *Network) constructVpcs() *Network {
 	result, err := nt.manager.FetchVpcs()
 	if err != nil {
-		return nt.stackError(err)
+		return nt.stackError(newFetchError("DescribeVpcs", "", err))
 	}
 	nt.Vpcs = parseDescribeVpcsOutputToVpcs(result)
 	return nt
 }
 
 func (nt *Network) constructRouteTables() *Network {
-	for _, vpc := range nt.Vpcs {
+	errs := make([]error, len(nt.Vpcs))
+	parallel(nt.workers, len(nt.Vpcs), func(i int) {
+		vpc := nt.Vpcs[i]
 		if result, err := nt.manager.FetchRouteTablesWithVpc(vpc.ID); err != nil {
-			nt.stackError(err)
+			errs[i] = newFetchError("DescribeRouteTables", vpc.ID, err)
 		} else {
 			vpc.RouteTables = parseDescribeRouteTablesOutputToRouteTables(result)
 		}
+	})
+	return nt.stackErrors(errs)
+}
+
+// constructPrefixLists describes the prefix lists routes go to, with their
+// CIDRs
+func (nt *Network) constructPrefixLists() *Network {
+	routes := make(map[string][]*Route)
+	ids := make([]*string, 0)
+	for _, v := range nt.Vpcs {
+		for _, rt := range v.RouteTables {
+			for _, r := range rt.Routes {
+				if r.DestinationPrefixListID == "" {
+					continue
+				}
+				if _, ok := routes[r.DestinationPrefixListID]; !ok {
+					ids = append(ids, aws.String(r.DestinationPrefixListID))
+				}
+				routes[r.DestinationPrefixListID] = append(routes[r.DestinationPrefixListID], r)
+			}
+		}
 	}
-	return nt
+	if len(ids) == 0 {
+		return nt
+	}
+	result, err := nt.manager.FetchManagedPrefixLists(ids)
+	if err != nil {
+		return nt.stackError(newFetchError("DescribeManagedPrefixLists", "", err))
+	}
+	pls := make([]*PrefixList, 0)
+	for _, v := range result.PrefixLists {
+		pls = append(pls, &PrefixList{
+			ID:   aws.StringValue(v.PrefixListId),
+			Name: aws.StringValue(v.PrefixListName),
+		})
+	}
+	errs := make([]error, len(pls))
+	parallel(nt.workers, len(pls), func(i int) {
+		pl := pls[i]
+		if result, err := nt.manager.FetchManagedPrefixListEntries(pl.ID); err != nil {
+			errs[i] = newFetchError("GetManagedPrefixListEntries", pl.ID, err)
+		} else {
+			pl.Cidrs = make([]string, 0)
+			for _, e := range result.Entries {
+				pl.Cidrs = append(pl.Cidrs, aws.StringValue(e.Cidr))
+			}
+		}
+	})
+	for _, pl := range pls {
+		for _, r := range routes[pl.ID] {
+			r.PrefixList = pl
+		}
+	}
+	return nt.stackErrors(errs)
 }
 
 func (nt *Network) constructSubnets() *Network {
-	for _, vpc := range nt.Vpcs {
+	errs := make([]error, len(nt.Vpcs))
+	parallel(nt.workers, len(nt.Vpcs), func(i int) {
+		vpc := nt.Vpcs[i]
 		if result, err := nt.manager.FetchSubnetsWithVpc(vpc.ID); err != nil {
-			nt.stackError(err)
+			errs[i] = newFetchError("DescribeSubnets", vpc.ID, err)
 		} else {
 			vpc.Subnets = parseDescribeSubnetsOutputToSubnets(result)
 		}
-	}
-	return nt
+	})
+	return nt.stackErrors(errs)
 }
 
+// associateRouteTableSubnet sets the route table each subnet uses: its own
+// association, else the main route table of the VPC
 func (nt *Network) associateRouteTableSubnet() *Network {
 	for _, vpc := range nt.Vpcs {
+		var main *RouteTable
+		for _, rt := range vpc.RouteTables {
+			if rt.IsMain() {
+				main = rt
+			}
+		}
 		for _, sn := range vpc.Subnets {
 			for _, rt := range vpc.RouteTables {
 				for _, rtas := range rt.AssociationSubnets {
@@ -107,149 +255,825 @@ func (nt *Network) associateRouteTableSubnet() *Network {
 					}
 				}
 			}
+			if sn.AssociatedRouteTable == nil && main != nil {
+				sn.AssociatedRouteTable = main
+				sn.ImplicitAssociation = true
+			}
+		}
+	}
+	return nt
+}
+
+// vpcsByID VPCs of nt by ID, for placing what is described for the whole
+// region
+func (nt *Network) vpcsByID() map[string]*Vpc {
+	vpcs := make(map[string]*Vpc, len(nt.Vpcs))
+	for _, v := range nt.Vpcs {
+		vpcs[v.ID] = v
+	}
+	return vpcs
+}
+
+// constructInternetGateways describes the internet and egress-only internet
+// gateways attached to the VPCs
+func (nt *Network) constructInternetGateways() *Network {
+	vpcs := nt.vpcsByID()
+	if result, err := nt.manager.FetchInternetGateways(); err != nil {
+		nt.stackError(newFetchError("DescribeInternetGateways", "", err))
+	} else {
+		for vpcID, igws := range parseDescribeInternetGatewaysOutput(result) {
+			if v, ok := vpcs[vpcID]; ok {
+				v.InternetGateways = igws
+			}
+		}
+	}
+	if result, err := nt.manager.FetchEgressOnlyInternetGateways(); err != nil {
+		nt.stackError(newFetchError("DescribeEgressOnlyInternetGateways", "", err))
+	} else {
+		for vpcID, eigws := range parseDescribeEgressOnlyInternetGatewaysOutput(result) {
+			if v, ok := vpcs[vpcID]; ok {
+				v.EgressOnlyInternetGateways = eigws
+			}
+		}
+	}
+	return nt
+}
+
+func (nt *Network) constructNatGateways() *Network {
+	result, err := nt.manager.FetchNatGateways()
+	if err != nil {
+		return nt.stackError(newFetchError("DescribeNatGateways", "", err))
+	}
+	vpcs := nt.vpcsByID()
+	for vpcID, ngws := range parseDescribeNatGatewaysOutput(result) {
+		if v, ok := vpcs[vpcID]; ok {
+			v.NatGateways = ngws
+		}
+	}
+	return nt
+}
+
+func (nt *Network) constructVpcEndpoints() *Network {
+	result, err := nt.manager.FetchVpcEndpoints()
+	if err != nil {
+		return nt.stackError(newFetchError("DescribeVpcEndpoints", "", err))
+	}
+	vpcs := nt.vpcsByID()
+	for vpcID, eps := range parseDescribeVpcEndpointsOutput(result) {
+		if v, ok := vpcs[vpcID]; ok {
+			v.VpcEndpoints = eps
+		}
+	}
+	return nt
+}
+
+// constructVpnGateways describes the virtual private gateways attached to
+// the VPCs, with their VPN connections and the customer gateways at the
+// other end
+func (nt *Network) constructVpnGateways() *Network {
+	result, err := nt.manager.FetchVpnGateways()
+	if err != nil {
+		return nt.stackError(newFetchError("DescribeVpnGateways", "", err))
+	}
+	vpcs := nt.vpcsByID()
+	vgws := make(map[string]*VpnGateway)
+	for vpcID, gws := range parseDescribeVpnGatewaysOutput(result) {
+		v, ok := vpcs[vpcID]
+		if !ok {
+			continue
+		}
+		v.VpnGateways = gws
+		for _, gw := range gws {
+			vgws[gw.ID] = gw
+		}
+	}
+	if len(vgws) == 0 {
+		return nt
+	}
+	connResult, err := nt.manager.FetchVpnConnections()
+	if err != nil {
+		return nt.stackError(newFetchError("DescribeVpnConnections", "", err))
+	}
+	cgwIDs := make(map[string]bool)
+	for vgwID, conns := range parseDescribeVpnConnectionsOutput(connResult) {
+		if gw, ok := vgws[vgwID]; ok {
+			gw.Connections = conns
+			for _, conn := range conns {
+				cgwIDs[conn.CustomerGatewayID] = true
+			}
+		}
+	}
+	if len(cgwIDs) == 0 {
+		return nt
+	}
+	cgwResult, err := nt.manager.FetchCustomerGateways()
+	if err != nil {
+		return nt.stackError(newFetchError("DescribeCustomerGateways", "", err))
+	}
+	cgws := parseDescribeCustomerGatewaysOutput(cgwResult)
+	for _, gw := range vgws {
+		for _, conn := range gw.Connections {
+			conn.CustomerGateway = cgws[conn.CustomerGatewayID]
 		}
 	}
 	return nt
 }
 
-func (nt *Network) convertXlsx(filename string) {
+// unknownRouteTableSubnets subnets of v whose route table is not known,
+// which happens only when the route tables could not be described
+func unknownRouteTableSubnets(v *Vpc) []*Subnet {
+	subnets := make([]*Subnet, 0)
+	for _, sn := range v.Subnets {
+		if sn.AssociatedRouteTable == nil {
+			subnets = append(subnets, sn)
+		}
+	}
+	return subnets
+}
+
+// subnetName tag name of sn, marked when it uses the main route table
+// implicitly
+func subnetName(sn *Subnet) string {
+	if sn.ImplicitAssociation {
+		return sn.TagName + " (implicit)"
+	}
+	return sn.TagName
+}
+
+// gatewaySection the gateways or endpoints of one kind in a VPC, as a
+// table whose rows start with the ID routes link to
+type gatewaySection struct {
+	Title  string
+	Header []string
+	Rows   [][]string
+}
+
+// gatewaySections sections of the gateways and endpoints of v, leaving out
+// kinds it has none of
+func gatewaySections(v *Vpc) []*gatewaySection {
+	sections := make([]*gatewaySection, 0)
+	add := func(sec *gatewaySection) {
+		if len(sec.Rows) > 0 {
+			sections = append(sections, sec)
+		}
+	}
+	igws := &gatewaySection{Title: "Internet Gateways", Header: []string{"ID", "Name", "State"}}
+	for _, gw := range v.InternetGateways {
+		igws.Rows = append(igws.Rows, []string{gw.ID, gw.TagName, gw.State})
+	}
+	add(igws)
+	eigws := &gatewaySection{Title: "Egress-only Internet Gateways", Header: []string{"ID", "Name", "State"}}
+	for _, gw := range v.EgressOnlyInternetGateways {
+		eigws.Rows = append(eigws.Rows, []string{gw.ID, gw.TagName, gw.State})
+	}
+	add(eigws)
+	ngws := &gatewaySection{Title: "NAT Gateways", Header: []string{"ID", "Name", "Connectivity", "Subnet", "Elastic IP", "Private IP", "State"}}
+	for _, gw := range v.NatGateways {
+		eips := make([]string, 0)
+		privateIPs := make([]string, 0)
+		for _, a := range gw.Addresses {
+			if a.PublicIP != "" {
+				eips = append(eips, fmt.Sprintf("%s (%s)", a.PublicIP, a.AllocationID))
+			}
+			privateIPs = append(privateIPs, a.PrivateIP)
+		}
+		ngws.Rows = append(ngws.Rows, []string{gw.ID, gw.TagName, gw.ConnectivityType, gw.SubnetID, strings.Join(eips, "\n"), strings.Join(privateIPs, "\n"), gw.State})
+	}
+	add(ngws)
+	eps := &gatewaySection{Title: "VPC Endpoints", Header: []string{"ID", "Name", "Type", "Service", "Subnets / Route Tables", "State", "Policy"}}
+	for _, ep := range v.VpcEndpoints {
+		placement := append(append([]string{}, ep.SubnetIDs...), ep.RouteTableIDs...)
+		eps.Rows = append(eps.Rows, []string{ep.ID, ep.TagName, ep.Type, ep.ServiceName, strings.Join(placement, "\n"), ep.State, indentJSON(ep.Policy)})
+	}
+	add(eps)
+	vgws := &gatewaySection{Title: "VPN Gateways", Header: []string{"ID", "Name", "Type", "Amazon ASN", "State", "VPN Connections", "Customer Gateways"}}
+	for _, gw := range v.VpnGateways {
+		conns := make([]string, 0)
+		cgws := make([]string, 0)
+		for _, conn := range gw.Connections {
+			conns = append(conns, strings.TrimSpace(fmt.Sprintf("%s %s (%s)", conn.ID, conn.TagName, conn.State)))
+			if cgw := conn.CustomerGateway; cgw != nil {
+				cgws = append(cgws, fmt.Sprintf("%s %s %s ASN %s", cgw.ID, cgw.TagName, cgw.IPAddress, cgw.BgpAsn))
+			} else {
+				cgws = append(cgws, conn.CustomerGatewayID)
+			}
+		}
+		vgws.Rows = append(vgws.Rows, []string{gw.ID, gw.TagName, gw.Type, strconv.FormatInt(gw.AmazonSideAsn, 10), gw.State, strings.Join(conns, "\n"), strings.Join(cgws, "\n")})
+	}
+	add(vgws)
+	return sections
+}
+
+func (nt *Network) convertXlsx(filename string) error {
 	file := xlsx.NewFile()
+	nt.addXlsxSheets(file)
+	addErrorsSheet(file, nt.Errs)
+	path, err := createPath(filename, "xlsx")
+	if err != nil {
+		return err
+	}
+	return file.Save(path)
+}
+
+// addXlsxSheets adds a sheet per VPC to file, and a Connectivity sheet when
+// there are peering connections or transit gateways. It returns their names,
+// and where each subnet is written for links from other sheets.
+func (nt *Network) addXlsxSheets(file *xlsx.File) ([]string, map[string]xlsxLocation) {
+	sheets := make([]string, 0, len(nt.Vpcs))
+	subnets := make(map[string]xlsxLocation)
+	// VPCs, route tables, peering connections and transit gateways by
+	// anchor, and the cells linking to them across sheets
+	locations := make(map[string]xlsxLocation)
+	links := make([]xlsxLink, 0)
+	namer := newSheetNamer(nt.scopes())
 	for _, v := range nt.Vpcs {
-		sheet, err := file.AddSheet(v.TagName)
+		name := v.TagName
+		if name == "" {
+			name = v.ID
+		}
+		sheet, err := addSheet(file, namer.name(scope{Account: v.Account, Region: v.Region}, name))
 		if err != nil {
 			util.PrintlnRed(err.Error())
 			continue
 		}
+		sheets = append(sheets, sheet.Name)
+		locations[anchor("vpc", v.Account, v.Region, v.ID)] = xlsxLocation{sheet: sheet.Name, row: 0, col: 0}
+		// route target and edge association cells by gateway ID, linked once
+		// the gateways are written
+		targetCells := make(map[string][]*xlsx.Cell)
 		currentRow := 0
 		headCell := sheet.Cell(currentRow, 0)
-		headCell.Value = fmt.Sprintf("%s  %s", v.TagName, v.CidrBlock)
-		headCell.Merge(3, 0)
+		headCell.Value = fmt.Sprintf("%s  %s", v.TagName, strings.Join(v.CidrBlocks(), "  "))
+		headCell.Merge(4, 0)
 		headCell.SetStyle(borderWithAlign("lrtb", true))
 		currentRow++
+		if v.Account != "" {
+			sheet.Cell(currentRow, 0).Value = "Account"
+			sheet.Cell(currentRow, 0).SetStyle(borderWithAlign("lrtb", true))
+			sheet.Cell(currentRow, 1).Value = v.Account
+			sheet.Cell(currentRow, 1).Merge(2, 0)
+			sheet.Cell(currentRow, 1).SetStyle(borderWithAlign("lrtb", false))
+			currentRow++
+		}
 		for _, rt := range v.RouteTables {
 			rtCell := sheet.Cell(currentRow, 0)
 			rtCell.Value = fmt.Sprintf("Route Table: %s", rt.TagName)
+			if rt.IsMain() {
+				rtCell.Value += " (main)"
+			}
 			rtCell.Merge(1, 0)
 			rtCell.SetStyle(borderWithAlign("lrtb", true))
+			locations[anchor("rtb", v.Account, v.Region, rt.ID)] = xlsxLocation{sheet: sheet.Name, row: currentRow, col: 0}
 			snCell := sheet.Cell(currentRow, 2)
-			snCell.Value = "Association Subnets"
+			snCell.Value = "Associations"
 			snCell.Merge(1, 0)
 			snCell.SetStyle(borderWithAlign("lrtb", true))
+			sheet.Cell(currentRow, 4).Value = "Region"
+			sheet.Cell(currentRow, 4).SetStyle(borderWithAlign("lrtb", true))
 			currentRow++
 			var rtNo int
 			for _, rtr := range rt.Routes {
-				sheet.Cell(currentRow+rtNo, 0).Value = rtr.DestinationCidrBlock
-				sheet.Cell(currentRow+rtNo, 0).SetStyle(borderWithAlign("l", false))
-				sheet.Cell(currentRow+rtNo, 1).Value = rtr.Router
-				sheet.Cell(currentRow+rtNo, 1).SetStyle(borderWithAlign("r", false))
+				sheet.Cell(currentRow+rtNo, 0).Value = rtr.DestinationName()
+				sheet.Cell(currentRow+rtNo, 0).SetStyle(routeStyle(rtr, "l"))
+				targetCell := sheet.Cell(currentRow+rtNo, 1)
+				targetCell.Value = rtr.TargetName()
+				targetCell.SetStyle(routeStyle(rtr, "r"))
+				if v.HasGateway(rtr.Router) {
+					targetCells[rtr.Router] = append(targetCells[rtr.Router], targetCell)
+				} else if nt.HasConnection(rtr.Router) {
+					links = append(links, xlsxLink{cell: targetCell, anchor: nt.ConnectionAnchor(rtr.Router)})
+				}
 				rtNo++
 			}
 			var snNo int
 			for _, sn := range v.Subnets {
 				if sn.AssociatedRouteTable == rt {
-					sheet.Cell(currentRow+snNo, 2).Value = sn.TagName
+					subnets[sn.ID] = xlsxLocation{sheet: sheet.Name, row: currentRow + snNo, col: 2}
+					sheet.Cell(currentRow+snNo, 2).Value = subnetName(sn)
 					sheet.Cell(currentRow+snNo, 2).SetStyle(borderWithAlign("l", false))
-					sheet.Cell(currentRow+snNo, 3).Value = sn.CidrBlock
+					sheet.Cell(currentRow+snNo, 3).Value = strings.Join(sn.CidrBlocks(), ", ")
 					sheet.Cell(currentRow+snNo, 3).SetStyle(borderWithAlign("r", false))
 					snNo++
 				}
 			}
+			for _, gw := range rt.AssociationGateways {
+				gwCell := sheet.Cell(currentRow+snNo, 2)
+				gwCell.Value = gw
+				gwCell.SetStyle(borderWithAlign("l", false))
+				if v.HasGateway(gw) {
+					targetCells[gw] = append(targetCells[gw], gwCell)
+				}
+				sheet.Cell(currentRow+snNo, 3).Value = "edge association"
+				sheet.Cell(currentRow+snNo, 3).SetStyle(borderWithAlign("r", false))
+				snNo++
+			}
 			maxNo := int(math.Max(float64(rtNo), float64(snNo)))
 			for i := 0; i < maxNo; i++ {
-				sheet.Cell(currentRow+i, 0).SetStyle(borderWithAlign("l", false))
+				if i >= rtNo {
+					sheet.Cell(currentRow+i, 0).SetStyle(borderWithAlign("l", false))
+				}
 				sheet.Cell(currentRow+i, 3).SetStyle(borderWithAlign("r", false))
+				sheet.Cell(currentRow+i, 4).Value = v.Region
+				sheet.Cell(currentRow+i, 4).SetStyle(borderWithAlign("lr", false))
 			}
 			currentRow += maxNo
 		}
 		sheet.Cell(currentRow, 0).SetStyle(borderWithAlign("t", false))
 		sheet.Cell(currentRow, 1).SetStyle(borderWithAlign("t", false))
-		noaSnCell := sheet.Cell(currentRow, 2)
-		noaSnCell.Value = "No Association Subnets"
-		noaSnCell.Merge(1, 0)
-		noaSnCell.SetStyle(borderWithAlign("lrtb", true))
-		currentRow++
-		for _, sn := range v.Subnets {
-			if sn.AssociatedRouteTable == nil {
+		if unknown := unknownRouteTableSubnets(v); len(unknown) > 0 {
+			unknownCell := sheet.Cell(currentRow, 2)
+			unknownCell.Value = "Route Table Unknown"
+			unknownCell.Merge(1, 0)
+			unknownCell.SetStyle(borderWithAlign("lrtb", true))
+			sheet.Cell(currentRow, 4).Value = "Region"
+			sheet.Cell(currentRow, 4).SetStyle(borderWithAlign("lrtb", true))
+			currentRow++
+			for _, sn := range unknown {
+				subnets[sn.ID] = xlsxLocation{sheet: sheet.Name, row: currentRow, col: 2}
 				sheet.Cell(currentRow, 2).Value = sn.TagName
 				sheet.Cell(currentRow, 2).SetStyle(borderWithAlign("l", false))
-				sheet.Cell(currentRow, 3).Value = sn.CidrBlock
+				sheet.Cell(currentRow, 3).Value = strings.Join(sn.CidrBlocks(), ", ")
 				sheet.Cell(currentRow, 3).SetStyle(borderWithAlign("r", false))
+				sheet.Cell(currentRow, 4).Value = v.Region
+				sheet.Cell(currentRow, 4).SetStyle(borderWithAlign("lr", false))
 				currentRow++
 			}
 		}
 		sheet.Cell(currentRow, 2).SetStyle(borderWithAlign("t", false))
 		sheet.Cell(currentRow, 3).SetStyle(borderWithAlign("t", false))
+		sheet.Cell(currentRow, 4).SetStyle(borderWithAlign("t", false))
+		currentRow++
+		gateways := addGatewaySectionsToXlsx(sheet, currentRow, gatewaySections(v), v.Region)
+		for id, cells := range targetCells {
+			if loc, ok := gateways[id]; ok {
+				for _, c := range cells {
+					c.SetFormula(loc.hyperlink(c.Value))
+				}
+			}
+		}
 	}
-	if err := file.Save(fmt.Sprintf("./%s.xlsx", filename)); err != nil {
-		nt.stackError(err)
+	if tables := nt.connectivityTables(); tables != nil {
+		if name, err := addConnectivitySheet(file, "Connectivity", tables, locations, &links); err != nil {
+			util.PrintlnRed(err.Error())
+		} else {
+			sheets = append(sheets, name)
+		}
+	}
+	for _, l := range links {
+		if loc, ok := locations[l.anchor]; ok {
+			l.cell.SetFormula(loc.hyperlink(l.cell.Value))
+		}
+	}
+	return sheets, subnets
+}
+
+// addGatewaySectionsToXlsx writes sections from row down, a blank row after
+// each, with a region column. It returns where the row of each gateway or
+// endpoint is written.
+func addGatewaySectionsToXlsx(sheet *xlsx.Sheet, row int, sections []*gatewaySection, region string) map[string]xlsxLocation {
+	locations := make(map[string]xlsxLocation)
+	for _, sec := range sections {
+		titleCell := sheet.Cell(row, 0)
+		titleCell.Value = sec.Title
+		titleCell.Merge(len(sec.Header), 0)
+		titleCell.SetStyle(borderWithAlign("lrtb", true))
+		row++
+		for i, h := range append(sec.Header, "Region") {
+			sheet.Cell(row, i).Value = h
+			sheet.Cell(row, i).SetStyle(borderWithAlign("lrtb", true))
+		}
+		row++
+		for _, r := range sec.Rows {
+			locations[r[0]] = xlsxLocation{sheet: sheet.Name, row: row, col: 0}
+			for i, v := range append(r, region) {
+				sheet.Cell(row, i).Value = v
+				sheet.Cell(row, i).SetStyle(borderWithAlign("lrtb", false))
+			}
+			row++
+		}
+		row++
 	}
+	return locations
 }
 
-func (nt *Network) convertPdf() {
-	pdf := gofpdf.New("P", "mm", "A4", "")
-	pdf.AddPage()
+// addGatewaySectionsToPdf writes the gateway sections of v, each row the
+// anchor routes to it link to
+func addGatewaySectionsToPdf(pdf *pdfDoc, v *Vpc) {
+	const idWidth = 35.0
+	for _, sec := range gatewaySections(v) {
+		widths := []float64{idWidth}
+		for i := 1; i < len(sec.Header); i++ {
+			widths = append(widths, (190-idWidth)/float64(len(sec.Header)-1))
+		}
+		header := make([]pdfCell, 0, len(sec.Header))
+		for _, h := range sec.Header {
+			header = append(header, pdfCell{text: h})
+		}
+		pdf.fit(pdfLineHeight * 4)
+		pdf.CellFormat(0, pdfLineHeight+2, sec.Title, "1", 1, "C", false, 0, "")
+		pdf.SetFontStyle("B")
+		pdf.row(widths, header)
+		pdf.SetFontStyle("")
+		for _, r := range sec.Rows {
+			cells := make([]pdfCell, 0, len(r))
+			for _, text := range r {
+				cells = append(cells, pdfCell{text: text})
+			}
+			pdf.fit(pdf.rowHeight(widths, cells))
+			pdf.anchor(anchor("gw", v.Account, v.Region, r[0]))
+			pdf.row(widths, cells)
+		}
+		pdf.Ln(pdfLineHeight)
+	}
+}
+
+func (nt *Network) convertPdf(filename string) error {
+	pdf := newPdfDoc()
 	pdf.SetFont("Arial", "", 10)
 	for _, v := range nt.Vpcs {
-		pdf.CellFormat(0, 10, fmt.Sprintf("%s  %s", v.TagName, v.CidrBlock), "1", 0, "C", false, 0, "")
+		pdf.anchor(anchor("vpc", v.Account, v.Region, v.ID))
+		pdf.CellFormat(0, 10, fmt.Sprintf("%s  %s  (%s)", v.TagName, strings.Join(v.CidrBlocks(), "  "), location(v.Account, v.Region)), "1", 0, "C", false, 0, "")
 		pdf.Ln(-1)
 		for _, rt := range v.RouteTables {
-			pdf.CellFormat(95, 10, fmt.Sprintf("%s", rt.TagName), "1", 0, "C", false, 0, "")
-			pdf.CellFormat(95, 10, "Association Subnets", "1", 0, "C", false, 0, "")
+			rtName := rt.TagName
+			if rt.IsMain() {
+				rtName += " (main)"
+			}
+			pdf.anchor(anchor("rtb", v.Account, v.Region, rt.ID))
+			pdf.CellFormat(95, 10, rtName, "1", 0, "C", false, 0, "")
+			pdf.CellFormat(95, 10, "Associations", "1", 0, "C", false, 0, "")
 			pdf.Ln(-1)
 			currentX, currentY := pdf.GetXY()
 			var rtHeight float64
 			for _, rtr := range rt.Routes {
 				pdf.MoveTo(currentX, currentY+rtHeight)
-				pdf.CellFormat(95, 10, fmt.Sprintf("%s %s", rtr.DestinationCidrBlock, rtr.Router), "RL", 0, "C", false, 0, "")
+				if rtr.IsBlackhole() {
+					pdf.SetTextColor(200, 0, 0)
+				}
+				var link int
+				if v.HasGateway(rtr.Router) {
+					link = pdf.link(anchor("gw", v.Account, v.Region, rtr.Router))
+				} else if nt.HasConnection(rtr.Router) {
+					link = pdf.link(nt.ConnectionAnchor(rtr.Router))
+				}
+				pdf.CellFormat(95, 10, fmt.Sprintf("%s %s", rtr.DestinationName(), rtr.TargetName()), "RL", 0, "C", false, link, "")
+				pdf.SetTextColor(0, 0, 0)
 				rtHeight += 10.0
 			}
 			var snHeight float64
 			for _, sn := range v.Subnets {
 				if sn.AssociatedRouteTable == rt {
 					pdf.MoveTo(currentX+95, currentY+snHeight)
-					pdf.CellFormat(95, 10, fmt.Sprintf("%s %s", sn.TagName, sn.CidrBlock), "RL", 0, "C", false, 0, "")
+					pdf.CellFormat(95, 10, fmt.Sprintf("%s %s", subnetName(sn), strings.Join(sn.CidrBlocks(), " ")), "RL", 0, "C", false, 0, "")
 					snHeight += 10.0
 				}
 			}
+			for _, gw := range rt.AssociationGateways {
+				pdf.MoveTo(currentX+95, currentY+snHeight)
+				pdf.CellFormat(95, 10, fmt.Sprintf("%s (edge association)", gw), "RL", 0, "C", false, 0, "")
+				snHeight += 10.0
+			}
 			maxHeight := math.Max(snHeight, rtHeight)
 			pdf.MoveTo(currentX, currentY)
 			pdf.CellFormat(0, maxHeight, "", "1", 0, "C", false, 0, "")
 			pdf.Ln(-1)
 		}
-		pdf.CellFormat(0, 10, "No Association Subnets", "1", 0, "C", false, 0, "")
-		pdf.Ln(-1)
-		currentX, currentY := pdf.GetXY()
-		var noaSnHeight float64
-		for _, sn := range v.Subnets {
-			if sn.AssociatedRouteTable == nil {
-				pdf.CellFormat(0, 10, fmt.Sprintf("%s %s", sn.TagName, sn.CidrBlock), "LR", 0, "C", false, 0, "")
+		if unknown := unknownRouteTableSubnets(v); len(unknown) > 0 {
+			pdf.CellFormat(0, 10, "Route Table Unknown", "1", 0, "C", false, 0, "")
+			pdf.Ln(-1)
+			currentX, currentY := pdf.GetXY()
+			var unknownHeight float64
+			for _, sn := range unknown {
+				pdf.CellFormat(0, 10, fmt.Sprintf("%s %s", sn.TagName, strings.Join(sn.CidrBlocks(), " ")), "LR", 0, "C", false, 0, "")
 				pdf.Ln(-1)
-				noaSnHeight += 10
+				unknownHeight += 10
 			}
+			pdf.MoveTo(currentX, currentY)
+			pdf.CellFormat(0, unknownHeight, "", "1", 0, "C", false, 0, "")
+			pdf.Ln(-1)
 		}
-		pdf.MoveTo(currentX, currentY)
-		pdf.CellFormat(0, noaSnHeight, "", "1", 0, "C", false, 0, "")
+		pdf.Ln(pdfLineHeight)
+		addGatewaySectionsToPdf(pdf, v)
+		pdf.AddPage()
+	}
+	if tables := nt.connectivityTables(); tables != nil {
+		addConnectivityToPdf(pdf, tables)
 		pdf.AddPage()
 	}
-	if err := pdf.OutputFileAndClose("./network.pdf"); err != nil {
-		nt.stackError(err)
+	if len(nt.Errs) > 0 {
+		pdf.CellFormat(0, 10, "Errors", "1", 0, "C", false, 0, "")
+		pdf.Ln(-1)
+		for _, err := range nt.Errs {
+			pdf.MultiCell(0, 10, err.Error(), "1", "L", false)
+		}
+	}
+	path, err := createPath(filename, "pdf")
+	if err != nil {
+		return err
+	}
+	return pdf.OutputFileAndClose(path)
+}
+
+func (nt *Network) convertJSON(filename string) error {
+	report := &jsonNetworkReport{
+		jsonHeader:                 newJSONHeader("network", nt.Errs),
+		Vpcs:                       make([]*jsonVpc, 0),
+		RouteTables:                make([]*jsonRouteTable, 0),
+		Subnets:                    make([]*jsonSubnet, 0),
+		InternetGateways:           make([]*jsonInternetGateway, 0),
+		EgressOnlyInternetGateways: make([]*jsonInternetGateway, 0),
+		NatGateways:                make([]*jsonNatGateway, 0),
+		VpcEndpoints:               make([]*jsonVpcEndpoint, 0),
+		VpnGateways:                make([]*jsonVpnGateway, 0),
+		PeeringConnections:         make([]*jsonPeeringConnection, 0),
+		TransitGateways:            make([]*jsonTransitGateway, 0),
+		Connectivity:               make([]*jsonConnection, 0),
+	}
+	for _, v := range nt.Vpcs {
+		vpc := &jsonVpc{
+			ID:                   v.ID,
+			Account:              v.Account,
+			Region:               v.Region,
+			Name:                 v.TagName,
+			CidrBlock:            v.CidrBlock,
+			AssociatedCidrBlocks: nonNil(v.AssociatedCidrBlocks),
+			Ipv6CidrBlocks:       nonNil(v.Ipv6CidrBlocks),
+			RouteTableIDs:        make([]string, 0),
+			SubnetIDs:            make([]string, 0),
+		}
+		for _, rt := range v.RouteTables {
+			vpc.RouteTableIDs = append(vpc.RouteTableIDs, rt.ID)
+			jrt := &jsonRouteTable{
+				ID:       rt.ID,
+				VpcID:    v.ID,
+				Name:     rt.TagName,
+				Main:     rt.IsMain(),
+				Routes:   make([]*jsonRoute, 0),
+				Subnets:  nonNil(rt.AssociationSubnets),
+				Gateways: nonNil(rt.AssociationGateways),
+			}
+			for _, r := range rt.Routes {
+				jr := &jsonRoute{
+					Destination: r.Destination(),
+					Target:      r.Router,
+					TargetType:  r.RouterType,
+					State:       r.State,
+					Origin:      r.Origin,
+					Blackhole:   r.IsBlackhole(),
+				}
+				if r.PrefixList != nil {
+					jr.PrefixListName = r.PrefixList.Name
+					jr.PrefixListCidrs = r.PrefixList.Cidrs
+				}
+				jrt.Routes = append(jrt.Routes, jr)
+			}
+			report.RouteTables = append(report.RouteTables, jrt)
+		}
+		for _, sn := range v.Subnets {
+			vpc.SubnetIDs = append(vpc.SubnetIDs, sn.ID)
+			jsn := &jsonSubnet{
+				ID:                  sn.ID,
+				VpcID:               v.ID,
+				Name:                sn.TagName,
+				CidrBlock:           sn.CidrBlock,
+				Ipv6CidrBlocks:      nonNil(sn.Ipv6CidrBlocks),
+				ImplicitAssociation: sn.ImplicitAssociation,
+			}
+			if sn.AssociatedRouteTable != nil {
+				jsn.RouteTableID = sn.AssociatedRouteTable.ID
+			}
+			report.Subnets = append(report.Subnets, jsn)
+		}
+		for _, gw := range v.InternetGateways {
+			report.InternetGateways = append(report.InternetGateways, &jsonInternetGateway{ID: gw.ID, VpcID: v.ID, Name: gw.TagName, State: gw.State})
+		}
+		for _, gw := range v.EgressOnlyInternetGateways {
+			report.EgressOnlyInternetGateways = append(report.EgressOnlyInternetGateways, &jsonInternetGateway{ID: gw.ID, VpcID: v.ID, Name: gw.TagName, State: gw.State})
+		}
+		for _, gw := range v.NatGateways {
+			jgw := &jsonNatGateway{
+				ID:               gw.ID,
+				VpcID:            v.ID,
+				Name:             gw.TagName,
+				State:            gw.State,
+				ConnectivityType: gw.ConnectivityType,
+				SubnetID:         gw.SubnetID,
+				Addresses:        make([]*jsonNatGatewayAddress, 0),
+			}
+			for _, a := range gw.Addresses {
+				jgw.Addresses = append(jgw.Addresses, &jsonNatGatewayAddress{
+					AllocationID:       a.AllocationID,
+					PublicIP:           a.PublicIP,
+					PrivateIP:          a.PrivateIP,
+					NetworkInterfaceID: a.NetworkInterfaceID,
+				})
+			}
+			report.NatGateways = append(report.NatGateways, jgw)
+		}
+		for _, ep := range v.VpcEndpoints {
+			report.VpcEndpoints = append(report.VpcEndpoints, &jsonVpcEndpoint{
+				ID:            ep.ID,
+				VpcID:         v.ID,
+				Name:          ep.TagName,
+				Type:          ep.Type,
+				ServiceName:   ep.ServiceName,
+				State:         ep.State,
+				SubnetIDs:     nonNil(ep.SubnetIDs),
+				RouteTableIDs: nonNil(ep.RouteTableIDs),
+				Policy:        ep.Policy,
+			})
+		}
+		for _, gw := range v.VpnGateways {
+			jgw := &jsonVpnGateway{
+				ID:            gw.ID,
+				VpcID:         v.ID,
+				Name:          gw.TagName,
+				State:         gw.State,
+				Type:          gw.Type,
+				AmazonSideAsn: gw.AmazonSideAsn,
+				Connections:   make([]*jsonVpnConnection, 0),
+			}
+			for _, conn := range gw.Connections {
+				jconn := &jsonVpnConnection{
+					ID:                conn.ID,
+					Name:              conn.TagName,
+					State:             conn.State,
+					Type:              conn.Type,
+					CustomerGatewayID: conn.CustomerGatewayID,
+				}
+				if cgw := conn.CustomerGateway; cgw != nil {
+					jconn.CustomerGateway = &jsonCustomerGateway{
+						ID:         cgw.ID,
+						Name:       cgw.TagName,
+						State:      cgw.State,
+						Type:       cgw.Type,
+						IPAddress:  cgw.IPAddress,
+						BgpAsn:     cgw.BgpAsn,
+						DeviceName: cgw.DeviceName,
+					}
+				}
+				jgw.Connections = append(jgw.Connections, jconn)
+			}
+			report.VpnGateways = append(report.VpnGateways, jgw)
+		}
+		report.Vpcs = append(report.Vpcs, vpc)
 	}
+	nt.addConnectivityToJSON(report)
+	return writeJSON(filename, report)
 }
 
+// convertCSV writes vpcs, route_tables, routes, subnets and the gateways and
+// endpoints into dir
+func (nt *Network) convertCSV(dir string) error {
+	vpcs := newCSVTable("vpcs", "account", "region", "vpc_id", "name", "cidr_block", "associated_cidr_blocks", "ipv6_cidr_blocks")
+	rts := newCSVTable("route_tables", "account", "region", "vpc_id", "route_table_id", "name", "main", "edge_gateway_ids")
+	routes := newCSVTable("routes", "account", "region", "vpc_id", "route_table_id", "destination", "prefix_list_name", "target", "target_type", "state", "blackhole", "origin")
+	subnets := newCSVTable("subnets", "account", "region", "vpc_id", "subnet_id", "name", "cidr_block", "ipv6_cidr_blocks", "route_table_id", "implicit_association")
+	igws := newCSVTable("internet_gateways", "account", "region", "vpc_id", "gateway_id", "name", "type", "state")
+	ngws := newCSVTable("nat_gateways", "account", "region", "vpc_id", "nat_gateway_id", "name", "connectivity_type", "subnet_id", "allocation_id", "public_ip", "private_ip", "state")
+	eps := newCSVTable("vpc_endpoints", "account", "region", "vpc_id", "vpc_endpoint_id", "name", "type", "service_name", "subnet_ids", "route_table_ids", "state", "policy")
+	vgws := newCSVTable("vpn_gateways", "account", "region", "vpc_id", "vpn_gateway_id", "name", "type", "amazon_side_asn", "state", "vpn_connection_id", "vpn_connection_state", "customer_gateway_id", "customer_gateway_ip", "customer_gateway_bgp_asn")
+	for _, v := range nt.Vpcs {
+		vpcs.add(v.Account, v.Region, v.ID, v.TagName, v.CidrBlock, strings.Join(v.AssociatedCidrBlocks, " "), strings.Join(v.Ipv6CidrBlocks, " "))
+		for _, rt := range v.RouteTables {
+			rts.add(v.Account, v.Region, v.ID, rt.ID, rt.TagName, strconv.FormatBool(rt.IsMain()), strings.Join(rt.AssociationGateways, " "))
+			for _, r := range rt.Routes {
+				var plName string
+				if r.PrefixList != nil {
+					plName = r.PrefixList.Name
+				}
+				routes.add(v.Account, v.Region, v.ID, rt.ID, r.Destination(), plName, r.Router, r.RouterType, r.State, strconv.FormatBool(r.IsBlackhole()), r.Origin)
+			}
+		}
+		for _, sn := range v.Subnets {
+			var rtID string
+			if sn.AssociatedRouteTable != nil {
+				rtID = sn.AssociatedRouteTable.ID
+			}
+			subnets.add(v.Account, v.Region, v.ID, sn.ID, sn.TagName, sn.CidrBlock, strings.Join(sn.Ipv6CidrBlocks, " "), rtID, strconv.FormatBool(sn.ImplicitAssociation))
+		}
+		for _, gw := range v.InternetGateways {
+			igws.add(v.Account, v.Region, v.ID, gw.ID, gw.TagName, routerInternetGateway, gw.State)
+		}
+		for _, gw := range v.EgressOnlyInternetGateways {
+			igws.add(v.Account, v.Region, v.ID, gw.ID, gw.TagName, routerEgressOnlyInternetGateway, gw.State)
+		}
+		for _, gw := range v.NatGateways {
+			if len(gw.Addresses) == 0 {
+				ngws.add(v.Account, v.Region, v.ID, gw.ID, gw.TagName, gw.ConnectivityType, gw.SubnetID, "", "", "", gw.State)
+			}
+			for _, a := range gw.Addresses {
+				ngws.add(v.Account, v.Region, v.ID, gw.ID, gw.TagName, gw.ConnectivityType, gw.SubnetID, a.AllocationID, a.PublicIP, a.PrivateIP, gw.State)
+			}
+		}
+		for _, ep := range v.VpcEndpoints {
+			eps.add(v.Account, v.Region, v.ID, ep.ID, ep.TagName, ep.Type, ep.ServiceName, strings.Join(ep.SubnetIDs, " "), strings.Join(ep.RouteTableIDs, " "), ep.State, ep.Policy)
+		}
+		for _, gw := range v.VpnGateways {
+			asn := strconv.FormatInt(gw.AmazonSideAsn, 10)
+			if len(gw.Connections) == 0 {
+				vgws.add(v.Account, v.Region, v.ID, gw.ID, gw.TagName, gw.Type, asn, gw.State, "", "", "", "", "")
+			}
+			for _, conn := range gw.Connections {
+				var cgwIP, cgwAsn string
+				if conn.CustomerGateway != nil {
+					cgwIP, cgwAsn = conn.CustomerGateway.IPAddress, conn.CustomerGateway.BgpAsn
+				}
+				vgws.add(v.Account, v.Region, v.ID, gw.ID, gw.TagName, gw.Type, asn, gw.State, conn.ID, conn.State, conn.CustomerGatewayID, cgwIP, cgwAsn)
+			}
+		}
+	}
+	pcxs, tgwAts, tgwRoutes, conns := nt.connectivityCSVTables()
+	return writeCSV(dir, vpcs, rts, routes, subnets, igws, ngws, eps, vgws, pcxs, tgwAts, tgwRoutes, conns, csvErrors(nt.Errs))
+}
+
+func (nt *Network) convertHTML(filename string) error {
+	return writeHTML(filename, "network", networkHTML, nt, nt.Errs, template.FuncMap{
+		"gatewaySections":    gatewaySections,
+		"connectivityTables": (*Network).connectivityTables,
+	})
+}
+
+const networkHTML = `
+{{define "toc"}}
+<strong>VPCs</strong>
+<ul>
+{{- range .Vpcs}}
+<li><a href="#{{anchor "vpc" .Account .Region .ID}}">{{.TagName}} {{.ID}}</a> <span class="meta">{{.Account}} {{.Region}}</span></li>
+{{- end}}
+</ul>
+{{- if connectivityTables .}}
+<p><a href="#connectivity">Connectivity</a></p>
+{{- end}}
+{{end}}
+
+{{define "body"}}
+<h2>VPCs</h2>
+{{- range $vpc := .Vpcs}}
+<details class="item" id="{{anchor "vpc" .Account .Region .ID}}">
+<summary>{{.TagName}} {{.ID}} <span class="meta">{{range .CidrBlocks}}{{.}} {{end}}{{.Account}} {{.Region}}</span></summary>
+{{- range .RouteTables}}
+<table id="{{anchor "rtb" $vpc.Account $vpc.Region .ID}}">
+<tr><th colspan="5">Route Table: {{.TagName}} {{.ID}}{{if .IsMain}} (main){{end}}</th></tr>
+<tr><th>Destination</th><th>Target</th><th>Type</th><th>State</th><th>Origin</th></tr>
+{{- range .Routes}}
+<tr{{if .IsBlackhole}} class="blackhole"{{end}}><td>{{.DestinationName}}{{with .PrefixList}}<br><span class="meta">{{join .Cidrs ", "}}</span>{{end}}</td><td>{{if $vpc.HasGateway .Router}}<a href="#{{anchor "gw" $vpc.Account $vpc.Region .Router}}">{{.Router}}</a>{{else if $.HasConnection .Router}}<a href="#{{$.ConnectionAnchor .Router}}">{{.Router}}</a>{{else}}{{.Router}}{{end}}</td><td>{{.RouterType}}</td><td>{{.State}}</td><td>{{.Origin}}</td></tr>
+{{- end}}
+{{- range .AssociationGateways}}
+<tr><td colspan="5"><span class="meta">edge association</span> {{if $vpc.HasGateway .}}<a href="#{{anchor "gw" $vpc.Account $vpc.Region .}}">{{.}}</a>{{else}}{{.}}{{end}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+<table>
+<tr><th>Subnet</th><th>CIDR</th><th>Route Table</th></tr>
+{{- range .Subnets}}
+<tr id="{{anchor "subnet" $vpc.Account $vpc.Region .ID}}"><td>{{.TagName}} {{.ID}}</td><td>{{range $i, $c := .CidrBlocks}}{{if $i}}<br>{{end}}{{$c}}{{end}}</td>
+<td>{{with .AssociatedRouteTable}}<a href="#{{anchor "rtb" $vpc.Account $vpc.Region .ID}}">{{.TagName}} {{.ID}}</a>{{else}}<span class="meta">unknown</span>{{end}}{{if .ImplicitAssociation}} <span class="meta">(implicit)</span>{{end}}</td></tr>
+{{- end}}
+</table>
+{{- range gatewaySections .}}
+<table>
+<tr><th colspan="{{len .Header}}">{{.Title}}</th></tr>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Rows}}
+<tr id="{{anchor "gw" $vpc.Account $vpc.Region (index . 0)}}">{{range .}}<td class="lines">{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+</details>
+{{- end}}
+{{- with connectivityTables .}}
+<h2 id="connectivity">Connectivity</h2>
+{{- range .}}
+<table{{with .Anchor}} id="{{.}}"{{end}}>
+<tr><th colspan="{{len .Header}}">{{.Title}}</th></tr>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Rows}}
+<tr{{with .Anchor}} id="{{.}}"{{end}}>{{range .Cells}}<td class="lines">{{if .Link}}<a href="#{{.Link}}">{{.Text}}</a>{{else}}{{.Text}}{{end}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{end}}
+`
+
 func (nt *Network) stackError(err error) *Network {
 	nt.Errs = append(nt.Errs, err)
 	return nt
 }
 
-func (nt *Network) flattenErrs() error {
-	if len(nt.Errs) == 0 {
-		return nil
-	}
-	var errStr string
-	for _, e := range nt.Errs {
-		errStr = errStr + e.Error() + "\n"
+// stackErrors stacks the errors that are not nil, in order
+func (nt *Network) stackErrors(errs []error) *Network {
+	for _, err := range errs {
+		if err != nil {
+			nt.stackError(err)
+		}
 	}
-	return fmt.Errorf(errStr)
+	return nt
+}
+
+func (nt *Network) flattenErrs() error {
+	return flattenErrors(nt.Errs)
 }
 
 func parseDescribeVpcsOutputToVpcs(output *ec2.DescribeVpcsOutput) []*Vpc {
@@ -265,6 +1089,13 @@ func parseDescribeVpcsOutputToVpcs(output *ec2.DescribeVpcsOutput) []*Vpc {
 			acbs = append(acbs, *cbs.CidrBlock)
 		}
 		vpc.AssociatedCidrBlocks = acbs
+		ipv6 := make([]string, 0)
+		for _, cbs := range v.Ipv6CidrBlockAssociationSet {
+			if cbs.Ipv6CidrBlockState == nil || aws.StringValue(cbs.Ipv6CidrBlockState.State) == "associated" {
+				ipv6 = append(ipv6, aws.StringValue(cbs.Ipv6CidrBlock))
+			}
+		}
+		vpc.Ipv6CidrBlocks = ipv6
 		vs = append(vs, vpc)
 	}
 	return vs
@@ -279,49 +1110,249 @@ func parseDescribeRouteTablesOutputToRouteTables(output *ec2.DescribeRouteTables
 		}
 		rs := make([]*Route, 0)
 		for _, r := range v.Routes {
-			if r.DestinationCidrBlock == nil {
+			if r.DestinationCidrBlock == nil && r.DestinationIpv6CidrBlock == nil && r.DestinationPrefixListId == nil {
 				continue
 			}
 			rr := &Route{
-				DestinationCidrBlock: *r.DestinationCidrBlock,
-			}
-			var routerID string
-			if r.GatewayId != nil {
-				routerID = *r.GatewayId
-			}
-			if r.NatGatewayId != nil {
-				routerID = *r.NatGatewayId
+				DestinationCidrBlock:     aws.StringValue(r.DestinationCidrBlock),
+				DestinationIpv6CidrBlock: aws.StringValue(r.DestinationIpv6CidrBlock),
+				DestinationPrefixListID:  aws.StringValue(r.DestinationPrefixListId),
+				State:                    aws.StringValue(r.State),
+				Origin:                   aws.StringValue(r.Origin),
 			}
-			if r.VpcPeeringConnectionId != nil {
-				routerID = *r.VpcPeeringConnectionId
+			rr.Router, rr.RouterType = routeTarget(r)
+			if rr.RouterType == routerInstance {
+				rr.NetworkInterfaceID = aws.StringValue(r.NetworkInterfaceId)
 			}
-			rr.Router = routerID
 			rs = append(rs, rr)
 		}
 		rt.Routes = rs
 		asSubnets := make([]string, 0)
+		asGateways := make([]string, 0)
 		for _, as := range v.Associations {
-			if as.SubnetId != nil {
+			if as.AssociationState != nil && aws.StringValue(as.AssociationState.State) != "associated" {
+				continue
+			}
+			switch {
+			case aws.BoolValue(as.Main):
+				rt.Main = true
+			case as.SubnetId != nil:
 				asSubnets = append(asSubnets, *as.SubnetId)
-			} else {
-				asSubnets = append(asSubnets, "implicit")
+			case as.GatewayId != nil:
+				asGateways = append(asGateways, *as.GatewayId)
 			}
 		}
 		rt.AssociationSubnets = asSubnets
+		rt.AssociationGateways = asGateways
 		rts = append(rts, rt)
 	}
 	return rts
 }
 
+// routeTarget ID and kind of the target of r. An instance target comes with
+// its interface as well, which goes to Route.NetworkInterfaceID.
+func routeTarget(r *ec2.Route) (string, string) {
+	if r.GatewayId != nil {
+		id := *r.GatewayId
+		switch {
+		case id == "local":
+			return id, routerLocal
+		case strings.HasPrefix(id, "igw-"):
+			return id, routerInternetGateway
+		case strings.HasPrefix(id, "vgw-"):
+			return id, routerVpnGateway
+		case strings.HasPrefix(id, "vpce-"):
+			return id, routerVpcEndpoint
+		}
+		return id, routerGateway
+	}
+	targets := []struct {
+		id  *string
+		typ string
+	}{
+		{r.NatGatewayId, routerNatGateway},
+		{r.VpcPeeringConnectionId, routerPeeringConnection},
+		{r.EgressOnlyInternetGatewayId, routerEgressOnlyInternetGateway},
+		{r.TransitGatewayId, routerTransitGateway},
+		{r.InstanceId, routerInstance},
+		{r.NetworkInterfaceId, routerNetworkInterface},
+		{r.CarrierGatewayId, routerCarrierGateway},
+		{r.LocalGatewayId, routerLocalGateway},
+		{r.CoreNetworkArn, routerCoreNetwork},
+	}
+	for _, t := range targets {
+		if t.id != nil {
+			return *t.id, t.typ
+		}
+	}
+	return "", ""
+}
+
 func parseDescribeSubnetsOutputToSubnets(output *ec2.DescribeSubnetsOutput) []*Subnet {
 	subnets := make([]*Subnet, 0)
 	for _, v := range output.Subnets {
 		sn := &Subnet{
-			ID:        *v.SubnetId,
-			TagName:   extractTagName(v.Tags),
-			CidrBlock: *v.CidrBlock,
+			ID:               *v.SubnetId,
+			TagName:          extractTagName(v.Tags),
+			CidrBlock:        aws.StringValue(v.CidrBlock),
+			AvailabilityZone: aws.StringValue(v.AvailabilityZone),
 		}
+		ipv6 := make([]string, 0)
+		for _, cbs := range v.Ipv6CidrBlockAssociationSet {
+			if cbs.Ipv6CidrBlockState == nil || aws.StringValue(cbs.Ipv6CidrBlockState.State) == "associated" {
+				ipv6 = append(ipv6, aws.StringValue(cbs.Ipv6CidrBlock))
+			}
+		}
+		sn.Ipv6CidrBlocks = ipv6
 		subnets = append(subnets, sn)
 	}
 	return subnets
 }
+
+// parseDescribeInternetGatewaysOutput internet gateways by the ID of the VPC
+// they are attached to
+func parseDescribeInternetGatewaysOutput(output *ec2.DescribeInternetGatewaysOutput) map[string][]*InternetGateway {
+	igws := make(map[string][]*InternetGateway)
+	for _, v := range output.InternetGateways {
+		for _, at := range v.Attachments {
+			vpcID := aws.StringValue(at.VpcId)
+			igws[vpcID] = append(igws[vpcID], &InternetGateway{
+				ID:      aws.StringValue(v.InternetGatewayId),
+				TagName: extractTagName(v.Tags),
+				State:   aws.StringValue(at.State),
+			})
+		}
+	}
+	return igws
+}
+
+func parseDescribeEgressOnlyInternetGatewaysOutput(output *ec2.DescribeEgressOnlyInternetGatewaysOutput) map[string][]*InternetGateway {
+	eigws := make(map[string][]*InternetGateway)
+	for _, v := range output.EgressOnlyInternetGateways {
+		for _, at := range v.Attachments {
+			vpcID := aws.StringValue(at.VpcId)
+			eigws[vpcID] = append(eigws[vpcID], &InternetGateway{
+				ID:      aws.StringValue(v.EgressOnlyInternetGatewayId),
+				TagName: extractTagName(v.Tags),
+				State:   aws.StringValue(at.State),
+			})
+		}
+	}
+	return eigws
+}
+
+// goneNatGatewayState states of a NAT gateway that is being or has been
+// deleted. DescribeNatGateways lists deleted ones for about an hour.
+var goneNatGatewayState = map[string]bool{
+	"deleting": true,
+	"deleted":  true,
+}
+
+// parseDescribeNatGatewaysOutput NAT gateways by VPC ID, leaving out deleted
+// ones
+func parseDescribeNatGatewaysOutput(output *ec2.DescribeNatGatewaysOutput) map[string][]*NatGateway {
+	ngws := make(map[string][]*NatGateway)
+	for _, v := range output.NatGateways {
+		if goneNatGatewayState[aws.StringValue(v.State)] {
+			continue
+		}
+		ngw := &NatGateway{
+			ID:               aws.StringValue(v.NatGatewayId),
+			TagName:          extractTagName(v.Tags),
+			State:            aws.StringValue(v.State),
+			ConnectivityType: aws.StringValue(v.ConnectivityType),
+			SubnetID:         aws.StringValue(v.SubnetId),
+			Addresses:        make([]*NatGatewayAddress, 0),
+		}
+		for _, a := range v.NatGatewayAddresses {
+			ngw.Addresses = append(ngw.Addresses, &NatGatewayAddress{
+				AllocationID:       aws.StringValue(a.AllocationId),
+				PublicIP:           aws.StringValue(a.PublicIp),
+				PrivateIP:          aws.StringValue(a.PrivateIp),
+				NetworkInterfaceID: aws.StringValue(a.NetworkInterfaceId),
+			})
+		}
+		vpcID := aws.StringValue(v.VpcId)
+		ngws[vpcID] = append(ngws[vpcID], ngw)
+	}
+	return ngws
+}
+
+// parseDescribeVpcEndpointsOutput VPC endpoints by VPC ID
+func parseDescribeVpcEndpointsOutput(output *ec2.DescribeVpcEndpointsOutput) map[string][]*VpcEndpoint {
+	eps := make(map[string][]*VpcEndpoint)
+	for _, v := range output.VpcEndpoints {
+		vpcID := aws.StringValue(v.VpcId)
+		eps[vpcID] = append(eps[vpcID], &VpcEndpoint{
+			ID:            aws.StringValue(v.VpcEndpointId),
+			TagName:       extractTagName(v.Tags),
+			Type:          aws.StringValue(v.VpcEndpointType),
+			ServiceName:   aws.StringValue(v.ServiceName),
+			State:         aws.StringValue(v.State),
+			SubnetIDs:     aws.StringValueSlice(v.SubnetIds),
+			RouteTableIDs: aws.StringValueSlice(v.RouteTableIds),
+			Policy:        aws.StringValue(v.PolicyDocument),
+		})
+	}
+	return eps
+}
+
+// parseDescribeVpnGatewaysOutput virtual private gateways by the ID of the
+// VPC they are attached to. Detached attachments are left out.
+func parseDescribeVpnGatewaysOutput(output *ec2.DescribeVpnGatewaysOutput) map[string][]*VpnGateway {
+	vgws := make(map[string][]*VpnGateway)
+	for _, v := range output.VpnGateways {
+		for _, at := range v.VpcAttachments {
+			if aws.StringValue(at.State) == "detached" {
+				continue
+			}
+			vpcID := aws.StringValue(at.VpcId)
+			vgws[vpcID] = append(vgws[vpcID], &VpnGateway{
+				ID:            aws.StringValue(v.VpnGatewayId),
+				TagName:       extractTagName(v.Tags),
+				State:         aws.StringValue(v.State),
+				Type:          aws.StringValue(v.Type),
+				AmazonSideAsn: aws.Int64Value(v.AmazonSideAsn),
+				Connections:   make([]*VpnConnection, 0),
+			})
+		}
+	}
+	return vgws
+}
+
+// parseDescribeVpnConnectionsOutput VPN connections by virtual private
+// gateway ID. Deleted ones and those to a transit gateway are left out.
+func parseDescribeVpnConnectionsOutput(output *ec2.DescribeVpnConnectionsOutput) map[string][]*VpnConnection {
+	conns := make(map[string][]*VpnConnection)
+	for _, v := range output.VpnConnections {
+		if v.VpnGatewayId == nil || aws.StringValue(v.State) == "deleted" {
+			continue
+		}
+		vgwID := aws.StringValue(v.VpnGatewayId)
+		conns[vgwID] = append(conns[vgwID], &VpnConnection{
+			ID:                aws.StringValue(v.VpnConnectionId),
+			TagName:           extractTagName(v.Tags),
+			State:             aws.StringValue(v.State),
+			Type:              aws.StringValue(v.Type),
+			CustomerGatewayID: aws.StringValue(v.CustomerGatewayId),
+		})
+	}
+	return conns
+}
+
+// parseDescribeCustomerGatewaysOutput customer gateways by ID
+func parseDescribeCustomerGatewaysOutput(output *ec2.DescribeCustomerGatewaysOutput) map[string]*CustomerGateway {
+	cgws := make(map[string]*CustomerGateway)
+	for _, v := range output.CustomerGateways {
+		cgws[aws.StringValue(v.CustomerGatewayId)] = &CustomerGateway{
+			ID:         aws.StringValue(v.CustomerGatewayId),
+			TagName:    extractTagName(v.Tags),
+			State:      aws.StringValue(v.State),
+			Type:       aws.StringValue(v.Type),
+			IPAddress:  aws.StringValue(v.IpAddress),
+			BgpAsn:     aws.StringValue(v.BgpAsn),
+			DeviceName: aws.StringValue(v.DeviceName),
+		}
+	}
+	return cgws
+}
@@ -1,29 +1,364 @@
 package cmd
 
+import "fmt"
+
 type Vpc struct {
 	ID                   string
+	Account              string
+	Region               string
 	TagName              string
 	CidrBlock            string
 	AssociatedCidrBlocks []string
+	Ipv6CidrBlocks       []string
 	RouteTables          []*RouteTable
 	Subnets              []*Subnet
+	// InternetGateways and the other gateways and endpoints attached to or
+	// placed in the VPC, the targets of its routes
+	InternetGateways           []*InternetGateway
+	EgressOnlyInternetGateways []*InternetGateway
+	NatGateways                []*NatGateway
+	VpcEndpoints               []*VpcEndpoint
+	VpnGateways                []*VpnGateway
+}
+
+// CidrBlocks the primary IPv4 block and the IPv6 blocks, for headings
+func (v *Vpc) CidrBlocks() []string {
+	return append([]string{v.CidrBlock}, v.Ipv6CidrBlocks...)
+}
+
+// HasGateway whether id is one of the gateways or endpoints of v, which
+// routes to it link to
+func (v *Vpc) HasGateway(id string) bool {
+	_, ok := v.gatewayName(id)
+	return ok
+}
+
+// gatewayName tag name of the gateway or endpoint id of v, and whether v
+// has it
+func (v *Vpc) gatewayName(id string) (string, bool) {
+	for _, gw := range v.InternetGateways {
+		if gw.ID == id {
+			return gw.TagName, true
+		}
+	}
+	for _, gw := range v.EgressOnlyInternetGateways {
+		if gw.ID == id {
+			return gw.TagName, true
+		}
+	}
+	for _, gw := range v.NatGateways {
+		if gw.ID == id {
+			return gw.TagName, true
+		}
+	}
+	for _, ep := range v.VpcEndpoints {
+		if ep.ID == id {
+			return ep.TagName, true
+		}
+	}
+	for _, gw := range v.VpnGateways {
+		if gw.ID == id {
+			return gw.TagName, true
+		}
+	}
+	return "", false
 }
 
 type RouteTable struct {
-	ID                 string
-	TagName            string
-	Routes             []*Route
-	AssociationSubnets []string //subnet-id
+	ID      string
+	TagName string
+	// Main whether rt is the main route table of its VPC, used by every
+	// subnet without an association of its own
+	Main   bool
+	Routes []*Route
+	// AssociationSubnets subnets associated explicitly
+	AssociationSubnets []string
+	// AssociationGateways internet and virtual private gateways associated as
+	// edge associations, routing traffic that enters the VPC through them
+	AssociationGateways []string
+}
+
+// IsMain whether rt is the main route table of its VPC
+func (rt *RouteTable) IsMain() bool {
+	return rt.Main
 }
 
 type Route struct {
-	DestinationCidrBlock string
-	Router               string
+	DestinationCidrBlock     string
+	DestinationIpv6CidrBlock string
+	DestinationPrefixListID  string
+	// PrefixList the destination prefix list, nil if it was not described
+	PrefixList *PrefixList
+	// Router target ID, of the kind RouterType tells
+	Router     string
+	RouterType string
+	// NetworkInterfaceID interface of an instance target
+	NetworkInterfaceID string
+	// State active or blackhole, the latter when the target is gone
+	State string
+	// Origin CreateRouteTable, CreateRoute or EnableVgwRoutePropagation
+	Origin string
+}
+
+// Kinds of route target, in Route.RouterType
+const (
+	routerLocal                     = "local"
+	routerGateway                   = "gateway"
+	routerInternetGateway           = "internet-gateway"
+	routerVpnGateway                = "vpn-gateway"
+	routerVpcEndpoint               = "vpc-endpoint"
+	routerNatGateway                = "nat-gateway"
+	routerPeeringConnection         = "vpc-peering-connection"
+	routerEgressOnlyInternetGateway = "egress-only-internet-gateway"
+	routerTransitGateway            = "transit-gateway"
+	routerInstance                  = "instance"
+	routerNetworkInterface          = "network-interface"
+	routerCarrierGateway            = "carrier-gateway"
+	routerLocalGateway              = "local-gateway"
+	routerCoreNetwork               = "core-network"
+)
+
+// Destination the IPv4 CIDR, IPv6 CIDR or prefix list ID r routes,
+// whichever it has
+func (r *Route) Destination() string {
+	switch {
+	case r.DestinationCidrBlock != "":
+		return r.DestinationCidrBlock
+	case r.DestinationIpv6CidrBlock != "":
+		return r.DestinationIpv6CidrBlock
+	}
+	return r.DestinationPrefixListID
+}
+
+// DestinationName Destination with the name of the prefix list, if known
+func (r *Route) DestinationName() string {
+	if r.PrefixList != nil && r.PrefixList.Name != "" {
+		return fmt.Sprintf("%s (%s)", r.DestinationPrefixListID, r.PrefixList.Name)
+	}
+	return r.Destination()
+}
+
+// IsBlackhole whether the target of r no longer exists
+func (r *Route) IsBlackhole() bool {
+	return r.State == "blackhole"
+}
+
+// IsPropagated whether a virtual private gateway propagated r
+func (r *Route) IsPropagated() bool {
+	return r.Origin == "EnableVgwRoutePropagation"
+}
+
+// TargetName Router, marked when it is a blackhole or propagated
+func (r *Route) TargetName() string {
+	name := r.Router
+	if r.IsPropagated() {
+		name += " (propagated)"
+	}
+	if r.IsBlackhole() {
+		name += " (blackhole)"
+	}
+	return name
+}
+
+// PrefixList a managed prefix list and its CIDRs
+type PrefixList struct {
+	ID    string
+	Name  string
+	Cidrs []string
 }
 
 type Subnet struct {
 	ID                   string
 	TagName              string
 	CidrBlock            string
+	Ipv6CidrBlocks       []string
+	AvailabilityZone     string
 	AssociatedRouteTable *RouteTable
+	// ImplicitAssociation whether AssociatedRouteTable is the main route
+	// table, used for want of an association of the subnet's own
+	ImplicitAssociation bool
+}
+
+// RouteTableName name of the route table sn uses, marked when implicit
+func (sn *Subnet) RouteTableName() string {
+	if sn.AssociatedRouteTable == nil {
+		return ""
+	}
+	name := sn.AssociatedRouteTable.TagName
+	if name == "" {
+		name = sn.AssociatedRouteTable.ID
+	}
+	if sn.ImplicitAssociation {
+		name += " (implicit)"
+	}
+	return name
+}
+
+// CidrBlocks the IPv4 block, if any, and the IPv6 blocks
+func (sn *Subnet) CidrBlocks() []string {
+	blocks := make([]string, 0)
+	if sn.CidrBlock != "" {
+		blocks = append(blocks, sn.CidrBlock)
+	}
+	return append(blocks, sn.Ipv6CidrBlocks...)
+}
+
+// InternetGateway an internet or egress-only internet gateway
+type InternetGateway struct {
+	ID      string
+	TagName string
+	// State of the attachment to the VPC
+	State string
+}
+
+type NatGateway struct {
+	ID      string
+	TagName string
+	State   string
+	// ConnectivityType public or private
+	ConnectivityType string
+	SubnetID         string
+	Addresses        []*NatGatewayAddress
+}
+
+// NatGatewayAddress an address of a NAT gateway. A public one has the
+// Elastic IP and its allocation.
+type NatGatewayAddress struct {
+	AllocationID       string
+	PublicIP           string
+	PrivateIP          string
+	NetworkInterfaceID string
+}
+
+type VpcEndpoint struct {
+	ID      string
+	TagName string
+	// Type Gateway, Interface or GatewayLoadBalancer
+	Type        string
+	ServiceName string
+	State       string
+	// SubnetIDs subnets of an interface endpoint
+	SubnetIDs []string
+	// RouteTableIDs route tables of a gateway endpoint
+	RouteTableIDs []string
+	Policy        string
+}
+
+// VpnGateway a virtual private gateway and its VPN connections
+type VpnGateway struct {
+	ID            string
+	TagName       string
+	State         string
+	Type          string
+	AmazonSideAsn int64
+	Connections   []*VpnConnection
+}
+
+type VpnConnection struct {
+	ID                string
+	TagName           string
+	State             string
+	Type              string
+	CustomerGatewayID string
+	// CustomerGateway nil if it was not described
+	CustomerGateway *CustomerGateway
+}
+
+type CustomerGateway struct {
+	ID         string
+	TagName    string
+	State      string
+	Type       string
+	IPAddress  string
+	BgpAsn     string
+	DeviceName string
+}
+
+// PeeringConnection a VPC peering connection, seen from the account and
+// region it was collected in
+type PeeringConnection struct {
+	ID      string
+	Account string
+	Region  string
+	TagName string
+	// Status e.g. active, pending-acceptance, rejected or deleted
+	Status        string
+	StatusMessage string
+	Requester     *PeeringVpc
+	Accepter      *PeeringVpc
+}
+
+// PeeringVpc one side of a peering connection, possibly a VPC of another
+// account or region
+type PeeringVpc struct {
+	VpcID      string
+	Account    string
+	Region     string
+	CidrBlocks []string
+}
+
+// TransitGateway a transit gateway with the attachments and route tables
+// collected for it. Only its owner sees the route tables, other accounts
+// see their own attachments.
+type TransitGateway struct {
+	ID      string
+	Account string
+	Region  string
+	// OwnerID ID of the account owning the transit gateway, empty when no
+	// attachment was collected to tell it
+	OwnerID     string
+	Attachments []*TransitGatewayAttachment
+	RouteTables []*TransitGatewayRouteTable
+}
+
+type TransitGatewayAttachment struct {
+	ID      string
+	TagName string
+	// ResourceType vpc, vpn, direct-connect-gateway, peering or connect
+	ResourceType  string
+	ResourceID    string
+	ResourceOwner string
+	State         string
+	// RouteTableID transit gateway route table the attachment is associated
+	// with, which routes the traffic coming from it
+	RouteTableID string
+}
+
+type TransitGatewayRouteTable struct {
+	ID                 string
+	TagName            string
+	DefaultAssociation bool
+	DefaultPropagation bool
+	Routes             []*TransitGatewayRoute
+}
+
+type TransitGatewayRoute struct {
+	Destination string
+	// Type static or propagated
+	Type          string
+	State         string
+	AttachmentIDs []string
+}
+
+// RouteUse a route of a collected route table, listed with the peering
+// connection or transit gateway it goes to
+type RouteUse struct {
+	Vpc        *Vpc
+	RouteTable *RouteTable
+	Route      *Route
+}
+
+// RoutesTo routes of the collected route tables whose target is id
+func (nt *Network) RoutesTo(id string) []*RouteUse {
+	uses := make([]*RouteUse, 0)
+	for _, v := range nt.Vpcs {
+		for _, rt := range v.RouteTables {
+			for _, r := range rt.Routes {
+				if r.Router == id {
+					uses = append(uses, &RouteUse{Vpc: v, RouteTable: rt, Route: r})
+				}
+			}
+		}
+	}
+	return uses
 }
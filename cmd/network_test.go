@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tealeg/xlsx"
+)
+
+func collectFixtureNetwork(t *testing.T) *Network {
+	t.Helper()
+	nt := &Network{Errs: make([]error, 0)}
+	nt.collect(fixtureTarget(t))
+	if err := nt.flattenErrs(); err != nil {
+		t.Fatal(err)
+	}
+	return nt
+}
+
+func TestNetworkCollect(t *testing.T) {
+	nt := collectFixtureNetwork(t)
+	if len(nt.Vpcs) != 1 {
+		t.Fatalf("vpcs = %d, want 1", len(nt.Vpcs))
+	}
+	v := nt.Vpcs[0]
+	if v.ID != "vpc-0a1b2c3d" || v.TagName != "sample-vpc" || v.Region != defaultRegion {
+		t.Errorf("vpc = %s %s %s", v.ID, v.TagName, v.Region)
+	}
+	if len(v.RouteTables) != 3 || len(v.Subnets) != 2 {
+		t.Fatalf("route tables = %d, subnets = %d, want 3 and 2", len(v.RouteTables), len(v.Subnets))
+	}
+	if len(v.NatGateways) != 1 || v.NatGateways[0].ID != "nat-0a1b2c3d" {
+		t.Errorf("nat gateways = %v, want only nat-0a1b2c3d", v.NatGateways)
+	}
+	if strings.Join(v.Ipv6CidrBlocks, ",") != "2406:da14:abc:de00::/56" {
+		t.Errorf("vpc IPv6 blocks = %v", v.Ipv6CidrBlocks)
+	}
+	if len(v.EgressOnlyInternetGateways) != 1 || v.EgressOnlyInternetGateways[0].ID != "eigw-0a1b2c3d" || !v.HasGateway("eigw-0a1b2c3d") {
+		t.Errorf("egress-only gateways = %v, want only eigw-0a1b2c3d", v.EgressOnlyInternetGateways)
+	}
+	ipv6Targets := make(map[string]string)
+	for _, rt := range v.RouteTables {
+		for _, r := range rt.Routes {
+			if r.DestinationIpv6CidrBlock == "::/0" {
+				ipv6Targets[rt.ID] = r.RouterType + " " + r.Router
+			}
+		}
+	}
+	if want := map[string]string{
+		"rtb-0main":   routerEgressOnlyInternetGateway + " eigw-0a1b2c3d",
+		"rtb-0public": routerInternetGateway + " igw-0a1b2c3d",
+	}; !reflect.DeepEqual(ipv6Targets, want) {
+		t.Errorf("::/0 routes = %v, want %v", ipv6Targets, want)
+	}
+	for _, sn := range v.Subnets {
+		want := map[string]string{"subnet-0public": "rtb-0public", "subnet-0private": "rtb-0main"}[sn.ID]
+		if sn.AssociatedRouteTable == nil || sn.AssociatedRouteTable.ID != want {
+			t.Errorf("%s route table = %v, want %s", sn.ID, sn.AssociatedRouteTable, want)
+		}
+		if sn.ImplicitAssociation != (sn.ID == "subnet-0private") {
+			t.Errorf("%s implicit association = %v", sn.ID, sn.ImplicitAssociation)
+		}
+		want = map[string]string{"subnet-0public": "2406:da14:abc:de00::/64", "subnet-0private": "2406:da14:abc:de01::/64"}[sn.ID]
+		if strings.Join(sn.Ipv6CidrBlocks, ",") != want {
+			t.Errorf("%s IPv6 blocks = %v, want %s", sn.ID, sn.Ipv6CidrBlocks, want)
+		}
+	}
+}
+
+func TestNetworkConvertCSV(t *testing.T) {
+	nt := collectFixtureNetwork(t)
+	dir := t.TempDir()
+	if err := nt.convertCSV(dir); err != nil {
+		t.Fatal(err)
+	}
+	routes := csvRows(t, dir, "routes")
+	if row := findRow(routes, map[string]string{"route_table_id": "rtb-0main", "destination": "0.0.0.0/0"}); row == nil || row["target"] != "nat-0a1b2c3d" || row["target_type"] != routerNatGateway {
+		t.Errorf("default route of rtb-0main = %v", row)
+	}
+	if row := findRow(routes, map[string]string{"destination": "192.168.0.0/16"}); row == nil || row["state"] != "blackhole" || row["blackhole"] != "true" {
+		t.Errorf("blackhole route to pcx-0a1b2c3d = %v", row)
+	}
+	if row := findRow(routes, map[string]string{"route_table_id": "rtb-0main", "destination": "0.0.0.0/0"}); row == nil || row["blackhole"] != "false" {
+		t.Errorf("active route blackhole = %v", row)
+	}
+	if row := findRow(routes, map[string]string{"route_table_id": "rtb-0main", "destination": "::/0"}); row == nil || row["target"] != "eigw-0a1b2c3d" || row["target_type"] != routerEgressOnlyInternetGateway {
+		t.Errorf("IPv6 default route of rtb-0main = %v", row)
+	}
+	if row := findRow(routes, map[string]string{"route_table_id": "rtb-0public", "destination": "::/0"}); row == nil || row["target"] != "igw-0a1b2c3d" {
+		t.Errorf("IPv6 default route of rtb-0public = %v", row)
+	}
+	subnets := csvRows(t, dir, "subnets")
+	if row := findRow(subnets, map[string]string{"subnet_id": "subnet-0private"}); row == nil || row["route_table_id"] != "rtb-0main" || row["implicit_association"] != "true" || row["ipv6_cidr_blocks"] != "2406:da14:abc:de01::/64" {
+		t.Errorf("subnet-0private = %v", row)
+	}
+	vpcs := csvRows(t, dir, "vpcs")
+	if len(vpcs) != 1 || vpcs[0]["ipv6_cidr_blocks"] != "2406:da14:abc:de00::/56" {
+		t.Errorf("vpcs.csv = %v", vpcs)
+	}
+	if row := findRow(csvRows(t, dir, "internet_gateways"), map[string]string{"gateway_id": "eigw-0a1b2c3d"}); row == nil || row["type"] != routerEgressOnlyInternetGateway {
+		t.Errorf("eigw-0a1b2c3d = %v", row)
+	}
+}
+
+func TestNetworkConvertJSON(t *testing.T) {
+	nt := collectFixtureNetwork(t)
+	filename := filepath.Join(t.TempDir(), "network")
+	if err := nt.convertJSON(filename); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filename + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report jsonNetworkReport
+	if err := json.Unmarshal(b, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.SchemaVersion != jsonSchemaVersion || report.Report != "network" {
+		t.Errorf("header = %d %s", report.SchemaVersion, report.Report)
+	}
+	if len(report.Vpcs) != 1 || len(report.RouteTables) != 3 || len(report.Subnets) != 2 {
+		t.Errorf("vpcs, route tables, subnets = %d, %d, %d", len(report.Vpcs), len(report.RouteTables), len(report.Subnets))
+	}
+}
+
+func TestNetworkXlsxSheets(t *testing.T) {
+	nt := collectFixtureNetwork(t)
+	file := xlsx.NewFile()
+	sheets, subnets := nt.addXlsxSheets(file)
+	if len(sheets) == 0 || sheets[0] != "sample-vpc" {
+		t.Fatalf("sheets = %v", sheets)
+	}
+	sheet := file.Sheet["sample-vpc"]
+	if head := sheet.Cell(0, 0).Value; !strings.HasPrefix(head, "sample-vpc") || !strings.Contains(head, "10.0.0.0/16") {
+		t.Errorf("head = %q", head)
+	}
+	loc, ok := subnets["subnet-0public"]
+	if !ok {
+		t.Fatal("subnet-0public not written")
+	}
+	if v := file.Sheet[loc.sheet].Cell(loc.row, loc.col).Value; !strings.Contains(v, "sample-public-a") {
+		t.Errorf("subnet-0public cell = %q", v)
+	}
+	if v := sheet.Cell(loc.row, 4).Value; v != defaultRegion {
+		t.Errorf("subnet-0public region = %q", v)
+	}
+}
+
+func TestNetworkXlsxSheetsDuplicateNames(t *testing.T) {
+	nt := collectFixtureNetwork(t)
+	copied := *nt.Vpcs[0]
+	copied.ID = "vpc-0copy"
+	nt.Vpcs = append(nt.Vpcs, &copied)
+	file := xlsx.NewFile()
+	sheets, _ := nt.addXlsxSheets(file)
+	if len(sheets) < 2 || sheets[0] != "sample-vpc" || sheets[1] != "sample-vpc~2" {
+		t.Fatalf("sheets = %v", sheets)
+	}
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atsushi-ishibashi/aws-state-report/util"
+	"github.com/urfave/cli"
+)
+
+// stdout --output writing the report to standard output
+const stdout = "-"
+
+// outputName --output of the command, else --src, both with or without the
+// extension. Progress goes to stderr when the report goes to stdout.
+func outputName(c *cli.Context) string {
+	name := c.String("output")
+	if name == "" {
+		name = c.String("src")
+	}
+	if name == stdout {
+		util.Out = os.Stderr
+	}
+	return name
+}
+
+// outputPath filename with ext added unless it ends with it already
+func outputPath(filename, ext string) string {
+	if strings.EqualFold(filepath.Ext(filename), "."+ext) {
+		return filename
+	}
+	return filename + "." + ext
+}
+
+// writeOutput writes b to filename with ext, creating its directory, or to
+// stdout for "-"
+func writeOutput(filename, ext string, b []byte) error {
+	if filename == stdout {
+		_, err := os.Stdout.Write(b)
+		return err
+	}
+	path := outputPath(filename, ext)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// fileFormats formats written as a binary file or a directory of files,
+// which cannot go to stdout
+var fileFormats = map[string]bool{"xlsx": true, "pdf": true, "csv": true}
+
+// createPath path of filename with ext, its directory created, for the
+// formats that write files themselves and not to stdout
+func createPath(filename, ext string) (string, error) {
+	if filename == stdout {
+		return "", fmt.Errorf("%s cannot be written to stdout, give --output a path", ext)
+	}
+	path := outputPath(filename, ext)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import "sync"
+
+// parallel calls fn for every index below n on up to workers goroutines and
+// returns when all calls are done. fn should keep its result at index i, so
+// results stay in input order however the calls interleave.
+func parallel(workers, n int, fn func(i int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	idx := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range idx {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		idx <- i
+	}
+	close(idx)
+	wg.Wait()
+}
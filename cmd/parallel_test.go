@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/atsushi-ishibashi/aws-state-report/svc"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+func TestParallel(t *testing.T) {
+	const n, workers = 50, 4
+	var mu sync.Mutex
+	running, most := 0, 0
+	out := make([]int, n)
+	parallel(workers, n, func(i int) {
+		mu.Lock()
+		running++
+		if running > most {
+			most = running
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		out[i] = i * i
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+	for i, v := range out {
+		if v != i*i {
+			t.Fatalf("out[%d] = %d", i, v)
+		}
+	}
+	if most > workers {
+		t.Errorf("%d calls at once, want at most %d", most, workers)
+	}
+}
+
+// slowManager serves responses, answering the calls for earlier VPCs and
+// principals last so that workers finish out of order
+type slowManager struct {
+	svc.Manager
+	n int
+}
+
+func (m *slowManager) wait(name string) {
+	var i int
+	fmt.Sscanf(name[strings.LastIndex(name, "-")+1:], "%d", &i)
+	time.Sleep(time.Duration(m.n-i) * time.Millisecond)
+}
+
+func (m *slowManager) FetchRouteTablesWithVpc(vpcID string) (*ec2.DescribeRouteTablesOutput, error) {
+	m.wait(vpcID)
+	return m.Manager.FetchRouteTablesWithVpc(vpcID)
+}
+
+func (m *slowManager) FetchGroupPolicies(name *string) (*iam.ListGroupPoliciesOutput, error) {
+	m.wait(*name)
+	return m.Manager.FetchGroupPolicies(name)
+}
+
+func (m *slowManager) FetchUserPolicies(name *string) (*iam.ListUserPoliciesOutput, error) {
+	m.wait(*name)
+	return m.Manager.FetchUserPolicies(name)
+}
+
+func (m *slowManager) FetchRolePolicies(name *string) (*iam.ListRolePoliciesOutput, error) {
+	m.wait(*name)
+	return m.Manager.FetchRolePolicies(name)
+}
+
+// orderResponses n VPCs with two route tables each, and n groups, users
+// and roles
+func orderResponses(n int) *svc.Responses {
+	r := &svc.Responses{
+		DescribeVpcs:        &ec2.DescribeVpcsOutput{},
+		DescribeRouteTables: &ec2.DescribeRouteTablesOutput{},
+		ListGroups:          &iam.ListGroupsOutput{},
+		ListUsers:           &iam.ListUsersOutput{},
+		ListRoles:           &iam.ListRolesOutput{},
+	}
+	for i := 0; i < n; i++ {
+		vpcID := fmt.Sprintf("vpc-%d", i)
+		r.DescribeVpcs.Vpcs = append(r.DescribeVpcs.Vpcs, &ec2.Vpc{VpcId: aws.String(vpcID), CidrBlock: aws.String(fmt.Sprintf("10.%d.0.0/16", i))})
+		for _, rt := range []string{"a", "b"} {
+			r.DescribeRouteTables.RouteTables = append(r.DescribeRouteTables.RouteTables, &ec2.RouteTable{
+				RouteTableId: aws.String(fmt.Sprintf("rtb-%s-%d", rt, i)),
+				VpcId:        aws.String(vpcID),
+			})
+		}
+		r.ListGroups.Groups = append(r.ListGroups.Groups, &iam.Group{GroupName: aws.String(fmt.Sprintf("group-%d", i))})
+		r.ListUsers.Users = append(r.ListUsers.Users, &iam.User{UserName: aws.String(fmt.Sprintf("user-%d", i))})
+		r.ListRoles.Roles = append(r.ListRoles.Roles, &iam.Role{RoleName: aws.String(fmt.Sprintf("role-%d", i)), AssumeRolePolicyDocument: aws.String("{}")})
+	}
+	return r
+}
+
+// collectedOrder IDs of the VPCs, route tables and IAM principals collected
+// with workers, in report order
+func collectedOrder(t *testing.T, workers int) []string {
+	t.Helper()
+	const n = 8
+	target := &target{Region: defaultRegion, manager: &slowManager{Manager: svc.NewResponsesManager(orderResponses(n)), n: n}, workers: workers}
+	nt := &Network{Errs: make([]error, 0)}
+	nt.collect(target)
+	iam := &IAM{Errs: make([]error, 0)}
+	iam.collect(target)
+	if len(nt.Errs) > 0 || len(iam.Errs) > 0 {
+		t.Fatalf("errs = %v %v", nt.Errs, iam.Errs)
+	}
+	ids := make([]string, 0)
+	for _, v := range nt.Vpcs {
+		ids = append(ids, v.ID)
+		for _, rt := range v.RouteTables {
+			ids = append(ids, rt.ID)
+		}
+	}
+	for _, v := range iam.Groups {
+		ids = append(ids, v.Name)
+	}
+	for _, v := range iam.Users {
+		ids = append(ids, v.Name)
+	}
+	for _, v := range iam.Roles {
+		ids = append(ids, v.Name)
+	}
+	return ids
+}
+
+func TestCollectOrderWithWorkers(t *testing.T) {
+	want := strings.Join(collectedOrder(t, 1), ",")
+	if !strings.HasPrefix(want, "vpc-0,rtb-a-0,rtb-b-0,vpc-1,") {
+		t.Fatalf("order with 1 worker = %s", want)
+	}
+	if got := strings.Join(collectedOrder(t, 4), ","); got != want {
+		t.Errorf("order with 4 workers = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	pdfLineHeight   = 5.0
+	pdfBottomMargin = 15.0
+)
+
+// pdfDoc A4 document with internal links by key and tables that continue on
+// the next page with their header repeated
+type pdfDoc struct {
+	*gofpdf.Fpdf
+	links map[string]int
+	tr    func(string) string
+}
+
+func newPdfDoc() *pdfDoc {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetAutoPageBreak(true, pdfBottomMargin)
+	pdf.AddPage()
+	pdf.SetFont("Arial", "", 9)
+	return &pdfDoc{Fpdf: pdf, links: make(map[string]int), tr: pdf.UnicodeTranslatorFromDescriptor("")}
+}
+
+// link ID of the link to key. Only link to keys that get an anchor.
+func (d *pdfDoc) link(key string) int {
+	if id, ok := d.links[key]; ok {
+		return id
+	}
+	d.links[key] = d.AddLink()
+	return d.links[key]
+}
+
+// anchor makes links to key point at the current position
+func (d *pdfDoc) anchor(key string) {
+	d.SetLink(d.link(key), -1, -1)
+}
+
+// heading bold line, in the outline at level
+func (d *pdfDoc) heading(text string, level int) {
+	text = strings.TrimSpace(text)
+	d.fit(pdfLineHeight * 3)
+	d.Bookmark(d.tr(text), level, -1)
+	d.SetFontStyle("B")
+	d.CellFormat(0, pdfLineHeight+2, d.tr(text), "B", 1, "L", false, 0, "")
+	d.SetFontStyle("")
+}
+
+// text lines wrapped at the page width, breaking pages as needed
+func (d *pdfDoc) text(s string) {
+	d.MultiCell(0, pdfLineHeight, d.tr(s), "", "L", false)
+}
+
+// fit starts a new page unless h more millimeters fit on this one, and
+// reports whether it did
+func (d *pdfDoc) fit(h float64) bool {
+	_, pageHeight := d.GetPageSize()
+	if d.GetY()+h <= pageHeight-pdfBottomMargin {
+		return false
+	}
+	d.AddPage()
+	return true
+}
+
+// pdfCell table cell text, linking to link when it is not 0
+type pdfCell struct {
+	text string
+	link int
+}
+
+// table rows under header, in columns of widths. Cells wrap, and a row that
+// does not fit goes to the next page under the header again. A nil header
+// draws none.
+func (d *pdfDoc) table(widths []float64, header []string, rows [][]pdfCell) {
+	head := make([]pdfCell, 0, len(header))
+	for _, v := range header {
+		head = append(head, pdfCell{text: v})
+	}
+	writeHead := func() {
+		if len(head) == 0 {
+			return
+		}
+		d.SetFontStyle("B")
+		d.row(widths, head)
+		d.SetFontStyle("")
+	}
+	if len(head) > 0 {
+		d.fit(d.rowHeight(widths, head) * 2)
+	}
+	writeHead()
+	for _, r := range rows {
+		if d.fit(d.rowHeight(widths, r)) {
+			writeHead()
+		}
+		d.row(widths, r)
+	}
+	d.Ln(pdfLineHeight)
+}
+
+func (d *pdfDoc) rowHeight(widths []float64, cells []pdfCell) float64 {
+	lines := 1
+	for i, c := range cells {
+		if n := len(d.SplitLines([]byte(d.tr(c.text)), widths[i])); n > lines {
+			lines = n
+		}
+	}
+	return float64(lines) * pdfLineHeight
+}
+
+func (d *pdfDoc) row(widths []float64, cells []pdfCell) {
+	h := d.rowHeight(widths, cells)
+	left, _, _, _ := d.GetMargins()
+	x, y := left, d.GetY()
+	for i, c := range cells {
+		d.Rect(x, y, widths[i], h, "D")
+		for j, line := range d.SplitLines([]byte(d.tr(c.text)), widths[i]) {
+			d.SetXY(x, y+float64(j)*pdfLineHeight)
+			d.CellFormat(widths[i], pdfLineHeight, string(line), "", 0, "L", false, c.link, "")
+		}
+		x += widths[i]
+	}
+	d.SetXY(left, y+h)
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// defaultFormat format when --format is not given
+const defaultFormat = "xlsx"
+
+// NetworkRenderer writes the network report. filename has no extension; the
+// renderer adds its own, or uses it as a directory.
+type NetworkRenderer interface {
+	RenderNetwork(nt *Network, filename string) error
+}
+
+// SGRenderer writes the sg report
+type SGRenderer interface {
+	RenderSG(sg *SG, filename string) error
+}
+
+// IAMRenderer writes the iam report
+type IAMRenderer interface {
+	RenderIAM(iam *IAM, filename string) error
+}
+
+// renderers output formats by name. A renderer implements NetworkRenderer,
+// SGRenderer and IAMRenderer for the reports it writes.
+var renderers = make(map[string]interface{})
+
+// RegisterRenderer makes r selectable by --format name. Call it from init or
+// before running the app; a later call for the same name replaces r.
+func RegisterRenderer(name string, r interface{}) {
+	renderers[name] = r
+}
+
+func init() {
+	RegisterRenderer("xlsx", xlsxRenderer{})
+	RegisterRenderer("json", jsonRenderer{})
+	RegisterRenderer("csv", csvRenderer{})
+	RegisterRenderer("html", htmlRenderer{})
+	RegisterRenderer("pdf", pdfRenderer{})
+	RegisterRenderer("dot", dotRenderer{})
+	RegisterRenderer("mermaid", mermaidRenderer{})
+}
+
+// rendersReport whether r writes report, one of network, sg and iam
+func rendersReport(r interface{}, report string) bool {
+	var ok bool
+	switch report {
+	case "network":
+		_, ok = r.(NetworkRenderer)
+	case "sg":
+		_, ok = r.(SGRenderer)
+	case "iam":
+		_, ok = r.(IAMRenderer)
+	}
+	return ok
+}
+
+// formatName --format of the command, else the global one, else xlsx
+func formatName(c *cli.Context) string {
+	if v := c.String("format"); v != "" {
+		return v
+	}
+	if v := c.GlobalString("format"); v != "" {
+		return v
+	}
+	return defaultFormat
+}
+
+// reportRenderer renderer of report: the --template when given, else the one
+// of format
+func reportRenderer(c *cli.Context, format, report string) (interface{}, error) {
+	if path := c.GlobalString("template"); path != "" {
+		return newTemplateRenderer(path)
+	}
+	return renderer(format, report)
+}
+
+// renderer the renderer of format for report
+func renderer(format, report string) (interface{}, error) {
+	if r, ok := renderers[format]; ok && rendersReport(r, report) {
+		return r, nil
+	}
+	formats := make([]string, 0, len(renderers))
+	for name, r := range renderers {
+		if rendersReport(r, report) {
+			formats = append(formats, name)
+		}
+	}
+	sort.Strings(formats)
+	return nil, fmt.Errorf("unknown format %q for %s, expected one of %s", format, report, strings.Join(formats, ", "))
+}
+
+type xlsxRenderer struct{}
+
+func (xlsxRenderer) RenderNetwork(nt *Network, filename string) error {
+	return nt.convertXlsx(filename)
+}
+
+func (xlsxRenderer) RenderSG(sg *SG, filename string) error {
+	return sg.convertXlsx(filename)
+}
+
+func (xlsxRenderer) RenderIAM(iam *IAM, filename string) error {
+	return iam.convertXlsx(filename)
+}
+
+type jsonRenderer struct{}
+
+func (jsonRenderer) RenderNetwork(nt *Network, filename string) error {
+	return nt.convertJSON(filename)
+}
+
+func (jsonRenderer) RenderSG(sg *SG, filename string) error {
+	return sg.convertJSON(filename)
+}
+
+func (jsonRenderer) RenderIAM(iam *IAM, filename string) error {
+	return iam.convertJSON(filename)
+}
+
+// csvRenderer uses filename as the directory of the CSV files
+type csvRenderer struct{}
+
+func (csvRenderer) RenderNetwork(nt *Network, filename string) error {
+	return nt.convertCSV(filename)
+}
+
+func (csvRenderer) RenderSG(sg *SG, filename string) error {
+	return sg.convertCSV(filename)
+}
+
+func (csvRenderer) RenderIAM(iam *IAM, filename string) error {
+	return iam.convertCSV(filename)
+}
+
+type htmlRenderer struct{}
+
+func (htmlRenderer) RenderNetwork(nt *Network, filename string) error {
+	return nt.convertHTML(filename)
+}
+
+func (htmlRenderer) RenderSG(sg *SG, filename string) error {
+	return sg.convertHTML(filename)
+}
+
+func (htmlRenderer) RenderIAM(iam *IAM, filename string) error {
+	return iam.convertHTML(filename)
+}
+
+type pdfRenderer struct{}
+
+func (pdfRenderer) RenderNetwork(nt *Network, filename string) error {
+	return nt.convertPdf(filename)
+}
+
+func (pdfRenderer) RenderSG(sg *SG, filename string) error {
+	return sg.convertPdf(filename)
+}
+
+func (pdfRenderer) RenderIAM(iam *IAM, filename string) error {
+	return iam.convertPdf(filename)
+}
+
+// dotRenderer and mermaidRenderer draw the network topology only
+type dotRenderer struct{}
+
+func (dotRenderer) RenderNetwork(nt *Network, filename string) error {
+	return nt.convertDot(filename)
+}
+
+type mermaidRenderer struct{}
+
+func (mermaidRenderer) RenderNetwork(nt *Network, filename string) error {
+	return nt.convertMermaid(filename)
+}
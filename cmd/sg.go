@@ -23,44 +23,113 @@ func NewSGCommand() cli.Command {
 				Usage: "file name to export",
 				Value: "sg",
 			},
+			cli.StringFlag{
+				Name:  "output",
+				Usage: "path to export to, directories created as needed. - for stdout (json, html). overrides --src",
+			},
+			cli.StringFlag{
+				Name:  "format",
+				Usage: "output format: xlsx, json, csv, html or pdf. overrides the global --format",
+			},
 		},
 		Action: func(c *cli.Context) error {
-			if err := util.ConfigAWS(c); err != nil {
+			format := formatName(c)
+			r, err := reportRenderer(c, format, "sg")
+			if err != nil {
+				return util.ErrorRed(err.Error())
+			}
+			name := outputName(c)
+			targets, err := newTargets(c, true)
+			if err != nil {
 				return util.ErrorRed(err.Error())
 			}
-			mng, err := svc.NewManager()
+			groups, err := groupTargets(c, targets, name, format)
 			if err != nil {
 				return util.ErrorRed(err.Error())
 			}
-			sg := &SG{
-				manager: mng,
-				Errs:    make([]error, 0),
+			sgs := make([]*SG, 0, len(groups))
+			for _, g := range groups {
+				sg := &SG{
+					Errs: make([]error, 0),
+				}
+				for _, t := range g.targets {
+					sg.collect(t)
+				}
+				sgs = append(sgs, sg)
+			}
+			printPageStats(targets)
+			errs := make([]error, 0)
+			for _, sg := range sgs {
+				if err := sg.flattenErrs(); err != nil && !continueOnError(c) {
+					return util.ErrorRed(err.Error())
+				}
+				errs = append(errs, sg.Errs...)
 			}
-			if err := sg.recursiveConstruct(); err != nil {
+			if err := saveSnapshot(c, targets); err != nil {
 				return util.ErrorRed(err.Error())
 			}
-			sg.convertXlsx(c.String("src"))
-			return nil
+			for i, sg := range sgs {
+				if err := r.(SGRenderer).RenderSG(sg, groups[i].filename(name)); err != nil {
+					return util.ErrorRed(err.Error())
+				}
+			}
+			return partialReportError(errs)
 		},
 	}
 }
 
 type SG struct {
 	SecurityGroups []*SecurityGroup
-	manager        *svc.Manager
-	Errs           []error
+	manager        svc.Manager
+	// rules whether to describe the rules of the groups one by one too,
+	// for their IDs
+	rules bool
+	Errs  []error
+}
+
+// collect adds the security groups of one target to sg
+func (sg *SG) collect(t *target) {
+	part := &SG{
+		manager: t.manager,
+		rules:   sg.rules,
+		Errs:    make([]error, 0),
+	}
+	part.recursiveConstruct()
+	for _, v := range part.SecurityGroups {
+		v.Account, v.Region = t.Account, t.Region
+		for _, ni := range v.NetworkInterfaces {
+			ni.Account, ni.Region = t.Account, t.Region
+			if ni.Ec2Instance != nil {
+				ni.Ec2Instance.Account, ni.Ec2Instance.Region = t.Account, t.Region
+			}
+		}
+	}
+	sg.SecurityGroups = append(sg.SecurityGroups, part.SecurityGroups...)
+	for _, err := range part.Errs {
+		sg.stackError(targetError(t, err))
+	}
+}
+
+// scopes accounts and regions of the security groups, in the order collected
+func (sg *SG) scopes() []scope {
+	scopes := make([]scope, 0)
+	for _, v := range sg.SecurityGroups {
+		scopes = appendScope(scopes, scope{Account: v.Account, Region: v.Region})
+	}
+	return scopes
 }
 
 func (sg *SG) recursiveConstruct() error {
 	sg.constructSecurityGroups().
-		constructNetworkInterfaces()
+		constructNetworkInterfaces().
+		constructSecurityGroupRules()
 	return sg.flattenErrs()
 }
 
 func (sg *SG) constructSecurityGroups() *SG {
 	result, err := sg.manager.FetchSecurityGroups()
 	if err != nil {
-		return sg.stackError(err)
+		return sg.stackError(newFetchError("DescribeSecurityGroups", "", err))
 	}
 	sg.SecurityGroups = parseDescribeSecurityGroupsOutput(result)
 	return sg
@@ -73,18 +142,12 @@ func (sg *SG) constructNetworkInterfaces() *SG {
 	}
 	result, err := sg.manager.FetchNetworkInterfaces(gids)
 	if err != nil {
-		return sg.stackError(err)
+		return sg.stackError(newFetchError("DescribeNetworkInterfaces", "", err))
 	}
 	nis := parseDescribeNetworkInterfacesOutput(result)
+	sg.constructEc2Instances(nis)
 	for _, v := range sg.SecurityGroups {
 		for _, ni := range nis {
-			if ni.InstanceID != "" {
-				diResult, err := sg.manager.FetchEc2Instance(aws.String(ni.InstanceID))
-				if err != nil {
-					return sg.stackError(err)
-				}
-				ni.Ec2Instance = parseDescribeInstancesOutput(diResult)
-			}
 			for _, gid := range ni.GroupIds {
 				if gid == v.ID {
 					v.NetworkInterfaces = append(v.NetworkInterfaces, ni)
@@ -96,20 +159,381 @@ func (sg *SG) constructNetworkInterfaces() *SG {
 	return sg
 }
 
+// constructSecurityGroupRules describes the rules of the groups when sg.rules
+// is set
+func (sg *SG) constructSecurityGroupRules() *SG {
+	if !sg.rules || len(sg.SecurityGroups) == 0 {
+		return sg
+	}
+	gids := make([]*string, 0)
+	for _, v := range sg.SecurityGroups {
+		gids = append(gids, aws.String(v.ID))
+	}
+	result, err := sg.manager.FetchSecurityGroupRules(gids)
+	if err != nil {
+		return sg.stackError(newFetchError("DescribeSecurityGroupRules", "", err))
+	}
+	rules := parseDescribeSecurityGroupRulesOutput(result)
+	for _, v := range sg.SecurityGroups {
+		v.Rules = rules[v.ID]
+	}
+	return sg
+}
+
+// constructEc2Instances describes the instances the network interfaces are
+// attached to, each once. Interfaces whose instance is gone keep only
+// InstanceID.
+func (sg *SG) constructEc2Instances(nis []*NetworkInterface) *SG {
+	iids := make([]*string, 0)
+	encountered := make(map[string]bool)
+	for _, ni := range nis {
+		if ni.InstanceID != "" && !encountered[ni.InstanceID] {
+			encountered[ni.InstanceID] = true
+			iids = append(iids, aws.String(ni.InstanceID))
+		}
+	}
+	if len(iids) == 0 {
+		return sg
+	}
+	result, err := sg.manager.FetchEc2Instances(iids)
+	if err != nil {
+		return sg.stackError(newFetchError("DescribeInstances", "", err))
+	}
+	instances := parseDescribeInstancesOutput(result)
+	for _, ni := range nis {
+		ni.Ec2Instance = instances[ni.InstanceID]
+	}
+	return sg
+}
+
+func (sg *SG) convertJSON(filename string) error {
+	report := &jsonSGReport{
+		jsonHeader:        newJSONHeader("sg", sg.Errs),
+		SecurityGroups:    make([]*jsonSecurityGroup, 0),
+		NetworkInterfaces: make([]*jsonNetworkInterface, 0),
+		Instances:         make([]*jsonInstance, 0),
+	}
+	nis := make([]*NetworkInterface, 0)
+	for _, v := range sg.SecurityGroups {
+		jsg := &jsonSecurityGroup{
+			ID:                  v.ID,
+			Account:             v.Account,
+			Region:              v.Region,
+			GroupName:           v.GroupName,
+			Name:                v.TagName,
+			Description:         v.Description,
+			Ingress:             jsonRules(v.Ingress),
+			Egress:              jsonRules(v.Egress),
+			NetworkInterfaceIDs: make([]string, 0),
+		}
+		for _, ni := range v.NetworkInterfaces {
+			jsg.NetworkInterfaceIDs = append(jsg.NetworkInterfaceIDs, ni.ID)
+		}
+		report.SecurityGroups = append(report.SecurityGroups, jsg)
+		nis = appendNIsWithoutDuplicate(nis, v.NetworkInterfaces)
+	}
+	encountered := make(map[*Instance]bool)
+	for _, ni := range nis {
+		report.NetworkInterfaces = append(report.NetworkInterfaces, &jsonNetworkInterface{
+			ID:               ni.ID,
+			Account:          ni.Account,
+			Region:           ni.Region,
+			Description:      ni.Description,
+			SubnetID:         ni.SubnetID,
+			InstanceID:       ni.InstanceID,
+			SecurityGroupIDs: nonNil(ni.GroupIds),
+		})
+		if ins := ni.Ec2Instance; ins != nil && !encountered[ins] {
+			encountered[ins] = true
+			report.Instances = append(report.Instances, &jsonInstance{
+				ID:               ins.ID,
+				Account:          ins.Account,
+				Region:           ins.Region,
+				Name:             ins.TagName,
+				AvailabilityZone: ins.AvailabilityZone,
+				InstanceType:     ins.InstanceType,
+				PrivateIP:        ins.PrivateIP,
+				PublicIP:         ins.PublicIP,
+				KeyName:          ins.KeyName,
+			})
+		}
+	}
+	return writeJSON(filename, report)
+}
+
+// convertCSV writes security_groups, sg_rules, enis, eni_security_groups and
+// instances into dir. sg_rules has one row per rule and target.
+func (sg *SG) convertCSV(dir string) error {
+	groups := newCSVTable("security_groups", "account", "region", "group_id", "group_name", "name", "description")
+	rules := newCSVTable("sg_rules", "account", "region", "group_id", "direction", "protocol", "from_port", "to_port", "target_type", "target")
+	enis := newCSVTable("enis", "account", "region", "eni_id", "description", "subnet_id", "instance_id")
+	eniGroups := newCSVTable("eni_security_groups", "account", "region", "eni_id", "group_id")
+	instances := newCSVTable("instances", "account", "region", "instance_id", "name", "availability_zone", "instance_type", "private_ip", "public_ip", "key_name")
+	nis := make([]*NetworkInterface, 0)
+	for _, v := range sg.SecurityGroups {
+		groups.add(v.Account, v.Region, v.ID, v.GroupName, v.TagName, v.Description)
+		for _, d := range []struct {
+			direction string
+			ips       []*IpPermission
+		}{{"ingress", v.Ingress}, {"egress", v.Egress}} {
+			for _, ip := range d.ips {
+				for _, r := range ip.AllRanges() {
+					rules.add(v.Account, v.Region, v.ID, d.direction, ip.Protocol, formatPort(ip.FromPort), formatPort(ip.ToPort), "cidr", r)
+				}
+				for _, p := range ip.PrefixListIds {
+					rules.add(v.Account, v.Region, v.ID, d.direction, ip.Protocol, formatPort(ip.FromPort), formatPort(ip.ToPort), "prefix_list", p)
+				}
+				for _, g := range ip.GroupIds {
+					rules.add(v.Account, v.Region, v.ID, d.direction, ip.Protocol, formatPort(ip.FromPort), formatPort(ip.ToPort), "security_group", g)
+				}
+			}
+		}
+		nis = appendNIsWithoutDuplicate(nis, v.NetworkInterfaces)
+	}
+	encountered := make(map[*Instance]bool)
+	for _, ni := range nis {
+		enis.add(ni.Account, ni.Region, ni.ID, ni.Description, ni.SubnetID, ni.InstanceID)
+		for _, g := range ni.GroupIds {
+			eniGroups.add(ni.Account, ni.Region, ni.ID, g)
+		}
+		if ins := ni.Ec2Instance; ins != nil && !encountered[ins] {
+			encountered[ins] = true
+			instances.add(ins.Account, ins.Region, ins.ID, ins.TagName, ins.AvailabilityZone, ins.InstanceType, ins.PrivateIP, ins.PublicIP, ins.KeyName)
+		}
+	}
+	return writeCSV(dir, groups, rules, enis, eniGroups, instances, csvErrors(sg.Errs))
+}
+
+// sgHTML what the sg HTML report renders, interfaces and instances once each
+type sgHTML struct {
+	SecurityGroups    []*SecurityGroup
+	NetworkInterfaces []*NetworkInterface
+	Instances         []*Instance
+}
+
+func (sg *SG) convertHTML(filename string) error {
+	data := &sgHTML{
+		SecurityGroups:    sg.SecurityGroups,
+		NetworkInterfaces: make([]*NetworkInterface, 0),
+		Instances:         make([]*Instance, 0),
+	}
+	for _, v := range sg.SecurityGroups {
+		data.NetworkInterfaces = appendNIsWithoutDuplicate(data.NetworkInterfaces, v.NetworkInterfaces)
+	}
+	encountered := make(map[*Instance]bool)
+	for _, ni := range data.NetworkInterfaces {
+		if ni.Ec2Instance != nil && !encountered[ni.Ec2Instance] {
+			encountered[ni.Ec2Instance] = true
+			data.Instances = append(data.Instances, ni.Ec2Instance)
+		}
+	}
+	return writeHTML(filename, "sg", sgHTMLTemplate, data, sg.Errs, nil)
+}
+
+const sgHTMLTemplate = `
+{{define "toc"}}
+<strong>Security Groups</strong>
+<ul>
+{{- range .SecurityGroups}}
+<li><a href="#{{anchor "sg" .Account .Region .ID}}">{{.GroupName}} {{.ID}}</a> <span class="meta">{{.Account}} {{.Region}}</span></li>
+{{- end}}
+</ul>
+<p><a href="#network-interfaces">Network Interfaces ({{len .NetworkInterfaces}})</a> <a href="#instances">Instances ({{len .Instances}})</a></p>
+{{end}}
+
+{{define "body"}}
+<h2>Security Groups</h2>
+{{- range $sg := .SecurityGroups}}
+<details class="item" id="{{anchor "sg" .Account .Region .ID}}">
+<summary>{{.GroupName}} {{.ID}} <span class="meta">{{.TagName}} {{.Account}} {{.Region}}</span></summary>
+<p>{{.Description}}</p>
+<table>
+<tr><th colspan="3">Ingress</th></tr>
+<tr><th>Protocol</th><th>Port</th><th>CIDR / Prefix List / Security Group</th></tr>
+{{- range .Ingress}}
+<tr><td>{{.Protocol}}</td><td>{{.FromPort}}-{{.ToPort}}</td><td>
+{{- range .AllRanges}}{{.}}<br>{{end}}
+{{- range .PrefixListIds}}{{.}}<br>{{end}}
+{{- range .GroupIds}}<a href="#{{anchor "sg" $sg.Account $sg.Region .}}">{{.}}</a><br>{{end}}</td></tr>
+{{- end}}
+<tr><th colspan="3">Egress</th></tr>
+<tr><th>Protocol</th><th>Port</th><th>CIDR / Prefix List / Security Group</th></tr>
+{{- range .Egress}}
+<tr><td>{{.Protocol}}</td><td>{{.FromPort}}-{{.ToPort}}</td><td>
+{{- range .AllRanges}}{{.}}<br>{{end}}
+{{- range .PrefixListIds}}{{.}}<br>{{end}}
+{{- range .GroupIds}}<a href="#{{anchor "sg" $sg.Account $sg.Region .}}">{{.}}</a><br>{{end}}</td></tr>
+{{- end}}
+</table>
+<table>
+<tr><th>Network Interface</th><th>Description</th></tr>
+{{- range .NetworkInterfaces}}
+<tr><td><a href="#{{anchor "eni" .Account .Region .ID}}">{{.ID}}</a></td><td>{{.Description}}</td></tr>
+{{- end}}
+</table>
+</details>
+{{- end}}
+
+<h2 id="network-interfaces">Network Interfaces</h2>
+{{- range .NetworkInterfaces}}
+<details class="item" id="{{anchor "eni" .Account .Region .ID}}">
+<summary>{{.ID}} <span class="meta">{{.Description}} {{.Account}} {{.Region}}</span></summary>
+<table>
+{{- if .InstanceID}}
+<tr><th>Instance</th><td>{{if .Ec2Instance}}<a href="#{{anchor "instance" .Account .Region .InstanceID}}">{{.InstanceID}}</a>{{else}}{{.InstanceID}}{{end}}</td></tr>
+{{- end}}
+{{- $ni := .}}
+<tr><th>Security Groups</th><td>{{range .GroupIds}}<a href="#{{anchor "sg" $ni.Account $ni.Region .}}">{{.}}</a><br>{{end}}</td></tr>
+</table>
+</details>
+{{- end}}
+
+<h2 id="instances">Instances</h2>
+{{- range .Instances}}
+<details class="item" id="{{anchor "instance" .Account .Region .ID}}">
+<summary>{{.ID}} <span class="meta">{{.TagName}} {{.Account}} {{.Region}}</span></summary>
+<table>
+<tr><th>Availability Zone</th><td>{{.AvailabilityZone}}</td></tr>
+<tr><th>Private IP</th><td>{{.PrivateIP}}</td></tr>
+<tr><th>Public IP</th><td>{{.PublicIP}}</td></tr>
+<tr><th>Instance Type</th><td>{{.InstanceType}}</td></tr>
+<tr><th>Key Name</th><td>{{.KeyName}}</td></tr>
+</table>
+</details>
+{{- end}}
+{{end}}
+`
+
+// convertPdf writes <filename>.pdf with the rules and interfaces of every
+// security group, then the interfaces and instances, linked to each other
+func (sg *SG) convertPdf(filename string) error {
+	d := newPdfDoc()
+	nis := make([]*NetworkInterface, 0)
+	for _, v := range sg.SecurityGroups {
+		nis = appendNIsWithoutDuplicate(nis, v.NetworkInterfaces)
+	}
+	ec2s := make([]*Instance, 0)
+	encountered := make(map[*Instance]bool)
+	for _, ni := range nis {
+		if ni.Ec2Instance != nil && !encountered[ni.Ec2Instance] {
+			encountered[ni.Ec2Instance] = true
+			ec2s = append(ec2s, ni.Ec2Instance)
+		}
+	}
+	groups := make(map[string]bool)
+	for _, v := range sg.SecurityGroups {
+		groups[anchor("sg", v.Account, v.Region, v.ID)] = true
+	}
+	// groupCell links to the group when it is in the report
+	groupCell := func(account, region, gid string) pdfCell {
+		key := anchor("sg", account, region, gid)
+		if !groups[key] {
+			return pdfCell{text: gid}
+		}
+		return pdfCell{text: gid, link: d.link(key)}
+	}
+
+	d.heading("Security Groups", 0)
+	for _, v := range sg.SecurityGroups {
+		d.heading(fmt.Sprintf("%s %s  %s", v.GroupName, v.ID, location(v.Account, v.Region)), 1)
+		d.anchor(anchor("sg", v.Account, v.Region, v.ID))
+		d.text(fmt.Sprintf("%s  %s", v.TagName, v.Description))
+		rules := make([][]pdfCell, 0)
+		for _, dir := range []struct {
+			name string
+			ips  []*IpPermission
+		}{{"Ingress", v.Ingress}, {"Egress", v.Egress}} {
+			for _, ip := range dir.ips {
+				ports := fmt.Sprintf("%d-%d", ip.FromPort, ip.ToPort)
+				for _, r := range append(ip.AllRanges(), ip.PrefixListIds...) {
+					rules = append(rules, []pdfCell{{text: dir.name}, {text: ip.Protocol}, {text: ports}, {text: r}})
+				}
+				for _, g := range ip.GroupIds {
+					rules = append(rules, []pdfCell{{text: dir.name}, {text: ip.Protocol}, {text: ports}, groupCell(v.Account, v.Region, g)})
+				}
+			}
+		}
+		d.table([]float64{25, 25, 30, 110}, []string{"Direction", "Protocol", "Port", "CIDR / Prefix List / Security Group"}, rules)
+		eniRows := make([][]pdfCell, 0)
+		for _, ni := range v.NetworkInterfaces {
+			eniRows = append(eniRows, []pdfCell{
+				{text: ni.ID, link: d.link(anchor("eni", ni.Account, ni.Region, ni.ID))},
+				{text: ni.Description},
+			})
+		}
+		d.table([]float64{50, 140}, []string{"Network Interface", "Description"}, eniRows)
+	}
+
+	d.AddPage()
+	d.heading("Network Interfaces", 0)
+	for _, ni := range nis {
+		d.heading(fmt.Sprintf("%s  %s", ni.ID, location(ni.Account, ni.Region)), 1)
+		d.anchor(anchor("eni", ni.Account, ni.Region, ni.ID))
+		rows := [][]pdfCell{{{text: "Description"}, {text: ni.Description}}}
+		if ni.InstanceID != "" {
+			instance := pdfCell{text: ni.InstanceID}
+			if ni.Ec2Instance != nil {
+				instance.link = d.link(anchor("instance", ni.Account, ni.Region, ni.InstanceID))
+			}
+			rows = append(rows, []pdfCell{{text: "Instance"}, instance})
+		}
+		for _, g := range ni.GroupIds {
+			rows = append(rows, []pdfCell{{text: "Security Group"}, groupCell(ni.Account, ni.Region, g)})
+		}
+		d.table([]float64{50, 140}, nil, rows)
+	}
+
+	d.AddPage()
+	d.heading("Instances", 0)
+	for _, ins := range ec2s {
+		d.heading(fmt.Sprintf("%s %s  %s", ins.ID, ins.TagName, location(ins.Account, ins.Region)), 1)
+		d.anchor(anchor("instance", ins.Account, ins.Region, ins.ID))
+		d.table([]float64{50, 140}, nil, [][]pdfCell{
+			{{text: "AvailabilityZone"}, {text: ins.AvailabilityZone}},
+			{{text: "Private IP"}, {text: ins.PrivateIP}},
+			{{text: "Public IP"}, {text: ins.PublicIP}},
+			{{text: "Instance Type"}, {text: ins.InstanceType}},
+			{{text: "Key Name"}, {text: ins.KeyName}},
+		})
+	}
+
+	if len(sg.Errs) > 0 {
+		d.AddPage()
+		d.heading("Errors", 0)
+		for _, err := range sg.Errs {
+			d.text(err.Error())
+		}
+	}
+	path, err := createPath(filename, "pdf")
+	if err != nil {
+		return err
+	}
+	return d.OutputFileAndClose(path)
+}
+
+func jsonRules(ips []*IpPermission) []*jsonRule {
+	rules := make([]*jsonRule, 0, len(ips))
+	for _, v := range ips {
+		rules = append(rules, &jsonRule{
+			Protocol:         v.Protocol,
+			FromPort:         v.FromPort,
+			ToPort:           v.ToPort,
+			CidrRanges:       nonNil(v.Ranges),
+			Ipv6CidrRanges:   nonNil(v.Ipv6Ranges),
+			PrefixListIDs:    nonNil(v.PrefixListIds),
+			SecurityGroupIDs: nonNil(v.GroupIds),
+		})
+	}
+	return rules
+}
+
 func (sg *SG) stackError(err error) *SG {
 	sg.Errs = append(sg.Errs, err)
 	return sg
 }
 
 func (sg *SG) flattenErrs() error {
-	if len(sg.Errs) == 0 {
-		return nil
-	}
-	var errStr string
-	for _, e := range sg.Errs {
-		errStr = errStr + e.Error() + "\n"
-	}
-	return fmt.Errorf(errStr)
+	return flattenErrors(sg.Errs)
 }
 
 func parseDescribeSecurityGroupsOutput(output *ec2.DescribeSecurityGroupsOutput) []*SecurityGroup {
@@ -117,6 +541,7 @@ func parseDescribeSecurityGroupsOutput(output *ec2.DescribeSecurityGroupsOutput)
 	for _, v := range output.SecurityGroups {
 		sg := &SecurityGroup{
 			ID:                *v.GroupId,
+			VpcID:             aws.StringValue(v.VpcId),
 			GroupName:         *v.GroupName,
 			TagName:           extractTagName(v.Tags),
 			Description:       *v.Description,
@@ -140,6 +565,20 @@ func parseDescribeSecurityGroupsOutput(output *ec2.DescribeSecurityGroupsOutput)
 				}
 				ip.Ranges = ranges
 			}
+			if i.Ipv6Ranges != nil {
+				ranges := make([]string, 0)
+				for _, r := range i.Ipv6Ranges {
+					ranges = append(ranges, *r.CidrIpv6)
+				}
+				ip.Ipv6Ranges = ranges
+			}
+			if i.PrefixListIds != nil {
+				pls := make([]string, 0)
+				for _, r := range i.PrefixListIds {
+					pls = append(pls, aws.StringValue(r.PrefixListId))
+				}
+				ip.PrefixListIds = pls
+			}
 			if i.UserIdGroupPairs != nil {
 				gids := make([]string, 0)
 				for _, r := range i.UserIdGroupPairs {
@@ -168,6 +607,20 @@ func parseDescribeSecurityGroupsOutput(output *ec2.DescribeSecurityGroupsOutput)
 				}
 				ip.Ranges = ranges
 			}
+			if i.Ipv6Ranges != nil {
+				ranges := make([]string, 0)
+				for _, r := range i.Ipv6Ranges {
+					ranges = append(ranges, *r.CidrIpv6)
+				}
+				ip.Ipv6Ranges = ranges
+			}
+			if i.PrefixListIds != nil {
+				pls := make([]string, 0)
+				for _, r := range i.PrefixListIds {
+					pls = append(pls, aws.StringValue(r.PrefixListId))
+				}
+				ip.PrefixListIds = pls
+			}
 			if i.UserIdGroupPairs != nil {
 				gids := make([]string, 0)
 				for _, r := range i.UserIdGroupPairs {
@@ -183,14 +636,39 @@ func parseDescribeSecurityGroupsOutput(output *ec2.DescribeSecurityGroupsOutput)
 	return sgs
 }
 
+// parseDescribeSecurityGroupRulesOutput rules by group ID
+func parseDescribeSecurityGroupRulesOutput(output *ec2.DescribeSecurityGroupRulesOutput) map[string][]*SecurityGroupRule {
+	rules := make(map[string][]*SecurityGroupRule)
+	for _, v := range output.SecurityGroupRules {
+		r := &SecurityGroupRule{
+			ID:           aws.StringValue(v.SecurityGroupRuleId),
+			Egress:       aws.BoolValue(v.IsEgress),
+			Protocol:     aws.StringValue(v.IpProtocol),
+			FromPort:     aws.Int64Value(v.FromPort),
+			ToPort:       aws.Int64Value(v.ToPort),
+			CidrIpv4:     aws.StringValue(v.CidrIpv4),
+			CidrIpv6:     aws.StringValue(v.CidrIpv6),
+			PrefixListID: aws.StringValue(v.PrefixListId),
+			Description:  aws.StringValue(v.Description),
+		}
+		if v.ReferencedGroupInfo != nil {
+			r.ReferencedGroupID = aws.StringValue(v.ReferencedGroupInfo.GroupId)
+		}
+		gid := aws.StringValue(v.GroupId)
+		rules[gid] = append(rules[gid], r)
+	}
+	return rules
+}
+
 func parseDescribeNetworkInterfacesOutput(output *ec2.DescribeNetworkInterfacesOutput) []*NetworkInterface {
 	nis := make([]*NetworkInterface, 0)
 	for _, v := range output.NetworkInterfaces {
 		ni := &NetworkInterface{
 			ID:          *v.NetworkInterfaceId,
-			Description: *v.Description,
+			Description: aws.StringValue(v.Description),
+			SubnetID:    aws.StringValue(v.SubnetId),
 		}
-		if v.Attachment.InstanceId != nil {
+		if v.Attachment != nil && v.Attachment.InstanceId != nil {
 			ni.InstanceID = *v.Attachment.InstanceId
 		}
 		gids := make([]string, 0)
@@ -203,51 +681,117 @@ func parseDescribeNetworkInterfacesOutput(output *ec2.DescribeNetworkInterfacesO
 	return nis
 }
 
-func parseDescribeInstancesOutput(output *ec2.DescribeInstancesOutput) *Instance {
-	res := output.Reservations[0].Instances[0]
-	ins := &Instance{
-		ID:               *res.InstanceId,
-		TagName:          extractTagName(res.Tags),
-		AvailabilityZone: *res.Placement.AvailabilityZone,
-		PrivateIP:        *res.PrivateIpAddress,
-		InstanceType:     *res.InstanceType,
+// parseDescribeInstancesOutput instances keyed by instance ID
+func parseDescribeInstancesOutput(output *ec2.DescribeInstancesOutput) map[string]*Instance {
+	m := make(map[string]*Instance)
+	for _, r := range output.Reservations {
+		for _, v := range r.Instances {
+			ins := &Instance{
+				ID:           aws.StringValue(v.InstanceId),
+				TagName:      extractTagName(v.Tags),
+				PrivateIP:    aws.StringValue(v.PrivateIpAddress),
+				PublicIP:     aws.StringValue(v.PublicIpAddress),
+				InstanceType: aws.StringValue(v.InstanceType),
+				KeyName:      aws.StringValue(v.KeyName),
+			}
+			if v.Placement != nil {
+				ins.AvailabilityZone = aws.StringValue(v.Placement.AvailabilityZone)
+			}
+			m[ins.ID] = ins
+		}
 	}
-	if res.PublicIpAddress != nil {
-		ins.PublicIP = *res.PublicIpAddress
+	return m
+}
+
+// sgSheets the sheets of one account and region
+type sgSheets struct {
+	instance         *xlsx.Sheet
+	networkInterface *xlsx.Sheet
+	securityGroup    *xlsx.Sheet
+}
+
+// addSGSheets adds the sheets of s to file
+func addSGSheets(file *xlsx.File, namer *sheetNamer, s scope) (*sgSheets, error) {
+	instance, err := addSheet(file, namer.name(s, "instance"))
+	if err != nil {
+		return nil, err
+	}
+	networkInterface, err := addSheet(file, namer.name(s, "networkinterface"))
+	if err != nil {
+		return nil, err
 	}
-	if res.KeyName != nil {
-		ins.KeyName = *res.KeyName
+	securityGroup, err := addSheet(file, namer.name(s, "security-group"))
+	if err != nil {
+		return nil, err
 	}
-	return ins
+	return &sgSheets{
+		instance:         instance,
+		networkInterface: networkInterface,
+		securityGroup:    securityGroup,
+	}, nil
 }
 
-func (sg *SG) convertXlsx(filename string) {
+func (sg *SG) convertXlsx(filename string) error {
 	file := xlsx.NewFile()
-	nis := make([]*NetworkInterface, 0)
-	for _, v := range sg.SecurityGroups {
-		nis = appendNIsWithoutDuplicate(nis, v.NetworkInterfaces)
+	sg.addXlsxSheets(file, nil)
+	addErrorsSheet(file, sg.Errs)
+	path, err := createPath(filename, "xlsx")
+	if err != nil {
+		return err
 	}
-	ec2s := make([]*Instance, 0)
-	for _, v := range nis {
-		if v.Ec2Instance != nil {
-			ec2s = append(ec2s, v.Ec2Instance)
+	return file.Save(path)
+}
+
+// addXlsxSheets adds the instance, network interface and security group
+// sheets of every account and region to file and returns their names.
+// Interfaces link to their subnet when subnets has it.
+func (sg *SG) addXlsxSheets(file *xlsx.File, subnets map[string]xlsxLocation) []string {
+	names := make([]string, 0)
+	scopes := sg.scopes()
+	namer := newSheetNamer(scopes)
+	for _, s := range scopes {
+		sheets, err := addSGSheets(file, namer, s)
+		if err != nil {
+			util.PrintlnRed(err.Error())
+			continue
 		}
+		names = append(names, sheets.instance.Name, sheets.networkInterface.Name, sheets.securityGroup.Name)
+		sgs := make([]*SecurityGroup, 0)
+		nis := make([]*NetworkInterface, 0)
+		for _, v := range sg.SecurityGroups {
+			if v.Account == s.Account && v.Region == s.Region {
+				sgs = append(sgs, v)
+				nis = appendNIsWithoutDuplicate(nis, v.NetworkInterfaces)
+			}
+		}
+		ec2s := make([]*Instance, 0)
+		encountered := make(map[string]bool)
+		for _, v := range nis {
+			if v.Ec2Instance != nil && !encountered[v.Ec2Instance.ID] {
+				encountered[v.Ec2Instance.ID] = true
+				ec2s = append(ec2s, v.Ec2Instance)
+			}
+		}
+		instanceLocation := make(map[string][2]int)
+		sg.convertInstanceToXlsx(sheets, ec2s, &instanceLocation)
+		networkInterfaceLocation := make(map[string][2]int)
+		sg.convertNetworkInterfaceToXlsx(sheets, nis, instanceLocation, subnets, &networkInterfaceLocation)
+		sg.convertSecurityGroupToXlsx(sheets, sgs, networkInterfaceLocation)
 	}
-	instanceLocation := make(map[string][2]int)
-	sg.convertInstanceToXlsx(file, ec2s, &instanceLocation)
-	networkInterfaceLocation := make(map[string][2]int)
-	sg.convertNetworkInterfaceToXlsx(file, nis, instanceLocation, &networkInterfaceLocation)
-	sg.convertSecurityGroupToXlsx(file, networkInterfaceLocation)
-	if err := file.Save(fmt.Sprintf("./%s.xlsx", filename)); err != nil {
-		sg.stackError(err)
-	}
+	return names
 }
 
-func (sg *SG) convertInstanceToXlsx(file *xlsx.File, ec2s []*Instance, locMap *map[string][2]int) {
-	sheet, err := file.AddSheet("instance")
-	if err != nil {
-		util.PrintlnRed(err.Error())
-	}
+// addRegionColumn writes a region column at col beside the block whose title
+// is at row: the header on the title row and region under it
+func addRegionColumn(sheet *xlsx.Sheet, row, col int, region string) {
+	sheet.Cell(row, col).Value = "Region"
+	sheet.Cell(row, col).SetStyle(borderWithAlign("lrtb", true))
+	sheet.Cell(row+1, col).Value = region
+	sheet.Cell(row+1, col).SetStyle(borderWithAlign("lrtb", false))
+}
+
+func (sg *SG) convertInstanceToXlsx(sheets *sgSheets, ec2s []*Instance, locMap *map[string][2]int) {
+	sheet := sheets.instance
 	m := make(map[string][2]int)
 	currentRow := 0
 	for _, v := range ec2s {
@@ -255,7 +799,15 @@ func (sg *SG) convertInstanceToXlsx(file *xlsx.File, ec2s []*Instance, locMap *m
 		sheet.Cell(currentRow, 0).Merge(1, 0)
 		sheet.Cell(currentRow, 0).Value = fmt.Sprintf("%s, tag: %s", v.ID, v.TagName)
 		sheet.Cell(currentRow, 0).SetStyle(borderWithAlign("lrtb", true))
+		addRegionColumn(sheet, currentRow, 2, v.Region)
 		currentRow++
+		if v.Account != "" {
+			sheet.Cell(currentRow, 0).Value = "Account"
+			sheet.Cell(currentRow, 0).SetStyle(borderWithAlign("lr", false))
+			sheet.Cell(currentRow, 1).Value = v.Account
+			sheet.Cell(currentRow, 1).SetStyle(borderWithAlign("lr", false))
+			currentRow++
+		}
 		sheet.Cell(currentRow, 0).Value = "AvailabilityZone"
 		sheet.Cell(currentRow, 0).SetStyle(borderWithAlign("lr", false))
 		sheet.Cell(currentRow, 1).Value = v.AvailabilityZone
@@ -285,11 +837,8 @@ func (sg *SG) convertInstanceToXlsx(file *xlsx.File, ec2s []*Instance, locMap *m
 	*locMap = m
 }
 
-func (sg *SG) convertNetworkInterfaceToXlsx(file *xlsx.File, nis []*NetworkInterface, refIns map[string][2]int, locMap *map[string][2]int) {
-	sheet, err := file.AddSheet("networkinterface")
-	if err != nil {
-		util.PrintlnRed(err.Error())
-	}
+func (sg *SG) convertNetworkInterfaceToXlsx(sheets *sgSheets, nis []*NetworkInterface, refIns map[string][2]int, refSubnets map[string]xlsxLocation, locMap *map[string][2]int) {
+	sheet := sheets.networkInterface
 	m := make(map[string][2]int)
 	currentRow := 0
 	for _, v := range nis {
@@ -297,19 +846,40 @@ func (sg *SG) convertNetworkInterfaceToXlsx(file *xlsx.File, nis []*NetworkInter
 		sheet.Cell(currentRow, 0).Merge(1, 0)
 		sheet.Cell(currentRow, 0).Value = v.ID
 		sheet.Cell(currentRow, 0).SetStyle(borderWithAlign("lrtb", true))
+		addRegionColumn(sheet, currentRow, 2, v.Region)
 		currentRow++
 		sheet.Cell(currentRow, 0).Value = "Description"
 		sheet.Cell(currentRow, 0).SetStyle(borderWithAlign("lr", false))
 		sheet.Cell(currentRow, 1).Value = v.Description
 		sheet.Cell(currentRow, 1).SetStyle(borderWithAlign("lr", false))
 		currentRow++
+		if v.Account != "" {
+			sheet.Cell(currentRow, 0).Value = "Account"
+			sheet.Cell(currentRow, 0).SetStyle(borderWithAlign("lr", false))
+			sheet.Cell(currentRow, 1).Value = v.Account
+			sheet.Cell(currentRow, 1).SetStyle(borderWithAlign("lr", false))
+			currentRow++
+		}
+		if v.SubnetID != "" {
+			sheet.Cell(currentRow, 0).Value = "Subnet"
+			sheet.Cell(currentRow, 0).SetStyle(borderWithAlign("lr", false))
+			if loc, ok := refSubnets[v.SubnetID]; ok {
+				sheet.Cell(currentRow, 1).SetFormula(loc.hyperlink(v.SubnetID))
+			} else {
+				sheet.Cell(currentRow, 1).Value = v.SubnetID
+			}
+			sheet.Cell(currentRow, 1).SetStyle(borderWithAlign("lr", false))
+			currentRow++
+		}
 		if v.InstanceID != "" {
 			sheet.Cell(currentRow, 0).Value = "Instance"
 			sheet.Cell(currentRow, 0).SetStyle(borderWithAlign("lr", false))
 			if loc, ok := refIns[v.InstanceID]; ok {
-				sheet.Cell(currentRow, 1).SetFormula(hyperlink("instance", loc[0], loc[1], v.InstanceID))
-				sheet.Cell(currentRow, 1).SetStyle(borderWithAlign("lr", false))
+				sheet.Cell(currentRow, 1).SetFormula(hyperlink(sheets.instance.Name, loc[0], loc[1], v.InstanceID))
+			} else {
+				sheet.Cell(currentRow, 1).Value = v.InstanceID
 			}
+			sheet.Cell(currentRow, 1).SetStyle(borderWithAlign("lr", false))
 			currentRow++
 		}
 		sheet.Cell(currentRow, 0).SetStyle(borderWithAlign("t", false))
@@ -319,16 +889,14 @@ func (sg *SG) convertNetworkInterfaceToXlsx(file *xlsx.File, nis []*NetworkInter
 	*locMap = m
 }
 
-func (sg *SG) convertSecurityGroupToXlsx(file *xlsx.File, refNi map[string][2]int) {
-	sheet, err := file.AddSheet("security-group")
-	if err != nil {
-		util.PrintlnRed(err.Error())
-	}
+func (sg *SG) convertSecurityGroupToXlsx(sheets *sgSheets, sgs []*SecurityGroup, refNi map[string][2]int) {
+	sheet := sheets.securityGroup
 	currentRow := 0
-	for _, v := range sg.SecurityGroups {
+	for _, v := range sgs {
 		sheet.Cell(currentRow, 0).Merge(5, 0)
 		sheet.Cell(currentRow, 0).Value = fmt.Sprintf("%s %s, tag: %s", v.ID, v.GroupName, v.TagName)
 		sheet.Cell(currentRow, 0).SetStyle(borderWithAlign("lrtb", true))
+		addRegionColumn(sheet, currentRow, 6, v.Region)
 		currentRow++
 		sheet.Cell(currentRow, 0).Value = "Description"
 		sheet.Cell(currentRow, 0).SetStyle(borderWithAlign("lrtb", false))
@@ -336,6 +904,14 @@ func (sg *SG) convertSecurityGroupToXlsx(file *xlsx.File, refNi map[string][2]in
 		sheet.Cell(currentRow, 1).Value = v.Description
 		sheet.Cell(currentRow, 1).SetStyle(borderWithAlign("lrtb", false))
 		currentRow++
+		if v.Account != "" {
+			sheet.Cell(currentRow, 0).Value = "Account"
+			sheet.Cell(currentRow, 0).SetStyle(borderWithAlign("lrtb", false))
+			sheet.Cell(currentRow, 1).Merge(4, 0)
+			sheet.Cell(currentRow, 1).Value = v.Account
+			sheet.Cell(currentRow, 1).SetStyle(borderWithAlign("lrtb", false))
+			currentRow++
+		}
 		sheet.Cell(currentRow, 0).Merge(2, 0)
 		sheet.Cell(currentRow, 0).Value = "Ingress Rules"
 		sheet.Cell(currentRow, 0).SetStyle(borderWithAlign("lrtb", true))
@@ -362,11 +938,7 @@ func (sg *SG) convertSecurityGroupToXlsx(file *xlsx.File, refNi map[string][2]in
 			sheet.Cell(currentRow+iRow, 0).SetStyle(borderWithAlign("lr", false))
 			sheet.Cell(currentRow+iRow, 1).Value = fmt.Sprintf("%d - %d", i.FromPort, i.ToPort)
 			sheet.Cell(currentRow+iRow, 1).SetStyle(borderWithAlign("lr", false))
-			if len(i.GroupIds) > 0 {
-				sheet.Cell(currentRow+iRow, 2).Value = strings.Join(i.GroupIds, ", ")
-			} else {
-				sheet.Cell(currentRow+iRow, 2).Value = strings.Join(i.Ranges, ", ")
-			}
+			sheet.Cell(currentRow+iRow, 2).Value = strings.Join(i.Sources(), ", ")
 			sheet.Cell(currentRow+iRow, 2).SetStyle(borderWithAlign("lr", false))
 			iRow++
 		}
@@ -376,11 +948,7 @@ func (sg *SG) convertSecurityGroupToXlsx(file *xlsx.File, refNi map[string][2]in
 			sheet.Cell(currentRow+eRow, 3).SetStyle(borderWithAlign("lr", false))
 			sheet.Cell(currentRow+eRow, 4).Value = fmt.Sprintf("%d - %d", e.FromPort, e.ToPort)
 			sheet.Cell(currentRow+eRow, 4).SetStyle(borderWithAlign("lr", false))
-			if len(e.GroupIds) > 0 {
-				sheet.Cell(currentRow+eRow, 5).Value = strings.Join(e.GroupIds, ", ")
-			} else {
-				sheet.Cell(currentRow+eRow, 5).Value = strings.Join(e.Ranges, ", ")
-			}
+			sheet.Cell(currentRow+eRow, 5).Value = strings.Join(e.Sources(), ", ")
 			sheet.Cell(currentRow+eRow, 5).SetStyle(borderWithAlign("lr", false))
 			eRow++
 		}
@@ -393,7 +961,7 @@ func (sg *SG) convertSecurityGroupToXlsx(file *xlsx.File, refNi map[string][2]in
 		for i, ni := range v.NetworkInterfaces {
 			row, col := i/7, i%7
 			if loc, ok := refNi[ni.ID]; ok {
-				sheet.Cell(currentRow+row, col).SetFormula(hyperlink("networkinterface", loc[0], loc[1], ni.ID))
+				sheet.Cell(currentRow+row, col).SetFormula(hyperlink(sheets.networkInterface.Name, loc[0], loc[1], ni.ID))
 			}
 			if col == 0 {
 				sheet.Cell(currentRow+row, col).SetStyle(borderWithAlign("l", false))
@@ -2,25 +2,59 @@ package cmd
 
 type SecurityGroup struct {
 	ID                string
+	Account           string
+	Region            string
+	VpcID             string
 	GroupName         string
 	TagName           string
 	Description       string
 	Ingress           []*IpPermission
 	Egress            []*IpPermission
 	NetworkInterfaces []*NetworkInterface
+	Rules             []*SecurityGroupRule
 }
 
 type IpPermission struct {
-	Protocol string
-	FromPort int64
-	ToPort   int64
-	Ranges   []string
-	GroupIds []string
+	Protocol      string
+	FromPort      int64
+	ToPort        int64
+	Ranges        []string
+	Ipv6Ranges    []string
+	PrefixListIds []string
+	GroupIds      []string
+}
+
+// AllRanges the IPv4 and IPv6 ranges of ip
+func (ip *IpPermission) AllRanges() []string {
+	return append(append([]string{}, ip.Ranges...), ip.Ipv6Ranges...)
+}
+
+// Sources the ranges, prefix lists and security groups of ip
+func (ip *IpPermission) Sources() []string {
+	return append(append(ip.AllRanges(), ip.PrefixListIds...), ip.GroupIds...)
+}
+
+// SecurityGroupRule one rule as DescribeSecurityGroupRules returns it: a
+// single CIDR, prefix list or group, with the ID it is imported by
+type SecurityGroupRule struct {
+	ID                string
+	Egress            bool
+	Protocol          string
+	FromPort          int64
+	ToPort            int64
+	CidrIpv4          string
+	CidrIpv6          string
+	PrefixListID      string
+	ReferencedGroupID string
+	Description       string
 }
 
 type NetworkInterface struct {
 	ID          string
+	Account     string
+	Region      string
 	Description string
+	SubnetID    string
 	InstanceID  string
 	Ec2Instance *Instance
 	GroupIds    []string
@@ -28,6 +62,8 @@ type NetworkInterface struct {
 
 type Instance struct {
 	ID               string
+	Account          string
+	Region           string
 	AvailabilityZone string
 	PrivateIP        string
 	PublicIP         string
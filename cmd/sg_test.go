@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/tealeg/xlsx"
+)
+
+func collectFixtureSG(t *testing.T) *SG {
+	t.Helper()
+	sg := &SG{Errs: make([]error, 0)}
+	sg.collect(fixtureTarget(t))
+	if err := sg.flattenErrs(); err != nil {
+		t.Fatal(err)
+	}
+	return sg
+}
+
+func TestSGCollect(t *testing.T) {
+	sg := collectFixtureSG(t)
+	if len(sg.SecurityGroups) != 2 {
+		t.Fatalf("security groups = %d, want 2", len(sg.SecurityGroups))
+	}
+	web := sg.SecurityGroups[0]
+	if web.ID != "sg-0web" || len(web.NetworkInterfaces) != 1 {
+		t.Fatalf("first group = %s with %d interfaces", web.ID, len(web.NetworkInterfaces))
+	}
+	ni := web.NetworkInterfaces[0]
+	if ni.ID != "eni-0web" || ni.Ec2Instance == nil || ni.Ec2Instance.ID != "i-0web" {
+		t.Errorf("interface = %s, instance %v", ni.ID, ni.Ec2Instance)
+	}
+	var https *IpPermission
+	for _, ip := range web.Ingress {
+		if ip.FromPort == 443 {
+			https = ip
+		}
+	}
+	if https == nil || strings.Join(https.Ranges, ",") != "0.0.0.0/0" || strings.Join(https.Ipv6Ranges, ",") != "::/0" {
+		t.Errorf("sg-0web 443 ingress = %v", https)
+	}
+}
+
+func TestSGCollectRules(t *testing.T) {
+	sg := &SG{rules: true, Errs: make([]error, 0)}
+	sg.collect(fixtureTarget(t))
+	if err := sg.flattenErrs(); err != nil {
+		t.Fatal(err)
+	}
+	ipv6 := make([]string, 0)
+	for _, r := range sg.SecurityGroups[0].Rules {
+		if r.CidrIpv6 != "" {
+			ipv6 = append(ipv6, fmt.Sprintf("%s %s %d-%d %s", r.ID, r.Protocol, r.FromPort, r.ToPort, r.CidrIpv6))
+		}
+	}
+	if got := strings.Join(ipv6, ","); got != "sgr-0web443v6 tcp 443-443 ::/0" {
+		t.Errorf("sg-0web IPv6 rules = %q", got)
+	}
+}
+
+func TestSGConvertCSV(t *testing.T) {
+	sg := collectFixtureSG(t)
+	dir := t.TempDir()
+	if err := sg.convertCSV(dir); err != nil {
+		t.Fatal(err)
+	}
+	rules := csvRows(t, dir, "sg_rules")
+	if findRow(rules, map[string]string{"group_id": "sg-0db", "direction": "ingress", "target_type": "security_group", "target": "sg-0web", "from_port": "5432"}) == nil {
+		t.Errorf("sg-0db ingress from sg-0web missing: %v", rules)
+	}
+	if row := findRow(rules, map[string]string{"group_id": "sg-0web", "direction": "ingress", "target": "::/0"}); row == nil || row["target_type"] != "cidr" || row["protocol"] != "tcp" || row["from_port"] != "443" || row["to_port"] != "443" {
+		t.Errorf("sg-0web ingress from ::/0 = %v", row)
+	}
+	if row := findRow(rules, map[string]string{"group_id": "sg-0web", "from_port": "22"}); row == nil || row["target_type"] != "prefix_list" || row["target"] != "pl-0office" {
+		t.Errorf("sg-0web ingress from pl-0office = %v", row)
+	}
+	if row := findRow(csvRows(t, dir, "enis"), map[string]string{"eni_id": "eni-0web"}); row == nil || row["instance_id"] != "i-0web" {
+		t.Errorf("eni-0web = %v", row)
+	}
+}
+
+func TestSGXlsxSheets(t *testing.T) {
+	sg := collectFixtureSG(t)
+	file := xlsx.NewFile()
+	sheets := sg.addXlsxSheets(file, nil)
+	if strings.Join(sheets, ",") != "instance,networkinterface,security-group" {
+		t.Fatalf("sheets = %v", sheets)
+	}
+	groups := file.Sheet["security-group"]
+	if v := groups.Cell(0, 0).Value; v != "sg-0web web, tag: sample-web" {
+		t.Errorf("first group = %q", v)
+	}
+	if h, v := groups.Cell(0, 6).Value, groups.Cell(1, 6).Value; h != "Region" || v != defaultRegion {
+		t.Errorf("region column = %q %q", h, v)
+	}
+	if f := file.Sheet["networkinterface"].Cell(3, 1).Formula(); !strings.Contains(f, "'instance'!A1") {
+		t.Errorf("eni-0web instance link = %q", f)
+	}
+}
+
+func TestParseDescribeNetworkInterfacesOutputWithoutDescription(t *testing.T) {
+	nis := parseDescribeNetworkInterfacesOutput(&ec2.DescribeNetworkInterfacesOutput{
+		NetworkInterfaces: []*ec2.NetworkInterface{{NetworkInterfaceId: aws.String("eni-0nodesc")}},
+	})
+	if len(nis) != 1 || nis[0].ID != "eni-0nodesc" || nis[0].Description != "" {
+		t.Errorf("nis = %v", nis)
+	}
+}
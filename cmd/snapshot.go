@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/atsushi-ishibashi/aws-state-report/svc"
+	"github.com/atsushi-ishibashi/aws-state-report/util"
+	"github.com/urfave/cli"
+)
+
+func snapshotTargets(path string, regional bool) ([]*target, error) {
+	snapshot, err := svc.ReadSnapshot(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshot.Collections) == 0 {
+		return nil, fmt.Errorf("%s: snapshot has no collections", path)
+	}
+	util.PrintlnGreen(fmt.Sprintf("Snapshot: %s, Created: %s", path, snapshot.CreatedAt.Format("2006-01-02 15:04:05 MST")))
+	targets := make([]*target, 0, len(snapshot.Collections))
+	for _, v := range snapshot.Collections {
+		if !regional && len(targets) > 0 && targets[len(targets)-1].Account == v.Account {
+			continue
+		}
+		targets = append(targets, &target{Account: v.Account, Region: v.Region, manager: svc.NewResponsesManager(v.Responses)})
+	}
+	return targets, nil
+}
+
+// saveSnapshot writes what the targets recorded to --save-snapshot, if given
+func saveSnapshot(c *cli.Context, targets []*target) error {
+	path := c.GlobalString("save-snapshot")
+	if path == "" {
+		return nil
+	}
+	snapshot := svc.NewSnapshot()
+	for _, t := range targets {
+		rec, ok := t.manager.(*svc.RecordingManager)
+		if !ok {
+			continue
+		}
+		snapshot.Collections = append(snapshot.Collections, &svc.Collection{
+			Account:   t.Account,
+			Region:    t.Region,
+			Responses: rec.Responses(),
+		})
+	}
+	if err := snapshot.Write(path); err != nil {
+		return err
+	}
+	util.PrintlnGreen(fmt.Sprintf("Snapshot saved: %s", path))
+	return nil
+}
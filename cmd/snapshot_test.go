@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/atsushi-ishibashi/aws-state-report/svc"
+	"github.com/urfave/cli"
+)
+
+// jsonReport the json report convert writes to dir, without generated_at
+func jsonReport(t *testing.T, dir string, convert func(string) error) map[string]interface{} {
+	t.Helper()
+	filename := filepath.Join(dir, "report")
+	if err := convert(filename); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filename + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := make(map[string]interface{})
+	if err := json.Unmarshal(b, &report); err != nil {
+		t.Fatal(err)
+	}
+	delete(report, "generated_at")
+	return report
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	recorded := fixtureTarget(t)
+	recorded.manager = svc.NewRecordingManager(recorded.manager)
+	nt := &Network{Errs: make([]error, 0)}
+	nt.collect(recorded)
+	sg := &SG{Errs: make([]error, 0)}
+	sg.collect(recorded)
+	iam := &IAM{Errs: make([]error, 0)}
+	iam.collect(recorded)
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	global := flag.NewFlagSet("global", flag.ContinueOnError)
+	global.String("save-snapshot", path, "")
+	c := cli.NewContext(nil, flag.NewFlagSet("all", flag.ContinueOnError), cli.NewContext(nil, global, nil))
+	if err := saveSnapshot(c, []*target{recorded}); err != nil {
+		t.Fatal(err)
+	}
+
+	targets, err := snapshotTargets(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 || targets[0].Region != defaultRegion {
+		t.Fatalf("targets = %v", targets)
+	}
+	replayed := targets[0]
+	replayed.workers = 2
+	rebuiltNt := &Network{Errs: make([]error, 0)}
+	rebuiltNt.collect(replayed)
+	rebuiltSG := &SG{Errs: make([]error, 0)}
+	rebuiltSG.collect(replayed)
+	rebuiltIAM := &IAM{Errs: make([]error, 0)}
+	rebuiltIAM.collect(replayed)
+
+	for _, tc := range []struct {
+		report    string
+		collected func(string) error
+		rebuilt   func(string) error
+	}{
+		{"network", nt.convertJSON, rebuiltNt.convertJSON},
+		{"sg", sg.convertJSON, rebuiltSG.convertJSON},
+		{"iam", iam.convertJSON, rebuiltIAM.convertJSON},
+	} {
+		want := jsonReport(t, t.TempDir(), tc.collected)
+		got := jsonReport(t, t.TempDir(), tc.rebuilt)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s rebuilt from the snapshot differs:\n got %v\nwant %v", tc.report, got, want)
+		}
+	}
+	if len(rebuiltIAM.Policies) != 1 || rebuiltIAM.Policies[0].Detail == "" {
+		t.Errorf("policies rebuilt without their document: %v", rebuiltIAM.Policies)
+	}
+}
+
+func TestSnapshotTargetsGlobal(t *testing.T) {
+	snapshot := svc.NewSnapshot()
+	for _, region := range []string{"ap-northeast-1", "us-east-1"} {
+		snapshot.Collections = append(snapshot.Collections, &svc.Collection{Account: "prod", Region: region, Responses: &svc.Responses{}})
+	}
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := snapshot.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	for regional, want := range map[bool]int{true: 2, false: 1} {
+		targets, err := snapshotTargets(path, regional)
+		if err != nil || len(targets) != want {
+			t.Errorf("regional %v: %d targets, err %v, want %d", regional, len(targets), err, want)
+		}
+	}
+}
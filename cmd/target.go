@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atsushi-ishibashi/aws-state-report/svc"
+	"github.com/atsushi-ishibashi/aws-state-report/util"
+	"github.com/urfave/cli"
+)
+
+// target one region of one account to collect from
+type target struct {
+	Account string
+	Region  string
+	manager svc.Manager
+	// workers calls made at once while collecting
+	workers int
+}
+
+// newTargets targets to collect from, read from the snapshot or fixtures
+// directory when one is given, otherwise one per account and region in AWS.
+// Commands for global services pass regional false and get a single region
+// per account.
+func newTargets(c *cli.Context, regional bool) ([]*target, error) {
+	var targets []*target
+	if path := c.GlobalString("from-snapshot"); path != "" {
+		ts, err := snapshotTargets(path, regional)
+		if err != nil {
+			return nil, err
+		}
+		targets = ts
+	} else if dir := c.GlobalString("fixtures"); dir != "" {
+		util.PrintlnGreen(fmt.Sprintf("Fixtures: %s", dir))
+		mng, err := svc.NewFixtureManager(dir)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, &target{Region: flagRegion(c), manager: mng})
+	} else {
+		accounts, err := targetAccounts(c)
+		if err != nil {
+			return nil, err
+		}
+		if name := c.GlobalString("awsconf"); name != "" {
+			util.PrintlnGreen(fmt.Sprintf("AWS Profile Name: %s", name))
+		}
+		for _, a := range accounts {
+			if a.RoleARN != "" {
+				util.PrintlnGreen(fmt.Sprintf("Account: %s, Role: %s", a.Name, a.RoleARN))
+			}
+			sess, err := svc.NewSession(&svc.Config{
+				Profile:     c.GlobalString("awsconf"),
+				RoleARN:     a.RoleARN,
+				ExternalID:  a.ExternalID,
+				RateLimit:   c.GlobalFloat64("rate-limit"),
+				MaxRetries:  c.GlobalInt("max-retries"),
+				Endpoint:    c.GlobalString("endpoint-url"),
+				EC2Endpoint: c.GlobalString("ec2-endpoint-url"),
+				IAMEndpoint: c.GlobalString("iam-endpoint-url"),
+			})
+			if err != nil {
+				return nil, err
+			}
+			regions, err := targetRegions(c, sess, regional)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", a.Name, err)
+			}
+			for _, region := range regions {
+				mng, err := svc.NewManager(sess, region)
+				if err != nil {
+					return nil, err
+				}
+				targets = append(targets, &target{Account: a.Name, Region: region, manager: mng})
+			}
+		}
+	}
+	for _, t := range targets {
+		t.workers = c.GlobalInt("concurrency")
+	}
+	if c.GlobalString("save-snapshot") != "" {
+		for _, t := range targets {
+			t.manager = svc.NewRecordingManager(t.manager)
+		}
+	}
+	return targets, nil
+}
+
+// defaultRegion region used when neither --awsregion nor the profile has one
+const defaultRegion = "ap-northeast-1"
+
+// flagRegion --awsregion, or defaultRegion
+func flagRegion(c *cli.Context) string {
+	if region := c.GlobalString("awsregion"); region != "" {
+		return region
+	}
+	return defaultRegion
+}
+
+// sessionRegion --awsregion, or the region of the profile, or defaultRegion
+func sessionRegion(c *cli.Context, sess *svc.Session) string {
+	if region := c.GlobalString("awsregion"); region != "" {
+		return region
+	}
+	if region := sess.Region(); region != "" {
+		return region
+	}
+	return defaultRegion
+}
+
+// targetRegions regions given by --regions, either "all" or comma separated
+func targetRegions(c *cli.Context, sess *svc.Session, regional bool) ([]string, error) {
+	region := sessionRegion(c, sess)
+	regions := c.GlobalString("regions")
+	if !regional || regions == "" {
+		return []string{region}, nil
+	}
+	if regions == "all" {
+		return svc.FetchRegions(sess, region)
+	}
+	result := make([]string, 0)
+	for _, v := range strings.Split(regions, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no region in --regions %q", regions)
+	}
+	return result, nil
+}
+
+// label account and region of t, for messages
+func (t *target) label() string {
+	if t.Account == "" {
+		return t.Region
+	}
+	return fmt.Sprintf("%s/%s", t.Account, t.Region)
+}
+
+// location account and region for headings, whichever of them is set
+func location(account, region string) string {
+	parts := make([]string, 0)
+	for _, v := range []string{account, region} {
+		if v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// targetError err with where it happened
+func targetError(t *target, err error) error {
+	if fe, ok := err.(*fetchError); ok {
+		fe.Account, fe.Region = t.Account, t.Region
+		return fe
+	}
+	return fmt.Errorf("%s: %s", t.label(), err)
+}
+
+// accountError err with the account it happened in, for global services
+func accountError(t *target, err error) error {
+	if fe, ok := err.(*fetchError); ok {
+		fe.Account = t.Account
+		return fe
+	}
+	if t.Account == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %s", t.Account, err)
+}
+
+// printPageStats pages and items fetched per API, to stderr so they never
+// mix with a report written to stdout
+func printPageStats(targets []*target) {
+	for _, t := range targets {
+		if len(targets) > 1 {
+			fmt.Fprintln(os.Stderr, util.SprintYellow(t.label()))
+		}
+		for _, v := range t.manager.PageStats() {
+			fmt.Fprintln(os.Stderr, util.SprintGreen(fmt.Sprintf("%s: %d pages, %d items", v.Operation, v.Pages, v.Items)))
+		}
+	}
+}
+
+// targetGroup targets rendered into one report
+type targetGroup struct {
+	// Account set when the report is for one account out of several
+	Account string
+	targets []*target
+}
+
+// groupTargets one group per account, or a single group with every target
+// when --merge-accounts is given or there is only one account. Several
+// groups need a report each, so they cannot all go to stdout, and neither
+// can the fileFormats unless a --template replaces them.
+func groupTargets(c *cli.Context, targets []*target, name, format string) ([]*targetGroup, error) {
+	if name == stdout && fileFormats[format] && c.GlobalString("template") == "" {
+		return nil, fmt.Errorf("%s cannot be written to stdout, give --output a path", format)
+	}
+	accounts := make([]string, 0)
+	byAccount := make(map[string][]*target)
+	for _, t := range targets {
+		if _, ok := byAccount[t.Account]; !ok {
+			accounts = append(accounts, t.Account)
+		}
+		byAccount[t.Account] = append(byAccount[t.Account], t)
+	}
+	if c.GlobalBool("merge-accounts") || len(accounts) <= 1 {
+		return []*targetGroup{&targetGroup{targets: targets}}, nil
+	}
+	if name == stdout {
+		return nil, fmt.Errorf("--output - writes one report, but %d accounts make one each; add --merge-accounts or write to a file", len(accounts))
+	}
+	groups := make([]*targetGroup, 0, len(accounts))
+	for _, a := range accounts {
+		groups = append(groups, &targetGroup{Account: a, targets: byAccount[a]})
+	}
+	return groups, nil
+}
+
+// filename src for a merged report, src-<account> for one of several
+// accounts, the account going before any extension. groupTargets keeps
+// stdout to a single group.
+func (g *targetGroup) filename(src string) string {
+	if g.Account == "" || src == stdout {
+		return src
+	}
+	ext := filepath.Ext(src)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(src, ext), g.Account, ext)
+}
+
+// scope account and region something was collected from
+type scope struct {
+	Account string
+	Region  string
+}
+
+// appendScope scopes with s added, unless already there
+func appendScope(scopes []scope, s scope) []scope {
+	for _, v := range scopes {
+		if v == s {
+			return scopes
+		}
+	}
+	return append(scopes, s)
+}
+
+// sheetNamer names the sheets of a report that may span accounts and
+// regions, prefixing them with whichever of the two the report has several of
+type sheetNamer struct {
+	multiAccount bool
+	multiRegion  bool
+}
+
+func newSheetNamer(scopes []scope) *sheetNamer {
+	accounts := make(map[string]bool)
+	regions := make(map[string]bool)
+	for _, v := range scopes {
+		accounts[v.Account] = true
+		regions[v.Region] = true
+	}
+	return &sheetNamer{multiAccount: len(accounts) > 1, multiRegion: len(regions) > 1}
+}
+
+func (sn *sheetNamer) name(s scope, name string) string {
+	parts := make([]string, 0)
+	if sn.multiAccount {
+		parts = append(parts, s.Account)
+	}
+	if sn.multiRegion {
+		parts = append(parts, s.Region)
+	}
+	return sheetName(append(parts, name)...)
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli"
+)
+
+func TestGroupTargetsStdout(t *testing.T) {
+	targets := []*target{
+		{Account: "prod", Region: defaultRegion},
+		{Account: "staging", Region: defaultRegion},
+	}
+	context := func(merge bool) *cli.Context {
+		global := flag.NewFlagSet("global", flag.ContinueOnError)
+		global.Bool("merge-accounts", merge, "")
+		return cli.NewContext(nil, flag.NewFlagSet("network", flag.ContinueOnError), cli.NewContext(nil, global, nil))
+	}
+	groups, err := groupTargets(context(false), targets, "report", "xlsx")
+	if err != nil || len(groups) != 2 || groups[1].filename("report.xlsx") != "report-staging.xlsx" {
+		t.Fatalf("groups = %v, %v", groups, err)
+	}
+	if _, err := groupTargets(context(false), targets, stdout, "json"); err == nil || !strings.Contains(err.Error(), "--merge-accounts") {
+		t.Errorf("stdout with 2 accounts: err = %v", err)
+	}
+	groups, err = groupTargets(context(true), targets, stdout, "json")
+	if err != nil || len(groups) != 1 || groups[0].filename(stdout) != stdout {
+		t.Errorf("merged to stdout: groups = %v, err = %v", groups, err)
+	}
+}
+
+func TestGroupTargetsStdoutFormat(t *testing.T) {
+	targets := []*target{{Region: defaultRegion}}
+	context := func(template string) *cli.Context {
+		global := flag.NewFlagSet("global", flag.ContinueOnError)
+		global.String("template", template, "")
+		return cli.NewContext(nil, flag.NewFlagSet("network", flag.ContinueOnError), cli.NewContext(nil, global, nil))
+	}
+	tests := []struct {
+		format   string
+		template string
+		ok       bool
+	}{
+		{"xlsx", "", false},
+		{"pdf", "", false},
+		{"csv", "", false},
+		{"json", "", true},
+		{"html", "", true},
+		{"dot", "", true},
+		{"mermaid", "", true},
+		{"tf", "", true},
+		// the template is written instead of the format
+		{"xlsx", "design.md.tmpl", true},
+	}
+	for _, tt := range tests {
+		_, err := groupTargets(context(tt.template), targets, stdout, tt.format)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("%s to stdout with template %q: err = %v", tt.format, tt.template, err)
+		}
+		if _, err := groupTargets(context(tt.template), targets, "report", tt.format); err != nil {
+			t.Errorf("%s to a file: err = %v", tt.format, err)
+		}
+	}
+}
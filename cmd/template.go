@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateRenderer executes a user's template against the report: the
+// *Network, *SG or *IAM itself. Templates named *.html or *.html.tmpl are
+// html/template, anything else text/template. The output gets the extension
+// of the template without .tmpl, e.g. design.md.tmpl writes <src>.md.
+type templateRenderer struct {
+	path string
+	text string
+	ext  string
+	html bool
+}
+
+func newTemplateRenderer(path string) (*templateRenderer, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), ".tmpl")
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+	if ext == "" {
+		ext = "txt"
+	}
+	t := &templateRenderer{
+		path: path,
+		text: string(b),
+		ext:  ext,
+		html: ext == "html" || ext == "htm",
+	}
+	// parse once now so a broken template fails before collecting
+	if _, err := t.parse(&templateIndex{}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// executor template parsed with its functions
+type executor interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+func (t *templateRenderer) parse(x *templateIndex) (executor, error) {
+	name := filepath.Base(t.path)
+	if t.html {
+		return htmltemplate.New(name).Funcs(htmltemplate.FuncMap(x.funcs())).Parse(t.text)
+	}
+	return template.New(name).Funcs(x.funcs()).Parse(t.text)
+}
+
+func (t *templateRenderer) render(x *templateIndex, data interface{}, filename string) error {
+	e, err := t.parse(x)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := e.Execute(&buf, data); err != nil {
+		return err
+	}
+	return writeOutput(filename, t.ext, buf.Bytes())
+}
+
+func (t *templateRenderer) RenderNetwork(nt *Network, filename string) error {
+	return t.render(&templateIndex{nt: nt}, nt, filename)
+}
+
+func (t *templateRenderer) RenderSG(sg *SG, filename string) error {
+	return t.render(&templateIndex{sg: sg}, sg, filename)
+}
+
+func (t *templateRenderer) RenderIAM(iam *IAM, filename string) error {
+	return t.render(&templateIndex{iam: iam}, iam, filename)
+}
+
+// templateIndex the report a template runs against, for the lookup functions.
+// Lookups into another report return nil.
+type templateIndex struct {
+	nt  *Network
+	sg  *SG
+	iam *IAM
+}
+
+func (x *templateIndex) funcs() template.FuncMap {
+	return template.FuncMap{
+		"join":             strings.Join,
+		"location":         location,
+		"now":              func() string { return time.Now().UTC().Format(time.RFC3339) },
+		"cidrRange":        cidrRange,
+		"cidrSize":         cidrSize,
+		"policyDocument":   policyDocument,
+		"prettyDocument":   prettyDocument,
+		"markdownCell":     markdownCell,
+		"vpc":              x.vpc,
+		"routeTable":       x.routeTable,
+		"subnet":           x.subnet,
+		"securityGroup":    x.securityGroup,
+		"networkInterface": x.networkInterface,
+		"instance":         x.instance,
+		"policy":           x.policy,
+		"user":             x.user,
+		"group":            x.group,
+		"role":             x.role,
+	}
+}
+
+func (x *templateIndex) vpc(id string) *Vpc {
+	if x.nt == nil {
+		return nil
+	}
+	for _, v := range x.nt.Vpcs {
+		if v.ID == id {
+			return v
+		}
+	}
+	return nil
+}
+
+func (x *templateIndex) routeTable(id string) *RouteTable {
+	if x.nt == nil {
+		return nil
+	}
+	for _, v := range x.nt.Vpcs {
+		for _, rt := range v.RouteTables {
+			if rt.ID == id {
+				return rt
+			}
+		}
+	}
+	return nil
+}
+
+func (x *templateIndex) subnet(id string) *Subnet {
+	if x.nt == nil {
+		return nil
+	}
+	for _, v := range x.nt.Vpcs {
+		for _, sn := range v.Subnets {
+			if sn.ID == id {
+				return sn
+			}
+		}
+	}
+	return nil
+}
+
+func (x *templateIndex) securityGroup(id string) *SecurityGroup {
+	if x.sg == nil {
+		return nil
+	}
+	for _, v := range x.sg.SecurityGroups {
+		if v.ID == id {
+			return v
+		}
+	}
+	return nil
+}
+
+func (x *templateIndex) networkInterface(id string) *NetworkInterface {
+	if x.sg == nil {
+		return nil
+	}
+	for _, v := range x.sg.SecurityGroups {
+		for _, ni := range v.NetworkInterfaces {
+			if ni.ID == id {
+				return ni
+			}
+		}
+	}
+	return nil
+}
+
+func (x *templateIndex) instance(id string) *Instance {
+	if x.sg == nil {
+		return nil
+	}
+	for _, v := range x.sg.SecurityGroups {
+		for _, ni := range v.NetworkInterfaces {
+			if ni.Ec2Instance != nil && ni.Ec2Instance.ID == id {
+				return ni.Ec2Instance
+			}
+		}
+	}
+	return nil
+}
+
+// sameAccount whether account is the one asked for in want, which holds at
+// most one. Any account is when none is asked for.
+func sameAccount(account string, want []string) bool {
+	return len(want) == 0 || want[0] == account
+}
+
+// policy, user, group and role look up by name, in the account given as the
+// optional second argument
+func (x *templateIndex) policy(name string, account ...string) *Policy {
+	if x.iam == nil {
+		return nil
+	}
+	for _, v := range x.iam.Policies {
+		if v.Name == name && sameAccount(v.Account, account) {
+			return v
+		}
+	}
+	return nil
+}
+
+func (x *templateIndex) user(name string, account ...string) *User {
+	if x.iam == nil {
+		return nil
+	}
+	for _, v := range x.iam.Users {
+		if v.Name == name && sameAccount(v.Account, account) {
+			return v
+		}
+	}
+	return nil
+}
+
+func (x *templateIndex) group(name string, account ...string) *Group {
+	if x.iam == nil {
+		return nil
+	}
+	for _, v := range x.iam.Groups {
+		if v.Name == name && sameAccount(v.Account, account) {
+			return v
+		}
+	}
+	return nil
+}
+
+func (x *templateIndex) role(name string, account ...string) *Role {
+	if x.iam == nil {
+		return nil
+	}
+	for _, v := range x.iam.Roles {
+		if v.Name == name && sameAccount(v.Account, account) {
+			return v
+		}
+	}
+	return nil
+}
+
+// cidrRange first and last address of cidr, cidr itself when it is not one
+func cidrRange(cidr string) string {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return cidr
+	}
+	last := make(net.IP, len(n.IP))
+	for i := range n.IP {
+		last[i] = n.IP[i] | ^n.Mask[i]
+	}
+	return n.IP.String() + " - " + last.String()
+}
+
+// cidrSize number of addresses in cidr, empty when it is not one
+func cidrSize(cidr string) string {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return ""
+	}
+	ones, bits := n.Mask.Size()
+	return new(big.Int).Lsh(big.NewInt(1), uint(bits-ones)).String()
+}
+
+// markdownCell s fit for a Markdown table cell: pipes escaped, line breaks
+// as <br>
+func markdownCell(s string) string {
+	s = strings.Replace(s, "|", `\|`, -1)
+	return strings.Replace(strings.Replace(s, "\r\n", "<br>", -1), "\n", "<br>", -1)
+}
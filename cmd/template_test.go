@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestCidrRange(t *testing.T) {
+	tests := []struct {
+		cidr, want string
+	}{
+		{"10.0.0.0/16", "10.0.0.0 - 10.0.255.255"},
+		{"10.0.1.5/24", "10.0.1.0 - 10.0.1.255"},
+		{"10.0.0.1/32", "10.0.0.1 - 10.0.0.1"},
+		{"0.0.0.0/0", "0.0.0.0 - 255.255.255.255"},
+		{"2406:da14:abc:de00::/56", "2406:da14:abc:de00:: - 2406:da14:abc:deff:ffff:ffff:ffff:ffff"},
+		{"::/0", ":: - ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"},
+		{"pl-0office", "pl-0office"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := cidrRange(tt.cidr); got != tt.want {
+			t.Errorf("cidrRange(%q) = %q, want %q", tt.cidr, got, tt.want)
+		}
+	}
+}
+
+func TestCidrSize(t *testing.T) {
+	tests := []struct {
+		cidr, want string
+	}{
+		{"10.0.0.0/16", "65536"},
+		{"10.0.0.1/32", "1"},
+		{"0.0.0.0/0", "4294967296"},
+		{"2406:da14:abc:de00::/64", "18446744073709551616"},
+		{"2406:da14:abc:de00::/56", "4722366482869645213696"},
+		{"::/0", "340282366920938463463374607431768211456"},
+		{"sg-0web", ""},
+	}
+	for _, tt := range tests {
+		if got := cidrSize(tt.cidr); got != tt.want {
+			t.Errorf("cidrSize(%q) = %q, want %q", tt.cidr, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownCell(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"a|b", `a\|b`},
+		{"one\ntwo", "one<br>two"},
+		{"one\r\ntwo\nthree", "one<br>two<br>three"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := markdownCell(tt.in); got != tt.want {
+			t.Errorf("markdownCell(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNewTemplateRenderer(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  string
+		html bool
+		out  string
+	}{
+		{"design.md.tmpl", "md", false, "<b>"},
+		{"design.md", "md", false, "<b>"},
+		{"report.html.tmpl", "html", true, "&lt;b&gt;"},
+		{"report.htm", "htm", true, "&lt;b&gt;"},
+		{"notes.tmpl", "txt", false, "<b>"},
+		{"notes", "txt", false, "<b>"},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		if err := ioutil.WriteFile(path, []byte(`{{"<b>"}}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		r, err := newTemplateRenderer(path)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if r.ext != tt.ext || r.html != tt.html {
+			t.Errorf("%s: ext, html = %s, %v, want %s, %v", tt.name, r.ext, r.html, tt.ext, tt.html)
+		}
+		e, err := r.parse(&templateIndex{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := e.Execute(&buf, nil); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.out {
+			t.Errorf("%s: output = %q, want %q", tt.name, buf.String(), tt.out)
+		}
+	}
+
+	path := filepath.Join(dir, "broken.md.tmpl")
+	if err := ioutil.WriteFile(path, []byte(`{{range .Vpcs}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newTemplateRenderer(path); err == nil {
+		t.Error("broken template parsed")
+	}
+}
+
+func TestTemplateIndexLookups(t *testing.T) {
+	nt := &templateIndex{nt: collectFixtureNetwork(t)}
+	sg := &templateIndex{sg: collectFixtureSG(t)}
+	iam := &templateIndex{iam: collectFixtureIAM(t)}
+	tests := []struct {
+		name  string
+		found bool
+	}{
+		{"vpc in network", nt.vpc("vpc-0a1b2c3d") != nil},
+		{"route table in network", nt.routeTable("rtb-0main") != nil},
+		{"subnet in network", nt.subnet("subnet-0public") != nil},
+		{"security group in sg", sg.securityGroup("sg-0web") != nil},
+		{"network interface in sg", sg.networkInterface("eni-0web") != nil},
+		{"instance in sg", sg.instance("i-0web") != nil},
+		{"policy in iam", iam.policy("SampleReadOnly") != nil},
+		{"user in iam", iam.user("alice") != nil},
+		{"group in iam", iam.group("operators") != nil},
+		{"role in iam", iam.role("sample-ec2-role") != nil},
+		{"user in its account", iam.user("alice", "") != nil},
+	}
+	for _, tt := range tests {
+		if !tt.found {
+			t.Errorf("%s not found", tt.name)
+		}
+	}
+
+	missing := []struct {
+		name  string
+		found bool
+	}{
+		{"vpc in sg", sg.vpc("vpc-0a1b2c3d") != nil},
+		{"route table in iam", iam.routeTable("rtb-0main") != nil},
+		{"subnet in sg", sg.subnet("subnet-0public") != nil},
+		{"security group in network", nt.securityGroup("sg-0web") != nil},
+		{"network interface in network", nt.networkInterface("eni-0web") != nil},
+		{"instance in iam", iam.instance("i-0web") != nil},
+		{"policy in network", nt.policy("SampleReadOnly") != nil},
+		{"user in sg", sg.user("alice") != nil},
+		{"group in network", nt.group("operators") != nil},
+		{"role in sg", sg.role("sample-ec2-role") != nil},
+		{"user in another account", iam.user("alice", "other") != nil},
+		{"unknown vpc", nt.vpc("vpc-0gone") != nil},
+	}
+	for _, tt := range missing {
+		if tt.found {
+			t.Errorf("%s found, want nil", tt.name)
+		}
+	}
+}
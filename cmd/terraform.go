@@ -0,0 +1,480 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/atsushi-ishibashi/aws-state-report/util"
+	"github.com/urfave/cli"
+)
+
+func NewTerraformCommand() cli.Command {
+	return cli.Command{
+		Name:  "terraform",
+		Usage: "export vpcs, subnets, route tables and security groups as terraform resources with import blocks",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "src",
+				Usage: "file name to export",
+				Value: "terraform",
+			},
+			cli.StringFlag{
+				Name:  "output",
+				Usage: "path to export to, directories created as needed. - for stdout. overrides --src",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.GlobalString("format") != "" || c.GlobalString("template") != "" {
+				return util.ErrorRed("terraform writes HCL only, drop --format and --template")
+			}
+			name := outputName(c)
+			targets, err := newTargets(c, true)
+			if err != nil {
+				return util.ErrorRed(err.Error())
+			}
+			groups, err := groupTargets(c, targets, name, "tf")
+			if err != nil {
+				return util.ErrorRed(err.Error())
+			}
+			reports := make([]*terraformReport, 0, len(groups))
+			for _, g := range groups {
+				r := &terraformReport{
+					network: &Network{Errs: make([]error, 0)},
+					sg:      &SG{rules: true, Errs: make([]error, 0)},
+				}
+				for _, t := range g.targets {
+					r.network.collect(t)
+					r.sg.collect(t)
+				}
+				reports = append(reports, r)
+			}
+			printPageStats(targets)
+			errs := make([]error, 0)
+			for _, r := range reports {
+				if err := r.flattenErrs(); err != nil && !continueOnError(c) {
+					return util.ErrorRed(err.Error())
+				}
+				errs = append(errs, r.errs()...)
+			}
+			if err := saveSnapshot(c, targets); err != nil {
+				return util.ErrorRed(err.Error())
+			}
+			for i, r := range reports {
+				if err := writeOutput(groups[i].filename(name), "tf", r.hcl()); err != nil {
+					return util.ErrorRed(err.Error())
+				}
+			}
+			return partialReportError(errs)
+		},
+	}
+}
+
+// terraformReport network and security groups of the same targets, written
+// as terraform resources, each followed by the import block that adopts it
+type terraformReport struct {
+	network *Network
+	sg      *SG
+	names   *terraformNamer
+	// providers alias by account and region, empty when there is only one
+	providers map[scope]string
+}
+
+// errs what failed in either collector
+func (r *terraformReport) errs() []error {
+	errs := make([]error, 0)
+	errs = append(errs, r.network.Errs...)
+	return append(errs, r.sg.Errs...)
+}
+
+func (r *terraformReport) flattenErrs() error {
+	return flattenErrors(r.errs())
+}
+
+func (r *terraformReport) hcl() []byte {
+	r.names = newTerraformNamer()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Generated by aws-state-report at %s.\n", time.Now().UTC().Format(time.RFC3339))
+	buf.WriteString("# Only the Name tag is collected; run terraform plan after importing and\n")
+	buf.WriteString("# fill in what it reports as changes.\n")
+
+	scopes := appendScopes(r.network.scopes(), r.sg.scopes())
+	r.providers = make(map[scope]string)
+	for _, s := range scopes {
+		b := &hclBlock{header: `provider "aws"`}
+		if len(scopes) > 1 {
+			alias := r.names.label("provider", location(s.Account, s.Region), location(s.Account, s.Region))
+			r.providers[s] = alias
+			b.attr("alias", hclString(alias))
+		}
+		b.attr("region", hclString(s.Region))
+		if s.Account != "" {
+			b.comment = fmt.Sprintf("account %s, add the credentials or assume_role to reach it", s.Account)
+		}
+		b.write(&buf)
+	}
+
+	// name everything first so references resolve whatever the order
+	for _, v := range r.network.Vpcs {
+		r.names.label("aws_vpc", v.ID, v.TagName)
+		for _, sn := range v.Subnets {
+			r.names.label("aws_subnet", sn.ID, sn.TagName)
+		}
+		for _, rt := range v.RouteTables {
+			r.names.label("aws_route_table", rt.ID, rt.TagName)
+		}
+	}
+	for _, v := range r.sg.SecurityGroups {
+		r.names.label(securityGroupResource(v), v.ID, v.GroupName)
+	}
+
+	for _, v := range r.network.Vpcs {
+		r.writeVpc(&buf, v)
+	}
+	for _, v := range r.sg.SecurityGroups {
+		r.writeSecurityGroup(&buf, v)
+	}
+	return buf.Bytes()
+}
+
+// appendScopes a with the scopes of b not in it yet
+func appendScopes(a, b []scope) []scope {
+	for _, s := range b {
+		a = appendScope(a, s)
+	}
+	return a
+}
+
+// resource writes a resource and the import block adopting id into it
+func (r *terraformReport) resource(buf *bytes.Buffer, s scope, b *hclBlock, typ, name, id string) {
+	b.header = fmt.Sprintf("resource %s %s", hclString(typ), hclString(name))
+	im := &hclBlock{header: "import"}
+	im.attr("to", typ+"."+name)
+	im.attr("id", hclString(id))
+	if alias, ok := r.providers[s]; ok {
+		b.attrs = append([]*hclAttr{&hclAttr{name: "provider", value: "aws." + alias}}, b.attrs...)
+		im.attr("provider", "aws."+alias)
+	}
+	b.write(buf)
+	im.write(buf)
+}
+
+func (r *terraformReport) writeVpc(buf *bytes.Buffer, v *Vpc) {
+	s := scope{Account: v.Account, Region: v.Region}
+	b := &hclBlock{}
+	b.attr("cidr_block", hclString(v.CidrBlock))
+	comments := make([]string, 0)
+	if len(v.Ipv6CidrBlocks) > 0 {
+		b.attr("assign_generated_ipv6_cidr_block", "true")
+		comments = append(comments, "IPv6 "+strings.Join(v.Ipv6CidrBlocks, ", ")+", set ipv6_ipam_pool_id instead if not Amazon-provided")
+	}
+	b.tags(v.TagName)
+	secondary := make([]string, 0)
+	for _, cidr := range v.AssociatedCidrBlocks {
+		if cidr != v.CidrBlock {
+			secondary = append(secondary, cidr)
+		}
+	}
+	if len(secondary) > 0 {
+		comments = append(comments, "secondary CIDR blocks, not exported: "+strings.Join(secondary, ", "))
+	}
+	b.comment = strings.Join(comments, "\n")
+	r.resource(buf, s, b, "aws_vpc", r.names.get("aws_vpc", v.ID), v.ID)
+
+	for _, sn := range v.Subnets {
+		b := &hclBlock{}
+		b.attr("vpc_id", r.names.ref("aws_vpc", v.ID))
+		if sn.CidrBlock != "" {
+			b.attr("cidr_block", hclString(sn.CidrBlock))
+		} else {
+			b.attr("ipv6_native", "true")
+		}
+		if len(sn.Ipv6CidrBlocks) > 0 {
+			b.attr("ipv6_cidr_block", hclString(sn.Ipv6CidrBlocks[0]))
+		}
+		if sn.AvailabilityZone != "" {
+			b.attr("availability_zone", hclString(sn.AvailabilityZone))
+		}
+		b.tags(sn.TagName)
+		r.resource(buf, s, b, "aws_subnet", r.names.get("aws_subnet", sn.ID), sn.ID)
+	}
+
+	for _, rt := range v.RouteTables {
+		b := &hclBlock{}
+		comments := make([]string, 0)
+		if rt.IsMain() {
+			comments = append(comments, "main route table of the VPC")
+		}
+		b.attr("vpc_id", r.names.ref("aws_vpc", v.ID))
+		for _, route := range rt.Routes {
+			switch {
+			case route.RouterType == routerLocal:
+				continue
+			case route.IsPropagated():
+				comments = append(comments, fmt.Sprintf("%s via %s is propagated, not exported", route.Destination(), route.Router))
+				continue
+			case route.RouterType == routerVpcEndpoint && route.DestinationPrefixListID != "":
+				comments = append(comments, fmt.Sprintf("%s via %s belongs to the gateway endpoint, see aws_vpc_endpoint_route_table_association", route.Destination(), route.Router))
+				continue
+			}
+			rb := &hclBlock{header: "route"}
+			switch {
+			case route.DestinationCidrBlock != "":
+				rb.attr("cidr_block", hclString(route.DestinationCidrBlock))
+			case route.DestinationIpv6CidrBlock != "":
+				rb.attr("ipv6_cidr_block", hclString(route.DestinationIpv6CidrBlock))
+			default:
+				rb.attr("destination_prefix_list_id", hclString(route.DestinationPrefixListID))
+			}
+			switch {
+			case route.Router == "":
+				rb.comment = "target not collected, fill it in"
+			case route.RouterType == routerInstance:
+				rb.attr("network_interface_id", hclString(route.NetworkInterfaceID))
+			default:
+				rb.attr(routeTargetAttr(route.RouterType), hclString(route.Router))
+			}
+			if route.IsBlackhole() {
+				rb.comment = "blackhole: " + route.Router + " no longer exists, drop the route or fix its target"
+			}
+			b.blocks = append(b.blocks, rb)
+		}
+		b.comment = strings.Join(comments, "\n")
+		b.tags(rt.TagName)
+		name := r.names.get("aws_route_table", rt.ID)
+		r.resource(buf, s, b, "aws_route_table", name, rt.ID)
+
+		for _, snID := range rt.AssociationSubnets {
+			b := &hclBlock{}
+			b.attr("subnet_id", r.names.ref("aws_subnet", snID))
+			b.attr("route_table_id", r.names.ref("aws_route_table", rt.ID))
+			label := r.names.label("aws_route_table_association", snID, r.names.get("aws_subnet", snID))
+			r.resource(buf, s, b, "aws_route_table_association", label, snID+"/"+rt.ID)
+		}
+		for _, gwID := range rt.AssociationGateways {
+			b := &hclBlock{comment: "edge association"}
+			b.attr("gateway_id", hclString(gwID))
+			b.attr("route_table_id", r.names.ref("aws_route_table", rt.ID))
+			label := r.names.label("aws_route_table_association", gwID, gwID)
+			r.resource(buf, s, b, "aws_route_table_association", label, gwID+"/"+rt.ID)
+		}
+	}
+}
+
+// routeTargetAttr attribute of a route block a target of the kind goes in
+func routeTargetAttr(routerType string) string {
+	switch routerType {
+	case routerNatGateway:
+		return "nat_gateway_id"
+	case routerPeeringConnection:
+		return "vpc_peering_connection_id"
+	case routerTransitGateway:
+		return "transit_gateway_id"
+	case routerNetworkInterface:
+		return "network_interface_id"
+	case routerVpcEndpoint:
+		return "vpc_endpoint_id"
+	case routerEgressOnlyInternetGateway:
+		return "egress_only_gateway_id"
+	case routerCarrierGateway:
+		return "carrier_gateway_id"
+	case routerLocalGateway:
+		return "local_gateway_id"
+	case routerCoreNetwork:
+		return "core_network_arn"
+	}
+	return "gateway_id"
+}
+
+// securityGroupResource the default group of a VPC cannot be created, only
+// adopted as aws_default_security_group
+func securityGroupResource(v *SecurityGroup) string {
+	if v.GroupName == "default" {
+		return "aws_default_security_group"
+	}
+	return "aws_security_group"
+}
+
+func (r *terraformReport) writeSecurityGroup(buf *bytes.Buffer, v *SecurityGroup) {
+	s := scope{Account: v.Account, Region: v.Region}
+	typ := securityGroupResource(v)
+	b := &hclBlock{}
+	if typ == "aws_security_group" {
+		b.attr("name", hclString(v.GroupName))
+		b.attr("description", hclString(v.Description))
+	}
+	if v.VpcID != "" {
+		b.attr("vpc_id", r.names.ref("aws_vpc", v.VpcID))
+	}
+	b.tags(v.TagName)
+	name := r.names.get(typ, v.ID)
+	r.resource(buf, s, b, typ, name, v.ID)
+
+	for _, rule := range v.Rules {
+		ruleTyp := "aws_vpc_security_group_ingress_rule"
+		if rule.Egress {
+			ruleTyp = "aws_vpc_security_group_egress_rule"
+		}
+		b := &hclBlock{}
+		b.attr("security_group_id", typ+"."+name+".id")
+		if rule.Description != "" {
+			b.attr("description", hclString(rule.Description))
+		}
+		b.attr("ip_protocol", hclString(rule.Protocol))
+		if rule.Protocol != "-1" {
+			b.attr("from_port", fmt.Sprint(rule.FromPort))
+			b.attr("to_port", fmt.Sprint(rule.ToPort))
+		}
+		switch {
+		case rule.CidrIpv4 != "":
+			b.attr("cidr_ipv4", hclString(rule.CidrIpv4))
+		case rule.CidrIpv6 != "":
+			b.attr("cidr_ipv6", hclString(rule.CidrIpv6))
+		case rule.PrefixListID != "":
+			b.attr("prefix_list_id", hclString(rule.PrefixListID))
+		case rule.ReferencedGroupID != "":
+			b.attr("referenced_security_group_id", r.securityGroupRef(rule.ReferencedGroupID))
+		}
+		label := r.names.label(ruleTyp, rule.ID, name+"_"+rule.ID)
+		r.resource(buf, s, b, ruleTyp, label, rule.ID)
+	}
+}
+
+// securityGroupRef reference to the group id, whichever resource type it was
+// exported as
+func (r *terraformReport) securityGroupRef(id string) string {
+	for _, v := range r.sg.SecurityGroups {
+		if v.ID == id {
+			return r.names.ref(securityGroupResource(v), id)
+		}
+	}
+	return hclString(id)
+}
+
+// terraformNamer resource names, unique per resource type, by the ID of what
+// they stand for
+type terraformNamer struct {
+	names map[string]string
+	used  map[string]bool
+}
+
+func newTerraformNamer() *terraformNamer {
+	return &terraformNamer{
+		names: make(map[string]string),
+		used:  make(map[string]bool),
+	}
+}
+
+var terraformNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// label names id of typ after want, the tag name or else id itself, made a
+// valid name and numbered if taken
+func (n *terraformNamer) label(typ, id, want string) string {
+	if name, ok := n.names[typ+"/"+id]; ok {
+		return name
+	}
+	if want == "" {
+		want = id
+	}
+	base := strings.Trim(terraformNameUnsafe.ReplaceAllString(want, "_"), "_")
+	if base == "" || !isLetterOrUnderscore(base[0]) {
+		base = "_" + base
+	}
+	name := base
+	for i := 2; n.used[typ+"/"+name]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	n.used[typ+"/"+name] = true
+	n.names[typ+"/"+id] = name
+	return name
+}
+
+func isLetterOrUnderscore(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// get name given to id of typ, id itself made a name if there is none
+func (n *terraformNamer) get(typ, id string) string {
+	return n.label(typ, id, "")
+}
+
+// ref expression for the id attribute of the resource exported for id, the
+// ID as a literal when it was not exported
+func (n *terraformNamer) ref(typ, id string) string {
+	if name, ok := n.names[typ+"/"+id]; ok {
+		return typ + "." + name + ".id"
+	}
+	return hclString(id)
+}
+
+// hclBlock a block with its attributes, then its nested blocks
+type hclBlock struct {
+	header  string
+	comment string
+	attrs   []*hclAttr
+	blocks  []*hclBlock
+}
+
+// hclAttr an attribute, value an HCL expression
+type hclAttr struct {
+	name  string
+	value string
+}
+
+func (b *hclBlock) attr(name, value string) {
+	b.attrs = append(b.attrs, &hclAttr{name: name, value: value})
+}
+
+// tags the Name tag, if there is one
+func (b *hclBlock) tags(name string) {
+	if name != "" {
+		b.attr("tags", fmt.Sprintf("{ Name = %s }", hclString(name)))
+	}
+}
+
+func (b *hclBlock) write(buf *bytes.Buffer) {
+	buf.WriteString("\n")
+	b.writeIndent(buf, "")
+}
+
+// writeIndent writes b with the attribute equals signs aligned like
+// terraform fmt does
+func (b *hclBlock) writeIndent(buf *bytes.Buffer, indent string) {
+	if b.comment != "" {
+		for _, line := range strings.Split(b.comment, "\n") {
+			fmt.Fprintf(buf, "%s# %s\n", indent, line)
+		}
+	}
+	fmt.Fprintf(buf, "%s%s {\n", indent, b.header)
+	width := 0
+	for _, a := range b.attrs {
+		if len(a.name) > width {
+			width = len(a.name)
+		}
+	}
+	for _, a := range b.attrs {
+		fmt.Fprintf(buf, "%s  %-*s = %s\n", indent, width, a.name, a.value)
+	}
+	for _, nb := range b.blocks {
+		buf.WriteString("\n")
+		nb.writeIndent(buf, indent+"  ")
+	}
+	fmt.Fprintf(buf, "%s}\n", indent)
+}
+
+var hclEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+	"${", "$${",
+	"%{", "%%{",
+)
+
+// hclString s as a quoted HCL string, with template sequences escaped
+func hclString(s string) string {
+	return `"` + hclEscaper.Replace(s) + `"`
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func collectFixtureTerraform(t *testing.T) *terraformReport {
+	t.Helper()
+	target := fixtureTarget(t)
+	r := &terraformReport{
+		network: &Network{Errs: make([]error, 0)},
+		sg:      &SG{rules: true, Errs: make([]error, 0)},
+	}
+	r.network.collect(target)
+	r.sg.collect(target)
+	if err := r.flattenErrs(); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// hclBlockOf the top-level block of hcl opening with header, without the
+// comment lines above it
+func hclBlockOf(t *testing.T, hcl, header string) string {
+	t.Helper()
+	start := strings.Index(hcl, "\n"+header+" {\n")
+	if start < 0 {
+		t.Fatalf("no %s block in:\n%s", header, hcl)
+	}
+	block := hcl[start+1:]
+	return block[:strings.Index(block, "\n}\n")+3]
+}
+
+// hclImportOf the import block following the resource block opening with
+// header
+func hclImportOf(t *testing.T, hcl, header string) string {
+	t.Helper()
+	rest := hcl[strings.Index(hcl, "\n"+header+" {\n")+1:]
+	return hclBlockOf(t, rest, "import")
+}
+
+func TestHclString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"web", `"web"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`C:\temp`, `"C:\\temp"`},
+		{"line\nbreak\ttab\r", `"line\nbreak\ttab\r"`},
+		{"${var.x}", `"$${var.x}"`},
+		{"%{ if x }", `"%%{ if x }"`},
+		{"$5 and 100%", `"$5 and 100%"`},
+	}
+	for _, tt := range tests {
+		if got := hclString(tt.in); got != tt.want {
+			t.Errorf("hclString(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTerraformNamerLabel(t *testing.T) {
+	n := newTerraformNamer()
+	tests := []struct {
+		typ, id, want, name string
+	}{
+		{"aws_subnet", "subnet-1", "web", "web"},
+		{"aws_subnet", "subnet-2", "web", "web_2"},
+		{"aws_subnet", "subnet-3", "web", "web_3"},
+		// the same ID keeps its name
+		{"aws_subnet", "subnet-1", "other", "web"},
+		// names are unique per type only
+		{"aws_route_table", "rtb-1", "web", "web"},
+		{"aws_subnet", "subnet-4", "", "subnet-4"},
+		{"aws_subnet", "subnet-5", "app servers (a)", "app_servers_a"},
+		{"aws_subnet", "subnet-6", "1st tier", "_1st_tier"},
+		{"aws_subnet", "subnet-7", "日本語", "_"},
+		{"aws_subnet", "subnet-8", "web_2", "web_2_2"},
+	}
+	for _, tt := range tests {
+		if got := n.label(tt.typ, tt.id, tt.want); got != tt.name {
+			t.Errorf("label(%s, %s, %q) = %s, want %s", tt.typ, tt.id, tt.want, got, tt.name)
+		}
+	}
+	if got := n.get("aws_subnet", "subnet-2"); got != "web_2" {
+		t.Errorf("get(subnet-2) = %s, want web_2", got)
+	}
+	if got := n.ref("aws_subnet", "subnet-2"); got != "aws_subnet.web_2.id" {
+		t.Errorf("ref(subnet-2) = %s", got)
+	}
+	if got := n.ref("aws_subnet", "subnet-9"); got != `"subnet-9"` {
+		t.Errorf("ref(uncollected) = %s, want the ID as a literal", got)
+	}
+}
+
+func TestTerraformHCL(t *testing.T) {
+	r := collectFixtureTerraform(t)
+	r.sg.SecurityGroups = append(r.sg.SecurityGroups, &SecurityGroup{
+		ID:          "sg-0default",
+		Region:      defaultRegion,
+		VpcID:       "vpc-0a1b2c3d",
+		GroupName:   "default",
+		Description: "default VPC security group",
+		Rules: []*SecurityGroupRule{{
+			ID:                "sgr-0defaultin",
+			Protocol:          "-1",
+			ReferencedGroupID: "sg-0default",
+		}},
+	})
+	// a group and a VPC that were not collected are referenced by ID
+	r.sg.SecurityGroups = append(r.sg.SecurityGroups, &SecurityGroup{
+		ID:          "sg-0other",
+		Region:      defaultRegion,
+		VpcID:       "vpc-0other",
+		GroupName:   "other",
+		Description: `uses "quotes" and ${braces}`,
+		Rules: []*SecurityGroupRule{{
+			ID:                "sgr-0otherin",
+			Protocol:          "tcp",
+			FromPort:          80,
+			ToPort:            80,
+			ReferencedGroupID: "sg-0gone",
+		}},
+	})
+	hcl := string(r.hcl())
+
+	if strings.Contains(hcl, "alias") || strings.Contains(hcl, "provider =") {
+		t.Errorf("single region has provider aliases:\n%s", hcl)
+	}
+	if b := hclBlockOf(t, hcl, `resource "aws_vpc" "sample-vpc"`); !strings.Contains(b, `cidr_block                       = "10.0.0.0/16"`) {
+		t.Errorf("vpc =\n%s", b)
+	}
+	if im := hclImportOf(t, hcl, `resource "aws_vpc" "sample-vpc"`); im != "import {\n  to = aws_vpc.sample-vpc\n  id = \"vpc-0a1b2c3d\"\n}\n" {
+		t.Errorf("vpc import =\n%s", im)
+	}
+	if b := hclBlockOf(t, hcl, `resource "aws_subnet" "sample-public-a"`); !strings.Contains(b, "vpc_id            = aws_vpc.sample-vpc.id\n") {
+		t.Errorf("subnet does not reference the vpc resource:\n%s", b)
+	}
+
+	b := hclBlockOf(t, hcl, `resource "aws_route_table" "sample-main"`)
+	for _, want := range []string{
+		"    ipv6_cidr_block        = \"::/0\"\n    egress_only_gateway_id = \"eigw-0a1b2c3d\"\n",
+		"  # blackhole: pcx-0a1b2c3d no longer exists, drop the route or fix its target\n  route {\n    cidr_block                = \"192.168.0.0/16\"\n",
+	} {
+		if !strings.Contains(b, want) {
+			t.Errorf("main route table missing %q:\n%s", want, b)
+		}
+	}
+	if strings.Contains(b, "10.100.0.0/16") || !strings.Contains(hcl, "# 10.100.0.0/16 via vgw-0a1b2c3d is propagated, not exported\n") {
+		t.Errorf("propagated route exported:\n%s", b)
+	}
+
+	header := `resource "aws_route_table_association" "sample-public-a"`
+	if b := hclBlockOf(t, hcl, header); !strings.Contains(b, "subnet_id      = aws_subnet.sample-public-a.id\n  route_table_id = aws_route_table.sample-public.id\n") {
+		t.Errorf("association =\n%s", b)
+	}
+	if im := hclImportOf(t, hcl, header); !strings.Contains(im, `id = "subnet-0public/rtb-0public"`) {
+		t.Errorf("association import =\n%s", im)
+	}
+	if im := hclImportOf(t, hcl, `resource "aws_route_table_association" "igw-0a1b2c3d"`); !strings.Contains(im, `id = "igw-0a1b2c3d/rtb-0ingress"`) {
+		t.Errorf("edge association import =\n%s", im)
+	}
+
+	header = `resource "aws_vpc_security_group_ingress_rule" "web_sgr-0web443v6"`
+	if b := hclBlockOf(t, hcl, header); !strings.Contains(b, `cidr_ipv6         = "::/0"`) {
+		t.Errorf("IPv6 rule =\n%s", b)
+	}
+	if im := hclImportOf(t, hcl, header); !strings.Contains(im, "to = aws_vpc_security_group_ingress_rule.web_sgr-0web443v6\n  id = \"sgr-0web443v6\"\n") {
+		t.Errorf("rule import =\n%s", im)
+	}
+	if b := hclBlockOf(t, hcl, `resource "aws_vpc_security_group_ingress_rule" "db_sgr-0db5432"`); !strings.Contains(b, "referenced_security_group_id = aws_security_group.web.id\n") {
+		t.Errorf("rule does not reference the web group resource:\n%s", b)
+	}
+
+	b = hclBlockOf(t, hcl, `resource "aws_default_security_group" "default"`)
+	if strings.Contains(b, "name ") || strings.Contains(b, "description") || !strings.Contains(b, "vpc_id = aws_vpc.sample-vpc.id\n") {
+		t.Errorf("default group =\n%s", b)
+	}
+	if im := hclImportOf(t, hcl, `resource "aws_default_security_group" "default"`); !strings.Contains(im, "to = aws_default_security_group.default\n  id = \"sg-0default\"\n") {
+		t.Errorf("default group import =\n%s", im)
+	}
+	if b := hclBlockOf(t, hcl, `resource "aws_vpc_security_group_ingress_rule" "default_sgr-0defaultin"`); !strings.Contains(b, "security_group_id            = aws_default_security_group.default.id\n") || !strings.Contains(b, "referenced_security_group_id = aws_default_security_group.default.id\n") || strings.Contains(b, "from_port") {
+		t.Errorf("default group rule =\n%s", b)
+	}
+
+	b = hclBlockOf(t, hcl, `resource "aws_security_group" "other"`)
+	if !strings.Contains(b, `description = "uses \"quotes\" and $${braces}"`) || !strings.Contains(b, `vpc_id      = "vpc-0other"`) {
+		t.Errorf("other group =\n%s", b)
+	}
+	if b := hclBlockOf(t, hcl, `resource "aws_vpc_security_group_ingress_rule" "other_sgr-0otherin"`); !strings.Contains(b, `referenced_security_group_id = "sg-0gone"`) {
+		t.Errorf("rule referencing an uncollected group =\n%s", b)
+	}
+}
+
+func TestTerraformHCLProviderAliases(t *testing.T) {
+	r := collectFixtureTerraform(t)
+	r.sg.SecurityGroups = append(r.sg.SecurityGroups, &SecurityGroup{
+		ID:          "sg-0east",
+		Account:     "prod",
+		Region:      "us-east-1",
+		GroupName:   "east",
+		Description: "east",
+	})
+	hcl := string(r.hcl())
+
+	for _, want := range []string{
+		"provider \"aws\" {\n  alias  = \"ap-northeast-1\"\n  region = \"ap-northeast-1\"\n}\n",
+		"# account prod, add the credentials or assume_role to reach it\nprovider \"aws\" {\n  alias  = \"prod_us-east-1\"\n  region = \"us-east-1\"\n}\n",
+	} {
+		if !strings.Contains(hcl, want) {
+			t.Errorf("missing provider %q in:\n%s", want, hcl)
+		}
+	}
+	header := `resource "aws_vpc" "sample-vpc"`
+	if b := hclBlockOf(t, hcl, header); !strings.HasPrefix(b, header+" {\n  provider                         = aws.ap-northeast-1\n") {
+		t.Errorf("vpc provider =\n%s", b)
+	}
+	if im := hclImportOf(t, hcl, header); !strings.Contains(im, "  provider = aws.ap-northeast-1\n") {
+		t.Errorf("vpc import provider =\n%s", im)
+	}
+	header = `resource "aws_security_group" "east"`
+	if b := hclBlockOf(t, hcl, header); !strings.Contains(b, "provider    = aws.prod_us-east-1\n") {
+		t.Errorf("east group provider =\n%s", b)
+	}
+	if im := hclImportOf(t, hcl, header); !strings.Contains(im, "  provider = aws.prod_us-east-1\n") {
+		t.Errorf("east group import provider =\n%s", im)
+	}
+}
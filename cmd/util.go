@@ -15,7 +15,74 @@ func hyperlink(sheet string, row, col int, name string) string {
 		colBytes = append(colBytes, byte(64+a))
 	}
 	colBytes = append(colBytes, byte(65+b))
-	return fmt.Sprintf(`HYPERLINK("#%s!%s%d","%s")`, sheet, string(colBytes), row+1, name)
+	sheet = strings.Replace(sheet, "'", "''", -1)
+	name = strings.Replace(name, `"`, `""`, -1)
+	return fmt.Sprintf(`HYPERLINK("#'%s'!%s%d","%s")`, sheet, string(colBytes), row+1, name)
+}
+
+// xlsxLocation cell of a workbook something is written at, for links to it
+// from other sheets
+type xlsxLocation struct {
+	sheet    string
+	row, col int
+}
+
+func (l xlsxLocation) hyperlink(name string) string {
+	return hyperlink(l.sheet, l.row, l.col, name)
+}
+
+// sheetName joins parts into a name xlsx accepts for a sheet. The leading
+// parts are cut first when it is too long, so the last one stays readable.
+func sheetName(parts ...string) string {
+	nonEmpty := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p == "" {
+			continue
+		}
+		for _, v := range []string{":", "\\", "/", "?", "*", "[", "]"} {
+			p = strings.Replace(p, v, "_", -1)
+		}
+		nonEmpty = append(nonEmpty, p)
+	}
+	if len(nonEmpty) == 0 {
+		return ""
+	}
+	parts = nonEmpty
+	last := []rune(parts[len(parts)-1])
+	if len(last) >= sheetNameLimit {
+		return string(last[:sheetNameLimit])
+	}
+	prefix := []rune(strings.Join(parts[:len(parts)-1], " "))
+	if len(prefix) == 0 {
+		return string(last)
+	}
+	if len(prefix)+1+len(last) > sheetNameLimit {
+		prefix = prefix[:sheetNameLimit-1-len(last)]
+	}
+	return string(prefix) + " " + string(last)
+}
+
+// sheetNameLimit max length of a sheet name
+const sheetNameLimit = 31
+
+// addSheet adds a sheet named name to file, suffixed "~2", "~3"... when the
+// name is already taken, since xlsx compares sheet names case-insensitively
+// and two VPCs can share a tag name
+func addSheet(file *xlsx.File, name string) (*xlsx.Sheet, error) {
+	taken := make(map[string]bool)
+	for _, s := range file.Sheets {
+		taken[strings.ToLower(s.Name)] = true
+	}
+	unique := name
+	for i := 2; taken[strings.ToLower(unique)]; i++ {
+		suffix := fmt.Sprintf("~%d", i)
+		base := []rune(name)
+		if len(base)+len(suffix) > sheetNameLimit {
+			base = base[:sheetNameLimit-len(suffix)]
+		}
+		unique = string(base) + suffix
+	}
+	return file.AddSheet(unique)
 }
 
 func extractTagName(tags []*ec2.Tag) string {
@@ -53,3 +120,21 @@ func borderWithAlign(lrtb string, isAlign bool) *xlsx.Style {
 	}
 	return st
 }
+
+// highlighted st in red, for what needs attention such as blackhole routes
+func highlighted(st *xlsx.Style) *xlsx.Style {
+	st.Font = *xlsx.DefaultFont()
+	st.Font.Color = "FF9C0006"
+	st.ApplyFont = true
+	st.Fill = xlsx.Fill{PatternType: "solid", FgColor: "FFFFC7CE", BgColor: "FFFFC7CE"}
+	st.ApplyFill = true
+	return st
+}
+
+// routeStyle border style of a route cell, highlighted for a blackhole
+func routeStyle(r *Route, lrtb string) *xlsx.Style {
+	if r.IsBlackhole() {
+		return highlighted(borderWithAlign(lrtb, false))
+	}
+	return borderWithAlign(lrtb, false)
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tealeg/xlsx"
+)
+
+func TestSheetName(t *testing.T) {
+	for _, tc := range []struct {
+		parts []string
+		want  string
+	}{
+		{[]string{"sample-vpc"}, "sample-vpc"},
+		{[]string{"", "us-east-1", "sample-vpc"}, "us-east-1 sample-vpc"},
+		{[]string{"prod", "", "policy"}, "prod policy"},
+		{[]string{"a/b", "c:d"}, "a_b c_d"},
+		{[]string{"111111111111", "ap-northeast-1", "sample-vpc"}, "111111111111 ap-nort sample-vpc"},
+	} {
+		if got := sheetName(tc.parts...); got != tc.want {
+			t.Errorf("sheetName(%q) = %q, want %q", tc.parts, got, tc.want)
+		}
+	}
+}
+
+func TestAddSheet(t *testing.T) {
+	file := xlsx.NewFile()
+	long := strings.Repeat("v", sheetNameLimit)
+	for _, name := range []string{"index", "Index", long, long} {
+		if _, err := addSheet(file, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names := make([]string, 0, len(file.Sheets))
+	for _, s := range file.Sheets {
+		names = append(names, s.Name)
+	}
+	want := "index,Index~2," + long + "," + long[:sheetNameLimit-2] + "~2"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("sheets = %s, want %s", got, want)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/atsushi-ishibashi/aws-state-report/cmd"
@@ -14,23 +15,96 @@ func main() {
 	app.Flags = []cli.Flag{
 		cli.StringFlag{
 			Name:  "awsconf",
-			Usage: "~/.aws/credentialsから環境変数をセット(プロセスの間のみ)",
+			Usage: "~/.aws/config, ~/.aws/credentialsのプロファイル名(role_arn, mfa_serial, sso_*に対応)",
 		},
 		cli.StringFlag{
 			Name:  "awsregion",
-			Usage: "AWS_DEFAULT_REGIONにセット(プロセスの間のみ)",
-			Value: "ap-northeast-1",
+			Usage: "リージョン。省略時はプロファイルのリージョン、なければap-northeast-1",
+		},
+		cli.StringFlag{
+			Name:  "regions",
+			Usage: "network, sgで収集するリージョン。カンマ区切りまたはall(有効な全リージョン)",
+		},
+		cli.IntFlag{
+			Name:  "concurrency",
+			Usage: "同時に実行するAPI呼び出しの数",
+			Value: 4,
+		},
+		cli.Float64Flag{
+			Name:  "rate-limit",
+			Usage: "アカウントごとの1秒あたりのAPI呼び出し数の上限。0で無制限",
+			Value: 10,
+		},
+		cli.IntFlag{
+			Name:  "max-retries",
+			Usage: "スロットリングなどで失敗したAPI呼び出しのリトライ回数(バックオフあり)",
+			Value: 8,
+		},
+		cli.BoolFlag{
+			Name:  "continue-on-error",
+			Usage: "失敗したAPI呼び出しがあっても収集できた分を出力し、失敗はerrorsシートに記載(終了ステータス2)",
+		},
+		cli.StringFlag{
+			Name:  "endpoint-url",
+			Usage: "AWSの代わりに呼び出すエンドポイント(LocalStack, motoなど)",
+		},
+		cli.StringFlag{
+			Name:  "ec2-endpoint-url",
+			Usage: "EC2のみ呼び出すエンドポイント。endpoint-urlより優先",
+		},
+		cli.StringFlag{
+			Name:  "iam-endpoint-url",
+			Usage: "IAMのみ呼び出すエンドポイント。endpoint-urlより優先",
+		},
+		cli.StringSliceFlag{
+			Name:  "assume-role",
+			Usage: "このロールを引き受けて収集(複数指定可)。arnまたはname=arn",
+		},
+		cli.StringFlag{
+			Name:  "accounts",
+			Usage: "収集するアカウントとロールのJSONファイル",
+		},
+		cli.BoolFlag{
+			Name:  "merge-accounts",
+			Usage: "アカウントごとではなく1つのレポートにまとめて出力",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Usage: "出力形式(xlsx, json, csv, html, pdf, dot, mermaid)。省略時はxlsx",
+		},
+		cli.StringFlag{
+			Name:  "template",
+			Usage: "--formatの代わりにこのGoテンプレート(text/template, *.htmlはhtml/template)で出力",
+		},
+		cli.StringFlag{
+			Name:  "fixtures",
+			Usage: "AWSの代わりにディレクトリ内のAPIレスポンス(JSON)から読み込む",
+		},
+		cli.StringFlag{
+			Name:  "save-snapshot",
+			Usage: "収集したAPIレスポンスをJSONのスナップショットに保存",
+		},
+		cli.StringFlag{
+			Name:  "from-snapshot",
+			Usage: "AWSの代わりにスナップショットから読み込む(認証情報不要)",
 		},
 	}
 
 	networkCommand := cmd.NewNetworkCommand()
 	iamCommand := cmd.NewIAMCommand()
 	sgCommand := cmd.NewSGCommand()
+	allCommand := cmd.NewAllCommand()
+	terraformCommand := cmd.NewTerraformCommand()
 
 	app.Commands = []cli.Command{
 		networkCommand,
 		iamCommand,
 		sgCommand,
+		allCommand,
+		terraformCommand,
+	}
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	app.Run(os.Args)
 }
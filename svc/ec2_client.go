@@ -7,11 +7,23 @@ import (
 
 type EC2Client struct {
 	*ec2.EC2
+	stats *pageStats
 }
 
 func (c *EC2Client) FetchVpcs() (*ec2.DescribeVpcsOutput, error) {
 	input := &ec2.DescribeVpcsInput{}
-	return c.DescribeVpcs(input)
+	result := &ec2.DescribeVpcsOutput{}
+	var pages int
+	err := c.DescribeVpcsPages(input, func(page *ec2.DescribeVpcsOutput, lastPage bool) bool {
+		pages++
+		result.Vpcs = append(result.Vpcs, page.Vpcs...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("DescribeVpcs", pages, len(result.Vpcs))
+	return result, nil
 }
 
 func (c *EC2Client) FetchRouteTablesWithVpc(vpcID string) (*ec2.DescribeRouteTablesOutput, error) {
@@ -23,7 +35,18 @@ func (c *EC2Client) FetchRouteTablesWithVpc(vpcID string) (*ec2.DescribeRouteTab
 			},
 		},
 	}
-	return c.DescribeRouteTables(input)
+	result := &ec2.DescribeRouteTablesOutput{}
+	var pages int
+	err := c.DescribeRouteTablesPages(input, func(page *ec2.DescribeRouteTablesOutput, lastPage bool) bool {
+		pages++
+		result.RouteTables = append(result.RouteTables, page.RouteTables...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("DescribeRouteTables", pages, len(result.RouteTables))
+	return result, nil
 }
 
 func (c *EC2Client) FetchSubnetsWithVpc(vpcID string) (*ec2.DescribeSubnetsOutput, error) {
@@ -35,5 +58,231 @@ func (c *EC2Client) FetchSubnetsWithVpc(vpcID string) (*ec2.DescribeSubnetsOutpu
 			},
 		},
 	}
-	return c.DescribeSubnets(input)
+	result := &ec2.DescribeSubnetsOutput{}
+	var pages int
+	err := c.DescribeSubnetsPages(input, func(page *ec2.DescribeSubnetsOutput, lastPage bool) bool {
+		pages++
+		result.Subnets = append(result.Subnets, page.Subnets...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("DescribeSubnets", pages, len(result.Subnets))
+	return result, nil
+}
+
+// FetchManagedPrefixLists describes the prefix lists in batches of
+// filterValuesLimit
+func (c *EC2Client) FetchManagedPrefixLists(ids []*string) (*ec2.DescribeManagedPrefixListsOutput, error) {
+	result := &ec2.DescribeManagedPrefixListsOutput{}
+	var pages int
+	for start := 0; start < len(ids); start += filterValuesLimit {
+		end := start + filterValuesLimit
+		if end > len(ids) {
+			end = len(ids)
+		}
+		input := &ec2.DescribeManagedPrefixListsInput{
+			Filters: []*ec2.Filter{
+				&ec2.Filter{
+					Name:   aws.String("prefix-list-id"),
+					Values: ids[start:end],
+				},
+			},
+		}
+		err := c.DescribeManagedPrefixListsPages(input, func(page *ec2.DescribeManagedPrefixListsOutput, lastPage bool) bool {
+			pages++
+			result.PrefixLists = append(result.PrefixLists, page.PrefixLists...)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.stats.add("DescribeManagedPrefixLists", pages, len(result.PrefixLists))
+	return result, nil
+}
+
+func (c *EC2Client) FetchManagedPrefixListEntries(id string) (*ec2.GetManagedPrefixListEntriesOutput, error) {
+	input := &ec2.GetManagedPrefixListEntriesInput{
+		PrefixListId: aws.String(id),
+	}
+	result := &ec2.GetManagedPrefixListEntriesOutput{}
+	var pages int
+	err := c.GetManagedPrefixListEntriesPages(input, func(page *ec2.GetManagedPrefixListEntriesOutput, lastPage bool) bool {
+		pages++
+		result.Entries = append(result.Entries, page.Entries...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("GetManagedPrefixListEntries", pages, len(result.Entries))
+	return result, nil
+}
+
+func (c *EC2Client) FetchInternetGateways() (*ec2.DescribeInternetGatewaysOutput, error) {
+	input := &ec2.DescribeInternetGatewaysInput{}
+	result := &ec2.DescribeInternetGatewaysOutput{}
+	var pages int
+	err := c.DescribeInternetGatewaysPages(input, func(page *ec2.DescribeInternetGatewaysOutput, lastPage bool) bool {
+		pages++
+		result.InternetGateways = append(result.InternetGateways, page.InternetGateways...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("DescribeInternetGateways", pages, len(result.InternetGateways))
+	return result, nil
+}
+
+func (c *EC2Client) FetchEgressOnlyInternetGateways() (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error) {
+	input := &ec2.DescribeEgressOnlyInternetGatewaysInput{}
+	result := &ec2.DescribeEgressOnlyInternetGatewaysOutput{}
+	var pages int
+	err := c.DescribeEgressOnlyInternetGatewaysPages(input, func(page *ec2.DescribeEgressOnlyInternetGatewaysOutput, lastPage bool) bool {
+		pages++
+		result.EgressOnlyInternetGateways = append(result.EgressOnlyInternetGateways, page.EgressOnlyInternetGateways...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("DescribeEgressOnlyInternetGateways", pages, len(result.EgressOnlyInternetGateways))
+	return result, nil
+}
+
+func (c *EC2Client) FetchNatGateways() (*ec2.DescribeNatGatewaysOutput, error) {
+	input := &ec2.DescribeNatGatewaysInput{}
+	result := &ec2.DescribeNatGatewaysOutput{}
+	var pages int
+	err := c.DescribeNatGatewaysPages(input, func(page *ec2.DescribeNatGatewaysOutput, lastPage bool) bool {
+		pages++
+		result.NatGateways = append(result.NatGateways, page.NatGateways...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("DescribeNatGateways", pages, len(result.NatGateways))
+	return result, nil
+}
+
+func (c *EC2Client) FetchVpcEndpoints() (*ec2.DescribeVpcEndpointsOutput, error) {
+	input := &ec2.DescribeVpcEndpointsInput{}
+	result := &ec2.DescribeVpcEndpointsOutput{}
+	var pages int
+	err := c.DescribeVpcEndpointsPages(input, func(page *ec2.DescribeVpcEndpointsOutput, lastPage bool) bool {
+		pages++
+		result.VpcEndpoints = append(result.VpcEndpoints, page.VpcEndpoints...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("DescribeVpcEndpoints", pages, len(result.VpcEndpoints))
+	return result, nil
+}
+
+// FetchVpnGateways describes the virtual private gateways, which the API
+// returns in one response
+func (c *EC2Client) FetchVpnGateways() (*ec2.DescribeVpnGatewaysOutput, error) {
+	result, err := c.DescribeVpnGateways(&ec2.DescribeVpnGatewaysInput{})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("DescribeVpnGateways", 1, len(result.VpnGateways))
+	return result, nil
+}
+
+func (c *EC2Client) FetchVpnConnections() (*ec2.DescribeVpnConnectionsOutput, error) {
+	result, err := c.DescribeVpnConnections(&ec2.DescribeVpnConnectionsInput{})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("DescribeVpnConnections", 1, len(result.VpnConnections))
+	return result, nil
+}
+
+func (c *EC2Client) FetchCustomerGateways() (*ec2.DescribeCustomerGatewaysOutput, error) {
+	result, err := c.DescribeCustomerGateways(&ec2.DescribeCustomerGatewaysInput{})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("DescribeCustomerGateways", 1, len(result.CustomerGateways))
+	return result, nil
+}
+
+func (c *EC2Client) FetchVpcPeeringConnections() (*ec2.DescribeVpcPeeringConnectionsOutput, error) {
+	input := &ec2.DescribeVpcPeeringConnectionsInput{}
+	result := &ec2.DescribeVpcPeeringConnectionsOutput{}
+	var pages int
+	err := c.DescribeVpcPeeringConnectionsPages(input, func(page *ec2.DescribeVpcPeeringConnectionsOutput, lastPage bool) bool {
+		pages++
+		result.VpcPeeringConnections = append(result.VpcPeeringConnections, page.VpcPeeringConnections...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("DescribeVpcPeeringConnections", pages, len(result.VpcPeeringConnections))
+	return result, nil
+}
+
+func (c *EC2Client) FetchTransitGatewayAttachments() (*ec2.DescribeTransitGatewayAttachmentsOutput, error) {
+	input := &ec2.DescribeTransitGatewayAttachmentsInput{}
+	result := &ec2.DescribeTransitGatewayAttachmentsOutput{}
+	var pages int
+	err := c.DescribeTransitGatewayAttachmentsPages(input, func(page *ec2.DescribeTransitGatewayAttachmentsOutput, lastPage bool) bool {
+		pages++
+		result.TransitGatewayAttachments = append(result.TransitGatewayAttachments, page.TransitGatewayAttachments...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("DescribeTransitGatewayAttachments", pages, len(result.TransitGatewayAttachments))
+	return result, nil
+}
+
+func (c *EC2Client) FetchTransitGatewayRouteTables() (*ec2.DescribeTransitGatewayRouteTablesOutput, error) {
+	input := &ec2.DescribeTransitGatewayRouteTablesInput{}
+	result := &ec2.DescribeTransitGatewayRouteTablesOutput{}
+	var pages int
+	err := c.DescribeTransitGatewayRouteTablesPages(input, func(page *ec2.DescribeTransitGatewayRouteTablesOutput, lastPage bool) bool {
+		pages++
+		result.TransitGatewayRouteTables = append(result.TransitGatewayRouteTables, page.TransitGatewayRouteTables...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("DescribeTransitGatewayRouteTables", pages, len(result.TransitGatewayRouteTables))
+	return result, nil
+}
+
+// transitGatewayRoutesLimit most routes SearchTransitGatewayRoutes returns,
+// in one response since it has no pages
+const transitGatewayRoutesLimit = 1000
+
+// FetchTransitGatewayRoutes active and blackhole routes of the transit
+// gateway route table, up to transitGatewayRoutesLimit
+func (c *EC2Client) FetchTransitGatewayRoutes(routeTableID string) (*ec2.SearchTransitGatewayRoutesOutput, error) {
+	input := &ec2.SearchTransitGatewayRoutesInput{
+		TransitGatewayRouteTableId: aws.String(routeTableID),
+		Filters: []*ec2.Filter{
+			&ec2.Filter{
+				Name:   aws.String("state"),
+				Values: []*string{aws.String("active"), aws.String("blackhole")},
+			},
+		},
+		MaxResults: aws.Int64(transitGatewayRoutesLimit),
+	}
+	result, err := c.SearchTransitGatewayRoutes(input)
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("SearchTransitGatewayRoutes", 1, len(result.Routes))
+	return result, nil
 }
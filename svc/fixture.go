@@ -0,0 +1,479 @@
+package svc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// Responses canned API responses. Per-principal IAM responses are keyed by
+// the role, group or user name, GetPolicyVersion by policy ARN,
+// GetManagedPrefixListEntries by prefix list ID and SearchTransitGatewayRoutes
+// by transit gateway route table ID.
+// Responses that are not there read as empty.
+type Responses struct {
+	DescribeVpcs                       *ec2.DescribeVpcsOutput                           `json:",omitempty"`
+	DescribeRouteTables                *ec2.DescribeRouteTablesOutput                    `json:",omitempty"`
+	DescribeSubnets                    *ec2.DescribeSubnetsOutput                        `json:",omitempty"`
+	DescribeManagedPrefixLists         *ec2.DescribeManagedPrefixListsOutput             `json:",omitempty"`
+	GetManagedPrefixListEntries        map[string]*ec2.GetManagedPrefixListEntriesOutput `json:",omitempty"`
+	DescribeInternetGateways           *ec2.DescribeInternetGatewaysOutput               `json:",omitempty"`
+	DescribeEgressOnlyInternetGateways *ec2.DescribeEgressOnlyInternetGatewaysOutput     `json:",omitempty"`
+	DescribeNatGateways                *ec2.DescribeNatGatewaysOutput                    `json:",omitempty"`
+	DescribeVpcEndpoints               *ec2.DescribeVpcEndpointsOutput                   `json:",omitempty"`
+	DescribeVpnGateways                *ec2.DescribeVpnGatewaysOutput                    `json:",omitempty"`
+	DescribeVpnConnections             *ec2.DescribeVpnConnectionsOutput                 `json:",omitempty"`
+	DescribeCustomerGateways           *ec2.DescribeCustomerGatewaysOutput               `json:",omitempty"`
+	DescribeVpcPeeringConnections      *ec2.DescribeVpcPeeringConnectionsOutput          `json:",omitempty"`
+	DescribeTransitGatewayAttachments  *ec2.DescribeTransitGatewayAttachmentsOutput      `json:",omitempty"`
+	DescribeTransitGatewayRouteTables  *ec2.DescribeTransitGatewayRouteTablesOutput      `json:",omitempty"`
+	SearchTransitGatewayRoutes         map[string]*ec2.SearchTransitGatewayRoutesOutput  `json:",omitempty"`
+	DescribeSecurityGroups             *ec2.DescribeSecurityGroupsOutput                 `json:",omitempty"`
+	DescribeNetworkInterfaces          *ec2.DescribeNetworkInterfacesOutput              `json:",omitempty"`
+	DescribeInstances                  *ec2.DescribeInstancesOutput                      `json:",omitempty"`
+	DescribeSecurityGroupRules         *ec2.DescribeSecurityGroupRulesOutput             `json:",omitempty"`
+	ListPolicies                       *iam.ListPoliciesOutput                           `json:",omitempty"`
+	GetPolicyVersion                   map[string]*iam.GetPolicyVersionOutput            `json:",omitempty"`
+	ListRoles                          *iam.ListRolesOutput                              `json:",omitempty"`
+	ListRolePolicies                   map[string]*iam.ListRolePoliciesOutput            `json:",omitempty"`
+	ListAttachedRolePolicies           map[string]*iam.ListAttachedRolePoliciesOutput    `json:",omitempty"`
+	ListGroups                         *iam.ListGroupsOutput                             `json:",omitempty"`
+	ListGroupPolicies                  map[string]*iam.ListGroupPoliciesOutput           `json:",omitempty"`
+	ListAttachedGroupPolicies          map[string]*iam.ListAttachedGroupPoliciesOutput   `json:",omitempty"`
+	ListUsers                          *iam.ListUsersOutput                              `json:",omitempty"`
+	ListUserPolicies                   map[string]*iam.ListUserPoliciesOutput            `json:",omitempty"`
+	ListAttachedUserPolicies           map[string]*iam.ListAttachedUserPoliciesOutput    `json:",omitempty"`
+	ListGroupsForUser                  map[string]*iam.ListGroupsForUserOutput           `json:",omitempty"`
+	GetCallerIdentity                  *sts.GetCallerIdentityOutput                      `json:",omitempty"`
+	ListAccountAliases                 *iam.ListAccountAliasesOutput                     `json:",omitempty"`
+}
+
+// LoadResponses reads <dir>/<Field>.json for each field of Responses
+func LoadResponses(dir string) (*Responses, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	r := &Responses{}
+	rv := reflect.ValueOf(r).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		name := rv.Type().Field(i).Name
+		b, err := ioutil.ReadFile(filepath.Join(dir, name+".json"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, rv.Field(i).Addr().Interface()); err != nil {
+			return nil, fmt.Errorf("%s.json: %s", name, err)
+		}
+	}
+	return r, nil
+}
+
+// FixtureManager Manager that answers from canned responses instead of AWS
+type FixtureManager struct {
+	responses *Responses
+	stats     *pageStats
+}
+
+func NewFixtureManager(dir string) (*FixtureManager, error) {
+	r, err := LoadResponses(dir)
+	if err != nil {
+		return nil, err
+	}
+	return NewResponsesManager(r), nil
+}
+
+// NewResponsesManager FixtureManager over responses already in memory
+func NewResponsesManager(r *Responses) *FixtureManager {
+	if r == nil {
+		r = &Responses{}
+	}
+	return &FixtureManager{responses: r, stats: &pageStats{}}
+}
+
+func (m *FixtureManager) PageStats() []PageStat {
+	return m.stats.list()
+}
+
+func (m *FixtureManager) FetchVpcs() (*ec2.DescribeVpcsOutput, error) {
+	result := &ec2.DescribeVpcsOutput{}
+	if m.responses.DescribeVpcs != nil {
+		result.Vpcs = m.responses.DescribeVpcs.Vpcs
+	}
+	m.stats.add("DescribeVpcs", 1, len(result.Vpcs))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchRouteTablesWithVpc(vpcID string) (*ec2.DescribeRouteTablesOutput, error) {
+	result := &ec2.DescribeRouteTablesOutput{}
+	if m.responses.DescribeRouteTables != nil {
+		for _, v := range m.responses.DescribeRouteTables.RouteTables {
+			if aws.StringValue(v.VpcId) == vpcID {
+				result.RouteTables = append(result.RouteTables, v)
+			}
+		}
+	}
+	m.stats.add("DescribeRouteTables", 1, len(result.RouteTables))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchSubnetsWithVpc(vpcID string) (*ec2.DescribeSubnetsOutput, error) {
+	result := &ec2.DescribeSubnetsOutput{}
+	if m.responses.DescribeSubnets != nil {
+		for _, v := range m.responses.DescribeSubnets.Subnets {
+			if aws.StringValue(v.VpcId) == vpcID {
+				result.Subnets = append(result.Subnets, v)
+			}
+		}
+	}
+	m.stats.add("DescribeSubnets", 1, len(result.Subnets))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchManagedPrefixLists(ids []*string) (*ec2.DescribeManagedPrefixListsOutput, error) {
+	result := &ec2.DescribeManagedPrefixListsOutput{}
+	wanted := make(map[string]bool)
+	for _, v := range ids {
+		wanted[aws.StringValue(v)] = true
+	}
+	if m.responses.DescribeManagedPrefixLists != nil {
+		for _, v := range m.responses.DescribeManagedPrefixLists.PrefixLists {
+			if wanted[aws.StringValue(v.PrefixListId)] {
+				result.PrefixLists = append(result.PrefixLists, v)
+			}
+		}
+	}
+	m.stats.add("DescribeManagedPrefixLists", 1, len(result.PrefixLists))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchManagedPrefixListEntries(id string) (*ec2.GetManagedPrefixListEntriesOutput, error) {
+	result := &ec2.GetManagedPrefixListEntriesOutput{}
+	if v, ok := m.responses.GetManagedPrefixListEntries[id]; ok {
+		result.Entries = v.Entries
+	}
+	m.stats.add("GetManagedPrefixListEntries", 1, len(result.Entries))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchInternetGateways() (*ec2.DescribeInternetGatewaysOutput, error) {
+	result := &ec2.DescribeInternetGatewaysOutput{}
+	if m.responses.DescribeInternetGateways != nil {
+		result.InternetGateways = m.responses.DescribeInternetGateways.InternetGateways
+	}
+	m.stats.add("DescribeInternetGateways", 1, len(result.InternetGateways))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchEgressOnlyInternetGateways() (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error) {
+	result := &ec2.DescribeEgressOnlyInternetGatewaysOutput{}
+	if m.responses.DescribeEgressOnlyInternetGateways != nil {
+		result.EgressOnlyInternetGateways = m.responses.DescribeEgressOnlyInternetGateways.EgressOnlyInternetGateways
+	}
+	m.stats.add("DescribeEgressOnlyInternetGateways", 1, len(result.EgressOnlyInternetGateways))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchNatGateways() (*ec2.DescribeNatGatewaysOutput, error) {
+	result := &ec2.DescribeNatGatewaysOutput{}
+	if m.responses.DescribeNatGateways != nil {
+		result.NatGateways = m.responses.DescribeNatGateways.NatGateways
+	}
+	m.stats.add("DescribeNatGateways", 1, len(result.NatGateways))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchVpcEndpoints() (*ec2.DescribeVpcEndpointsOutput, error) {
+	result := &ec2.DescribeVpcEndpointsOutput{}
+	if m.responses.DescribeVpcEndpoints != nil {
+		result.VpcEndpoints = m.responses.DescribeVpcEndpoints.VpcEndpoints
+	}
+	m.stats.add("DescribeVpcEndpoints", 1, len(result.VpcEndpoints))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchVpnGateways() (*ec2.DescribeVpnGatewaysOutput, error) {
+	result := &ec2.DescribeVpnGatewaysOutput{}
+	if m.responses.DescribeVpnGateways != nil {
+		result.VpnGateways = m.responses.DescribeVpnGateways.VpnGateways
+	}
+	m.stats.add("DescribeVpnGateways", 1, len(result.VpnGateways))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchVpnConnections() (*ec2.DescribeVpnConnectionsOutput, error) {
+	result := &ec2.DescribeVpnConnectionsOutput{}
+	if m.responses.DescribeVpnConnections != nil {
+		result.VpnConnections = m.responses.DescribeVpnConnections.VpnConnections
+	}
+	m.stats.add("DescribeVpnConnections", 1, len(result.VpnConnections))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchCustomerGateways() (*ec2.DescribeCustomerGatewaysOutput, error) {
+	result := &ec2.DescribeCustomerGatewaysOutput{}
+	if m.responses.DescribeCustomerGateways != nil {
+		result.CustomerGateways = m.responses.DescribeCustomerGateways.CustomerGateways
+	}
+	m.stats.add("DescribeCustomerGateways", 1, len(result.CustomerGateways))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchVpcPeeringConnections() (*ec2.DescribeVpcPeeringConnectionsOutput, error) {
+	result := &ec2.DescribeVpcPeeringConnectionsOutput{}
+	if m.responses.DescribeVpcPeeringConnections != nil {
+		result.VpcPeeringConnections = m.responses.DescribeVpcPeeringConnections.VpcPeeringConnections
+	}
+	m.stats.add("DescribeVpcPeeringConnections", 1, len(result.VpcPeeringConnections))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchTransitGatewayAttachments() (*ec2.DescribeTransitGatewayAttachmentsOutput, error) {
+	result := &ec2.DescribeTransitGatewayAttachmentsOutput{}
+	if m.responses.DescribeTransitGatewayAttachments != nil {
+		result.TransitGatewayAttachments = m.responses.DescribeTransitGatewayAttachments.TransitGatewayAttachments
+	}
+	m.stats.add("DescribeTransitGatewayAttachments", 1, len(result.TransitGatewayAttachments))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchTransitGatewayRouteTables() (*ec2.DescribeTransitGatewayRouteTablesOutput, error) {
+	result := &ec2.DescribeTransitGatewayRouteTablesOutput{}
+	if m.responses.DescribeTransitGatewayRouteTables != nil {
+		result.TransitGatewayRouteTables = m.responses.DescribeTransitGatewayRouteTables.TransitGatewayRouteTables
+	}
+	m.stats.add("DescribeTransitGatewayRouteTables", 1, len(result.TransitGatewayRouteTables))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchTransitGatewayRoutes(routeTableID string) (*ec2.SearchTransitGatewayRoutesOutput, error) {
+	result := &ec2.SearchTransitGatewayRoutesOutput{}
+	if v, ok := m.responses.SearchTransitGatewayRoutes[routeTableID]; ok {
+		result.Routes = v.Routes
+		result.AdditionalRoutesAvailable = v.AdditionalRoutesAvailable
+	}
+	m.stats.add("SearchTransitGatewayRoutes", 1, len(result.Routes))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchSecurityGroups() (*ec2.DescribeSecurityGroupsOutput, error) {
+	result := &ec2.DescribeSecurityGroupsOutput{}
+	if m.responses.DescribeSecurityGroups != nil {
+		result.SecurityGroups = m.responses.DescribeSecurityGroups.SecurityGroups
+	}
+	m.stats.add("DescribeSecurityGroups", 1, len(result.SecurityGroups))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchNetworkInterfaces(gids []*string) (*ec2.DescribeNetworkInterfacesOutput, error) {
+	result := &ec2.DescribeNetworkInterfacesOutput{}
+	wanted := make(map[string]bool)
+	for _, v := range gids {
+		wanted[aws.StringValue(v)] = true
+	}
+	if m.responses.DescribeNetworkInterfaces != nil {
+		for _, v := range m.responses.DescribeNetworkInterfaces.NetworkInterfaces {
+			for _, g := range v.Groups {
+				if wanted[aws.StringValue(g.GroupId)] {
+					result.NetworkInterfaces = append(result.NetworkInterfaces, v)
+					break
+				}
+			}
+		}
+	}
+	m.stats.add("DescribeNetworkInterfaces", 1, len(result.NetworkInterfaces))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchSecurityGroupRules(gids []*string) (*ec2.DescribeSecurityGroupRulesOutput, error) {
+	result := &ec2.DescribeSecurityGroupRulesOutput{}
+	wanted := make(map[string]bool)
+	for _, v := range gids {
+		wanted[aws.StringValue(v)] = true
+	}
+	if m.responses.DescribeSecurityGroupRules != nil {
+		for _, v := range m.responses.DescribeSecurityGroupRules.SecurityGroupRules {
+			if wanted[aws.StringValue(v.GroupId)] {
+				result.SecurityGroupRules = append(result.SecurityGroupRules, v)
+			}
+		}
+	}
+	m.stats.add("DescribeSecurityGroupRules", 1, len(result.SecurityGroupRules))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchEc2Instances(iids []*string) (*ec2.DescribeInstancesOutput, error) {
+	result := &ec2.DescribeInstancesOutput{}
+	wanted := make(map[string]bool)
+	for _, v := range iids {
+		wanted[aws.StringValue(v)] = true
+	}
+	if m.responses.DescribeInstances != nil {
+		for _, r := range m.responses.DescribeInstances.Reservations {
+			instances := make([]*ec2.Instance, 0)
+			for _, v := range r.Instances {
+				if wanted[aws.StringValue(v.InstanceId)] {
+					instances = append(instances, v)
+				}
+			}
+			if len(instances) > 0 {
+				result.Reservations = append(result.Reservations, &ec2.Reservation{
+					ReservationId: r.ReservationId,
+					OwnerId:       r.OwnerId,
+					Instances:     instances,
+				})
+			}
+		}
+	}
+	m.stats.add("DescribeInstances", 1, len(result.Reservations))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchPolicies() (*iam.ListPoliciesOutput, error) {
+	result := &iam.ListPoliciesOutput{}
+	if m.responses.ListPolicies != nil {
+		result.Policies = m.responses.ListPolicies.Policies
+	}
+	m.stats.add("ListPolicies", 1, len(result.Policies))
+	return result, nil
+}
+
+// FetchPolicyVersion the GetPolicyVersion response for arn. Version 1
+// snapshots have none, and the document is the Description the policy was
+// listed with.
+func (m *FixtureManager) FetchPolicyVersion(arn, version *string) (*iam.GetPolicyVersionOutput, error) {
+	result := &iam.GetPolicyVersionOutput{PolicyVersion: &iam.PolicyVersion{VersionId: version}}
+	if v, ok := m.responses.GetPolicyVersion[aws.StringValue(arn)]; ok && v.PolicyVersion != nil {
+		result.PolicyVersion = v.PolicyVersion
+	} else if m.responses.ListPolicies != nil {
+		for _, p := range m.responses.ListPolicies.Policies {
+			if aws.StringValue(p.Arn) == aws.StringValue(arn) {
+				result.PolicyVersion.Document = p.Description
+			}
+		}
+	}
+	m.stats.add("GetPolicyVersion", 1, 1)
+	return result, nil
+}
+
+func (m *FixtureManager) FetchRoles() (*iam.ListRolesOutput, error) {
+	result := &iam.ListRolesOutput{}
+	if m.responses.ListRoles != nil {
+		result.Roles = m.responses.ListRoles.Roles
+	}
+	m.stats.add("ListRoles", 1, len(result.Roles))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchRolePolicies(name *string) (*iam.ListRolePoliciesOutput, error) {
+	result := &iam.ListRolePoliciesOutput{}
+	if v, ok := m.responses.ListRolePolicies[aws.StringValue(name)]; ok {
+		result.PolicyNames = v.PolicyNames
+	}
+	m.stats.add("ListRolePolicies", 1, len(result.PolicyNames))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchRoleManagedPolicies(name *string) (*iam.ListAttachedRolePoliciesOutput, error) {
+	result := &iam.ListAttachedRolePoliciesOutput{}
+	if v, ok := m.responses.ListAttachedRolePolicies[aws.StringValue(name)]; ok {
+		result.AttachedPolicies = v.AttachedPolicies
+	}
+	m.stats.add("ListAttachedRolePolicies", 1, len(result.AttachedPolicies))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchGroups() (*iam.ListGroupsOutput, error) {
+	result := &iam.ListGroupsOutput{}
+	if m.responses.ListGroups != nil {
+		result.Groups = m.responses.ListGroups.Groups
+	}
+	m.stats.add("ListGroups", 1, len(result.Groups))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchGroupPolicies(name *string) (*iam.ListGroupPoliciesOutput, error) {
+	result := &iam.ListGroupPoliciesOutput{}
+	if v, ok := m.responses.ListGroupPolicies[aws.StringValue(name)]; ok {
+		result.PolicyNames = v.PolicyNames
+	}
+	m.stats.add("ListGroupPolicies", 1, len(result.PolicyNames))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchGroupManagedPolicies(name *string) (*iam.ListAttachedGroupPoliciesOutput, error) {
+	result := &iam.ListAttachedGroupPoliciesOutput{}
+	if v, ok := m.responses.ListAttachedGroupPolicies[aws.StringValue(name)]; ok {
+		result.AttachedPolicies = v.AttachedPolicies
+	}
+	m.stats.add("ListAttachedGroupPolicies", 1, len(result.AttachedPolicies))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchUsers() (*iam.ListUsersOutput, error) {
+	result := &iam.ListUsersOutput{}
+	if m.responses.ListUsers != nil {
+		result.Users = m.responses.ListUsers.Users
+	}
+	m.stats.add("ListUsers", 1, len(result.Users))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchUserPolicies(name *string) (*iam.ListUserPoliciesOutput, error) {
+	result := &iam.ListUserPoliciesOutput{}
+	if v, ok := m.responses.ListUserPolicies[aws.StringValue(name)]; ok {
+		result.PolicyNames = v.PolicyNames
+	}
+	m.stats.add("ListUserPolicies", 1, len(result.PolicyNames))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchUserManagedPolicies(name *string) (*iam.ListAttachedUserPoliciesOutput, error) {
+	result := &iam.ListAttachedUserPoliciesOutput{}
+	if v, ok := m.responses.ListAttachedUserPolicies[aws.StringValue(name)]; ok {
+		result.AttachedPolicies = v.AttachedPolicies
+	}
+	m.stats.add("ListAttachedUserPolicies", 1, len(result.AttachedPolicies))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchUserGroups(name *string) (*iam.ListGroupsForUserOutput, error) {
+	result := &iam.ListGroupsForUserOutput{}
+	if v, ok := m.responses.ListGroupsForUser[aws.StringValue(name)]; ok {
+		result.Groups = v.Groups
+	}
+	m.stats.add("ListGroupsForUser", 1, len(result.Groups))
+	return result, nil
+}
+
+func (m *FixtureManager) FetchCallerIdentity() (*sts.GetCallerIdentityOutput, error) {
+	result := &sts.GetCallerIdentityOutput{}
+	if m.responses.GetCallerIdentity != nil {
+		result = m.responses.GetCallerIdentity
+	}
+	m.stats.add("GetCallerIdentity", 1, 1)
+	return result, nil
+}
+
+func (m *FixtureManager) FetchAccountAliases() (*iam.ListAccountAliasesOutput, error) {
+	result := &iam.ListAccountAliasesOutput{}
+	if m.responses.ListAccountAliases != nil {
+		result.AccountAliases = m.responses.ListAccountAliases.AccountAliases
+	}
+	m.stats.add("ListAccountAliases", 1, len(result.AccountAliases))
+	return result, nil
+}
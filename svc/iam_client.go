@@ -7,94 +7,221 @@ import (
 
 type IAMClient struct {
 	*iam.IAM
+	stats *pageStats
 }
 
 func (c *IAMClient) FetchRoles() (*iam.ListRolesOutput, error) {
 	input := &iam.ListRolesInput{}
-	return c.ListRoles(input)
+	result := &iam.ListRolesOutput{}
+	var pages int
+	err := c.ListRolesPages(input, func(page *iam.ListRolesOutput, lastPage bool) bool {
+		pages++
+		result.Roles = append(result.Roles, page.Roles...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("ListRoles", pages, len(result.Roles))
+	return result, nil
 }
 
 func (c *IAMClient) FetchRolePolicies(name *string) (*iam.ListRolePoliciesOutput, error) {
 	input := &iam.ListRolePoliciesInput{
 		RoleName: name,
 	}
-	return c.ListRolePolicies(input)
+	result := &iam.ListRolePoliciesOutput{}
+	var pages int
+	err := c.ListRolePoliciesPages(input, func(page *iam.ListRolePoliciesOutput, lastPage bool) bool {
+		pages++
+		result.PolicyNames = append(result.PolicyNames, page.PolicyNames...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("ListRolePolicies", pages, len(result.PolicyNames))
+	return result, nil
 }
 
 func (c *IAMClient) FetchRoleManagedPolicies(name *string) (*iam.ListAttachedRolePoliciesOutput, error) {
 	input := &iam.ListAttachedRolePoliciesInput{
 		RoleName: name,
 	}
-	return c.ListAttachedRolePolicies(input)
+	result := &iam.ListAttachedRolePoliciesOutput{}
+	var pages int
+	err := c.ListAttachedRolePoliciesPages(input, func(page *iam.ListAttachedRolePoliciesOutput, lastPage bool) bool {
+		pages++
+		result.AttachedPolicies = append(result.AttachedPolicies, page.AttachedPolicies...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("ListAttachedRolePolicies", pages, len(result.AttachedPolicies))
+	return result, nil
 }
 
 func (c *IAMClient) FetchGroups() (*iam.ListGroupsOutput, error) {
 	input := &iam.ListGroupsInput{}
-	return c.ListGroups(input)
+	result := &iam.ListGroupsOutput{}
+	var pages int
+	err := c.ListGroupsPages(input, func(page *iam.ListGroupsOutput, lastPage bool) bool {
+		pages++
+		result.Groups = append(result.Groups, page.Groups...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("ListGroups", pages, len(result.Groups))
+	return result, nil
 }
 
 func (c *IAMClient) FetchGroupPolicies(name *string) (*iam.ListGroupPoliciesOutput, error) {
 	input := &iam.ListGroupPoliciesInput{
 		GroupName: name,
 	}
-	return c.ListGroupPolicies(input)
+	result := &iam.ListGroupPoliciesOutput{}
+	var pages int
+	err := c.ListGroupPoliciesPages(input, func(page *iam.ListGroupPoliciesOutput, lastPage bool) bool {
+		pages++
+		result.PolicyNames = append(result.PolicyNames, page.PolicyNames...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("ListGroupPolicies", pages, len(result.PolicyNames))
+	return result, nil
 }
 
 func (c *IAMClient) FetchGroupManagedPolicies(name *string) (*iam.ListAttachedGroupPoliciesOutput, error) {
 	input := &iam.ListAttachedGroupPoliciesInput{
 		GroupName: name,
 	}
-	return c.ListAttachedGroupPolicies(input)
+	result := &iam.ListAttachedGroupPoliciesOutput{}
+	var pages int
+	err := c.ListAttachedGroupPoliciesPages(input, func(page *iam.ListAttachedGroupPoliciesOutput, lastPage bool) bool {
+		pages++
+		result.AttachedPolicies = append(result.AttachedPolicies, page.AttachedPolicies...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("ListAttachedGroupPolicies", pages, len(result.AttachedPolicies))
+	return result, nil
 }
 
 func (c *IAMClient) FetchUsers() (*iam.ListUsersOutput, error) {
 	input := &iam.ListUsersInput{}
-	return c.ListUsers(input)
+	result := &iam.ListUsersOutput{}
+	var pages int
+	err := c.ListUsersPages(input, func(page *iam.ListUsersOutput, lastPage bool) bool {
+		pages++
+		result.Users = append(result.Users, page.Users...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("ListUsers", pages, len(result.Users))
+	return result, nil
 }
 
 func (c *IAMClient) FetchUserPolicies(name *string) (*iam.ListUserPoliciesOutput, error) {
 	input := &iam.ListUserPoliciesInput{
 		UserName: name,
 	}
-	return c.ListUserPolicies(input)
+	result := &iam.ListUserPoliciesOutput{}
+	var pages int
+	err := c.ListUserPoliciesPages(input, func(page *iam.ListUserPoliciesOutput, lastPage bool) bool {
+		pages++
+		result.PolicyNames = append(result.PolicyNames, page.PolicyNames...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("ListUserPolicies", pages, len(result.PolicyNames))
+	return result, nil
 }
 
 func (c *IAMClient) FetchUserManagedPolicies(name *string) (*iam.ListAttachedUserPoliciesOutput, error) {
 	input := &iam.ListAttachedUserPoliciesInput{
 		UserName: name,
 	}
-	return c.ListAttachedUserPolicies(input)
+	result := &iam.ListAttachedUserPoliciesOutput{}
+	var pages int
+	err := c.ListAttachedUserPoliciesPages(input, func(page *iam.ListAttachedUserPoliciesOutput, lastPage bool) bool {
+		pages++
+		result.AttachedPolicies = append(result.AttachedPolicies, page.AttachedPolicies...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("ListAttachedUserPolicies", pages, len(result.AttachedPolicies))
+	return result, nil
 }
 
 func (c *IAMClient) FetchUserGroups(name *string) (*iam.ListGroupsForUserOutput, error) {
 	input := &iam.ListGroupsForUserInput{
 		UserName: name,
 	}
-	return c.ListGroupsForUser(input)
+	result := &iam.ListGroupsForUserOutput{}
+	var pages int
+	err := c.ListGroupsForUserPages(input, func(page *iam.ListGroupsForUserOutput, lastPage bool) bool {
+		pages++
+		result.Groups = append(result.Groups, page.Groups...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("ListGroupsForUser", pages, len(result.Groups))
+	return result, nil
 }
 
 func (c *IAMClient) FetchPolicies() (*iam.ListPoliciesOutput, error) {
 	input := &iam.ListPoliciesInput{
 		OnlyAttached: aws.Bool(true),
 	}
-	result, err := c.ListPolicies(input)
+	result := &iam.ListPoliciesOutput{}
+	var pages int
+	err := c.ListPoliciesPages(input, func(page *iam.ListPoliciesOutput, lastPage bool) bool {
+		pages++
+		result.Policies = append(result.Policies, page.Policies...)
+		return true
+	})
 	if err != nil {
 		return nil, err
 	}
-	for _, v := range result.Policies {
-		gpvResult, err := c.fetchPolicyVersion(v.Arn, v.DefaultVersionId)
-		if err != nil {
-			return nil, err
-		}
-		v.Description = gpvResult.PolicyVersion.Document
-	}
+	c.stats.add("ListPolicies", pages, len(result.Policies))
 	return result, nil
 }
 
-func (c *IAMClient) fetchPolicyVersion(arn, version *string) (*iam.GetPolicyVersionOutput, error) {
+// FetchPolicyVersion version of the policy, its document URL-encoded
+func (c *IAMClient) FetchPolicyVersion(arn, version *string) (*iam.GetPolicyVersionOutput, error) {
 	input := &iam.GetPolicyVersionInput{
 		PolicyArn: arn,
 		VersionId: version,
 	}
-	return c.GetPolicyVersion(input)
+	result, err := c.GetPolicyVersion(input)
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("GetPolicyVersion", 1, 1)
+	return result, nil
+}
+
+// FetchAccountAliases alias of the account; IAM allows at most one
+func (c *IAMClient) FetchAccountAliases() (*iam.ListAccountAliasesOutput, error) {
+	result, err := c.ListAccountAliases(&iam.ListAccountAliasesInput{})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("ListAccountAliases", 1, len(result.AccountAliases))
+	return result, nil
 }
@@ -1,29 +1,213 @@
 package svc
 
 import (
+	"fmt"
 	"os"
+	"sort"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/sts"
 )
 
-type Manager struct {
+// NetworkFetcher fetches what the network report is built from
+type NetworkFetcher interface {
+	FetchVpcs() (*ec2.DescribeVpcsOutput, error)
+	FetchRouteTablesWithVpc(vpcID string) (*ec2.DescribeRouteTablesOutput, error)
+	FetchSubnetsWithVpc(vpcID string) (*ec2.DescribeSubnetsOutput, error)
+	FetchManagedPrefixLists(ids []*string) (*ec2.DescribeManagedPrefixListsOutput, error)
+	FetchManagedPrefixListEntries(id string) (*ec2.GetManagedPrefixListEntriesOutput, error)
+	FetchInternetGateways() (*ec2.DescribeInternetGatewaysOutput, error)
+	FetchEgressOnlyInternetGateways() (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error)
+	FetchNatGateways() (*ec2.DescribeNatGatewaysOutput, error)
+	FetchVpcEndpoints() (*ec2.DescribeVpcEndpointsOutput, error)
+	FetchVpnGateways() (*ec2.DescribeVpnGatewaysOutput, error)
+	FetchVpnConnections() (*ec2.DescribeVpnConnectionsOutput, error)
+	FetchCustomerGateways() (*ec2.DescribeCustomerGatewaysOutput, error)
+	FetchVpcPeeringConnections() (*ec2.DescribeVpcPeeringConnectionsOutput, error)
+	FetchTransitGatewayAttachments() (*ec2.DescribeTransitGatewayAttachmentsOutput, error)
+	FetchTransitGatewayRouteTables() (*ec2.DescribeTransitGatewayRouteTablesOutput, error)
+	FetchTransitGatewayRoutes(routeTableID string) (*ec2.SearchTransitGatewayRoutesOutput, error)
+}
+
+// IAMFetcher fetches what the iam report is built from
+type IAMFetcher interface {
+	FetchPolicies() (*iam.ListPoliciesOutput, error)
+	FetchPolicyVersion(arn, version *string) (*iam.GetPolicyVersionOutput, error)
+	FetchRoles() (*iam.ListRolesOutput, error)
+	FetchRolePolicies(name *string) (*iam.ListRolePoliciesOutput, error)
+	FetchRoleManagedPolicies(name *string) (*iam.ListAttachedRolePoliciesOutput, error)
+	FetchGroups() (*iam.ListGroupsOutput, error)
+	FetchGroupPolicies(name *string) (*iam.ListGroupPoliciesOutput, error)
+	FetchGroupManagedPolicies(name *string) (*iam.ListAttachedGroupPoliciesOutput, error)
+	FetchUsers() (*iam.ListUsersOutput, error)
+	FetchUserPolicies(name *string) (*iam.ListUserPoliciesOutput, error)
+	FetchUserManagedPolicies(name *string) (*iam.ListAttachedUserPoliciesOutput, error)
+	FetchUserGroups(name *string) (*iam.ListGroupsForUserOutput, error)
+}
+
+// SGFetcher fetches what the sg report is built from
+type SGFetcher interface {
+	FetchSecurityGroups() (*ec2.DescribeSecurityGroupsOutput, error)
+	FetchNetworkInterfaces(gids []*string) (*ec2.DescribeNetworkInterfacesOutput, error)
+	FetchEc2Instances(iids []*string) (*ec2.DescribeInstancesOutput, error)
+	FetchSecurityGroupRules(gids []*string) (*ec2.DescribeSecurityGroupRulesOutput, error)
+}
+
+// AccountFetcher fetches which account the calls go to, for the index of
+// the all report
+type AccountFetcher interface {
+	FetchCallerIdentity() (*sts.GetCallerIdentityOutput, error)
+	FetchAccountAliases() (*iam.ListAccountAliasesOutput, error)
+}
+
+// Manager is what the commands collect from, either AWS or fixtures
+type Manager interface {
+	NetworkFetcher
+	IAMFetcher
+	SGFetcher
+	AccountFetcher
+	PageStats() []PageStat
+}
+
+// AWSManager Manager backed by the AWS APIs
+type AWSManager struct {
 	*EC2Client
 	*IAMClient
 	*SGClient
+	*STSClient
+	stats *pageStats
+}
+
+// Config how to reach one account
+type Config struct {
+	// Profile named profile in ~/.aws/config and ~/.aws/credentials, the
+	// default chain when empty
+	Profile string
+	// RoleARN role assumed for every call, if set
+	RoleARN    string
+	ExternalID string
+	// RateLimit requests per second started with the session, unlimited
+	// when 0. Retries count too.
+	RateLimit float64
+	// MaxRetries retries of a throttled or failed request, with backoff
+	MaxRetries int
+	// Endpoint URL every service is called at instead of AWS, e.g. an
+	// emulator like LocalStack or moto
+	Endpoint string
+	// EC2Endpoint and IAMEndpoint override Endpoint for one service
+	EC2Endpoint string
+	IAMEndpoint string
+}
+
+// Session AWS session for one account
+type Session struct {
+	*session.Session
+	cfg *Config
 }
 
-func NewManager() (*Manager, error) {
-	awsregion := os.Getenv("AWS_DEFAULT_REGION")
-	sess, err := session.NewSession()
+const (
+	roleSessionName  = "aws-state-report"
+	minThrottleDelay = 500 * time.Millisecond
+	maxThrottleDelay = 30 * time.Second
+)
+
+// NewSession session from the shared config and credentials files, so
+// profiles with role_arn/source_profile, mfa_serial and sso_* work. MFA
+// token codes are read from stdin, prompted for on stderr.
+func NewSession(cfg *Config) (*Session, error) {
+	var awsCfg aws.Config
+	if cfg.Endpoint != "" {
+		awsCfg.Endpoint = aws.String(cfg.Endpoint)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:                  awsCfg,
+		Profile:                 cfg.Profile,
+		SharedConfigState:       session.SharedConfigEnable,
+		AssumeRoleTokenProvider: stderrTokenProvider,
+	})
 	if err != nil {
 		return nil, err
 	}
-	m := &Manager{}
-	m.EC2Client = &EC2Client{EC2: ec2.New(sess, &aws.Config{Region: aws.String(awsregion)})}
-	m.IAMClient = &IAMClient{IAM: iam.New(sess, &aws.Config{Region: aws.String(awsregion)})}
-	m.SGClient = &SGClient{EC2: ec2.New(sess, &aws.Config{Region: aws.String(awsregion)})}
+	if cfg.RoleARN != "" {
+		creds := stscreds.NewCredentials(sess, cfg.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+			p.RoleSessionName = roleSessionName
+			if cfg.ExternalID != "" {
+				p.ExternalID = aws.String(cfg.ExternalID)
+			}
+		})
+		sess = sess.Copy(&aws.Config{Credentials: creds})
+	}
+	sess = sess.Copy(request.WithRetryer(&aws.Config{}, client.DefaultRetryer{
+		NumMaxRetries:    cfg.MaxRetries,
+		MinThrottleDelay: minThrottleDelay,
+		MaxThrottleDelay: maxThrottleDelay,
+	}))
+	if cfg.RateLimit > 0 {
+		limiter := newRateLimiter(cfg.RateLimit)
+		sess.Handlers.Send.PushFrontNamed(request.NamedHandler{
+			Name: "aws-state-report.RateLimit",
+			Fn: func(r *request.Request) {
+				limiter.wait()
+			},
+		})
+	}
+	return &Session{Session: sess, cfg: cfg}, nil
+}
+
+// stderrTokenProvider stscreds.StdinTokenProvider prompting on stderr, so
+// nothing but the report goes to stdout
+func stderrTokenProvider() (string, error) {
+	var v string
+	fmt.Fprint(os.Stderr, "Assume Role MFA token code: ")
+	_, err := fmt.Scanln(&v)
+	return v, err
+}
+
+// Region region of the profile or environment, empty when neither has one
+func (s *Session) Region() string {
+	return aws.StringValue(s.Config.Region)
+}
+
+// clientConfig config of a client in region, at endpoint when one is given
+func clientConfig(region, endpoint string) *aws.Config {
+	cfg := &aws.Config{Region: aws.String(region)}
+	if endpoint != "" {
+		cfg.Endpoint = aws.String(endpoint)
+	}
+	return cfg
+}
+
+func NewManager(sess *Session, region string) (Manager, error) {
+	m := &AWSManager{stats: &pageStats{}}
+	m.EC2Client = &EC2Client{EC2: ec2.New(sess, clientConfig(region, sess.cfg.EC2Endpoint)), stats: m.stats}
+	m.IAMClient = &IAMClient{IAM: iam.New(sess, clientConfig(region, sess.cfg.IAMEndpoint)), stats: m.stats}
+	m.SGClient = &SGClient{EC2: ec2.New(sess, clientConfig(region, sess.cfg.EC2Endpoint)), stats: m.stats}
+	m.STSClient = &STSClient{STS: sts.New(sess, clientConfig(region, "")), stats: m.stats}
 	return m, nil
 }
+
+// FetchRegions names of the regions enabled for the account, asked in region
+func FetchRegions(sess *Session, region string) ([]string, error) {
+	result, err := ec2.New(sess, clientConfig(region, sess.cfg.EC2Endpoint)).DescribeRegions(&ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, err
+	}
+	regions := make([]string, 0, len(result.Regions))
+	for _, v := range result.Regions {
+		regions = append(regions, *v.RegionName)
+	}
+	sort.Strings(regions)
+	return regions, nil
+}
+
+// PageStats pages and items collected so far, in the order of first call
+func (m *AWSManager) PageStats() []PageStat {
+	return m.stats.list()
+}
@@ -0,0 +1,38 @@
+package svc
+
+import "sync"
+
+// PageStat pages and items collected by one kind of API call
+type PageStat struct {
+	Operation string
+	Pages     int
+	Items     int
+}
+
+type pageStats struct {
+	mu    sync.Mutex
+	stats []*PageStat
+}
+
+func (ps *pageStats) add(operation string, pages, items int) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for _, v := range ps.stats {
+		if v.Operation == operation {
+			v.Pages += pages
+			v.Items += items
+			return
+		}
+	}
+	ps.stats = append(ps.stats, &PageStat{Operation: operation, Pages: pages, Items: items})
+}
+
+func (ps *pageStats) list() []PageStat {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	result := make([]PageStat, 0, len(ps.stats))
+	for _, v := range ps.stats {
+		result = append(result, *v)
+	}
+	return result
+}
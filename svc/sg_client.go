@@ -7,28 +7,122 @@ import (
 
 type SGClient struct {
 	*ec2.EC2
+	stats *pageStats
 }
 
 func (c *SGClient) FetchSecurityGroups() (*ec2.DescribeSecurityGroupsOutput, error) {
 	input := &ec2.DescribeSecurityGroupsInput{}
-	return c.DescribeSecurityGroups(input)
+	result := &ec2.DescribeSecurityGroupsOutput{}
+	var pages int
+	err := c.DescribeSecurityGroupsPages(input, func(page *ec2.DescribeSecurityGroupsOutput, lastPage bool) bool {
+		pages++
+		result.SecurityGroups = append(result.SecurityGroups, page.SecurityGroups...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("DescribeSecurityGroups", pages, len(result.SecurityGroups))
+	return result, nil
 }
 
+// filterValuesLimit max number of values EC2 accepts in one filter
+const filterValuesLimit = 200
+
 func (c *SGClient) FetchNetworkInterfaces(gids []*string) (*ec2.DescribeNetworkInterfacesOutput, error) {
-	input := &ec2.DescribeNetworkInterfacesInput{
-		Filters: []*ec2.Filter{
-			&ec2.Filter{
-				Name:   aws.String("group-id"),
-				Values: gids,
+	result := &ec2.DescribeNetworkInterfacesOutput{}
+	encountered := make(map[string]bool)
+	var pages int
+	for start := 0; start < len(gids); start += filterValuesLimit {
+		end := start + filterValuesLimit
+		if end > len(gids) {
+			end = len(gids)
+		}
+		input := &ec2.DescribeNetworkInterfacesInput{
+			Filters: []*ec2.Filter{
+				&ec2.Filter{
+					Name:   aws.String("group-id"),
+					Values: gids[start:end],
+				},
 			},
-		},
+		}
+		err := c.DescribeNetworkInterfacesPages(input, func(page *ec2.DescribeNetworkInterfacesOutput, lastPage bool) bool {
+			pages++
+			for _, v := range page.NetworkInterfaces {
+				if encountered[*v.NetworkInterfaceId] {
+					continue
+				}
+				encountered[*v.NetworkInterfaceId] = true
+				result.NetworkInterfaces = append(result.NetworkInterfaces, v)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
-	return c.DescribeNetworkInterfaces(input)
+	c.stats.add("DescribeNetworkInterfaces", pages, len(result.NetworkInterfaces))
+	return result, nil
 }
 
-func (c *SGClient) FetchEc2Instance(iid *string) (*ec2.DescribeInstancesOutput, error) {
-	input := &ec2.DescribeInstancesInput{
-		InstanceIds: []*string{iid},
+// FetchSecurityGroupRules describes the rules of the groups in batches of
+// filterValuesLimit
+func (c *SGClient) FetchSecurityGroupRules(gids []*string) (*ec2.DescribeSecurityGroupRulesOutput, error) {
+	result := &ec2.DescribeSecurityGroupRulesOutput{}
+	var pages int
+	for start := 0; start < len(gids); start += filterValuesLimit {
+		end := start + filterValuesLimit
+		if end > len(gids) {
+			end = len(gids)
+		}
+		input := &ec2.DescribeSecurityGroupRulesInput{
+			Filters: []*ec2.Filter{
+				&ec2.Filter{
+					Name:   aws.String("group-id"),
+					Values: gids[start:end],
+				},
+			},
+		}
+		err := c.DescribeSecurityGroupRulesPages(input, func(page *ec2.DescribeSecurityGroupRulesOutput, lastPage bool) bool {
+			pages++
+			result.SecurityGroupRules = append(result.SecurityGroupRules, page.SecurityGroupRules...)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.stats.add("DescribeSecurityGroupRules", pages, len(result.SecurityGroupRules))
+	return result, nil
+}
+
+// FetchEc2Instances describes the instances in batches of filterValuesLimit.
+// Instances that no longer exist are left out rather than failing the call.
+func (c *SGClient) FetchEc2Instances(iids []*string) (*ec2.DescribeInstancesOutput, error) {
+	result := &ec2.DescribeInstancesOutput{}
+	var pages int
+	for start := 0; start < len(iids); start += filterValuesLimit {
+		end := start + filterValuesLimit
+		if end > len(iids) {
+			end = len(iids)
+		}
+		input := &ec2.DescribeInstancesInput{
+			Filters: []*ec2.Filter{
+				&ec2.Filter{
+					Name:   aws.String("instance-id"),
+					Values: iids[start:end],
+				},
+			},
+		}
+		err := c.DescribeInstancesPages(input, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			pages++
+			result.Reservations = append(result.Reservations, page.Reservations...)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
-	return c.DescribeInstances(input)
+	c.stats.add("DescribeInstances", pages, len(result.Reservations))
+	return result, nil
 }
@@ -0,0 +1,563 @@
+package svc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// SnapshotVersion version of the snapshot file this build writes and the
+// newest one it reads. Version 1 recorded no GetPolicyVersion, the policy
+// documents being in the Description of ListPolicies instead.
+const SnapshotVersion = 2
+
+// Snapshot raw responses of one or more collections, saved as JSON
+type Snapshot struct {
+	Version     int           `json:"version"`
+	CreatedAt   time.Time     `json:"created_at"`
+	Collections []*Collection `json:"collections"`
+}
+
+// Collection responses collected from one region of one account
+type Collection struct {
+	Account   string     `json:"account,omitempty"`
+	Region    string     `json:"region,omitempty"`
+	Responses *Responses `json:"responses"`
+}
+
+func NewSnapshot() *Snapshot {
+	return &Snapshot{
+		Version:     SnapshotVersion,
+		CreatedAt:   time.Now().UTC(),
+		Collections: make([]*Collection, 0),
+	}
+}
+
+func ReadSnapshot(path string) (*Snapshot, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	if s.Version < 1 || s.Version > SnapshotVersion {
+		return nil, fmt.Errorf("%s: unsupported snapshot version %d, this build reads up to %d", path, s.Version, SnapshotVersion)
+	}
+	return s, nil
+}
+
+func (s *Snapshot) Write(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// RecordingManager Manager that keeps every response passing through it
+type RecordingManager struct {
+	Manager
+	mu        sync.Mutex
+	responses *Responses
+}
+
+func NewRecordingManager(m Manager) *RecordingManager {
+	return &RecordingManager{Manager: m, responses: &Responses{}}
+}
+
+// Responses what has been recorded so far
+func (m *RecordingManager) Responses() *Responses {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.responses
+}
+
+func (m *RecordingManager) FetchVpcs() (*ec2.DescribeVpcsOutput, error) {
+	result, err := m.Manager.FetchVpcs()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.DescribeVpcs = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchRouteTablesWithVpc(vpcID string) (*ec2.DescribeRouteTablesOutput, error) {
+	result, err := m.Manager.FetchRouteTablesWithVpc(vpcID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses.DescribeRouteTables == nil {
+		m.responses.DescribeRouteTables = &ec2.DescribeRouteTablesOutput{}
+	}
+	for _, v := range result.RouteTables {
+		if v.VpcId == nil {
+			v.VpcId = aws.String(vpcID)
+		}
+	}
+	m.responses.DescribeRouteTables.RouteTables = append(m.responses.DescribeRouteTables.RouteTables, result.RouteTables...)
+	return result, nil
+}
+
+func (m *RecordingManager) FetchSubnetsWithVpc(vpcID string) (*ec2.DescribeSubnetsOutput, error) {
+	result, err := m.Manager.FetchSubnetsWithVpc(vpcID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses.DescribeSubnets == nil {
+		m.responses.DescribeSubnets = &ec2.DescribeSubnetsOutput{}
+	}
+	for _, v := range result.Subnets {
+		if v.VpcId == nil {
+			v.VpcId = aws.String(vpcID)
+		}
+	}
+	m.responses.DescribeSubnets.Subnets = append(m.responses.DescribeSubnets.Subnets, result.Subnets...)
+	return result, nil
+}
+
+func (m *RecordingManager) FetchManagedPrefixLists(ids []*string) (*ec2.DescribeManagedPrefixListsOutput, error) {
+	result, err := m.Manager.FetchManagedPrefixLists(ids)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses.DescribeManagedPrefixLists == nil {
+		m.responses.DescribeManagedPrefixLists = &ec2.DescribeManagedPrefixListsOutput{}
+	}
+	recorded := make(map[string]bool)
+	for _, v := range m.responses.DescribeManagedPrefixLists.PrefixLists {
+		recorded[aws.StringValue(v.PrefixListId)] = true
+	}
+	for _, v := range result.PrefixLists {
+		if !recorded[aws.StringValue(v.PrefixListId)] {
+			recorded[aws.StringValue(v.PrefixListId)] = true
+			m.responses.DescribeManagedPrefixLists.PrefixLists = append(m.responses.DescribeManagedPrefixLists.PrefixLists, v)
+		}
+	}
+	return result, nil
+}
+
+func (m *RecordingManager) FetchManagedPrefixListEntries(id string) (*ec2.GetManagedPrefixListEntriesOutput, error) {
+	result, err := m.Manager.FetchManagedPrefixListEntries(id)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses.GetManagedPrefixListEntries == nil {
+		m.responses.GetManagedPrefixListEntries = make(map[string]*ec2.GetManagedPrefixListEntriesOutput)
+	}
+	m.responses.GetManagedPrefixListEntries[id] = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchInternetGateways() (*ec2.DescribeInternetGatewaysOutput, error) {
+	result, err := m.Manager.FetchInternetGateways()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.DescribeInternetGateways = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchEgressOnlyInternetGateways() (*ec2.DescribeEgressOnlyInternetGatewaysOutput, error) {
+	result, err := m.Manager.FetchEgressOnlyInternetGateways()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.DescribeEgressOnlyInternetGateways = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchNatGateways() (*ec2.DescribeNatGatewaysOutput, error) {
+	result, err := m.Manager.FetchNatGateways()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.DescribeNatGateways = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchVpcEndpoints() (*ec2.DescribeVpcEndpointsOutput, error) {
+	result, err := m.Manager.FetchVpcEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.DescribeVpcEndpoints = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchVpnGateways() (*ec2.DescribeVpnGatewaysOutput, error) {
+	result, err := m.Manager.FetchVpnGateways()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.DescribeVpnGateways = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchVpnConnections() (*ec2.DescribeVpnConnectionsOutput, error) {
+	result, err := m.Manager.FetchVpnConnections()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.DescribeVpnConnections = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchCustomerGateways() (*ec2.DescribeCustomerGatewaysOutput, error) {
+	result, err := m.Manager.FetchCustomerGateways()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.DescribeCustomerGateways = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchVpcPeeringConnections() (*ec2.DescribeVpcPeeringConnectionsOutput, error) {
+	result, err := m.Manager.FetchVpcPeeringConnections()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.DescribeVpcPeeringConnections = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchTransitGatewayAttachments() (*ec2.DescribeTransitGatewayAttachmentsOutput, error) {
+	result, err := m.Manager.FetchTransitGatewayAttachments()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.DescribeTransitGatewayAttachments = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchTransitGatewayRouteTables() (*ec2.DescribeTransitGatewayRouteTablesOutput, error) {
+	result, err := m.Manager.FetchTransitGatewayRouteTables()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.DescribeTransitGatewayRouteTables = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchTransitGatewayRoutes(routeTableID string) (*ec2.SearchTransitGatewayRoutesOutput, error) {
+	result, err := m.Manager.FetchTransitGatewayRoutes(routeTableID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses.SearchTransitGatewayRoutes == nil {
+		m.responses.SearchTransitGatewayRoutes = make(map[string]*ec2.SearchTransitGatewayRoutesOutput)
+	}
+	m.responses.SearchTransitGatewayRoutes[routeTableID] = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchSecurityGroups() (*ec2.DescribeSecurityGroupsOutput, error) {
+	result, err := m.Manager.FetchSecurityGroups()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.DescribeSecurityGroups = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchNetworkInterfaces(gids []*string) (*ec2.DescribeNetworkInterfacesOutput, error) {
+	result, err := m.Manager.FetchNetworkInterfaces(gids)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses.DescribeNetworkInterfaces == nil {
+		m.responses.DescribeNetworkInterfaces = &ec2.DescribeNetworkInterfacesOutput{}
+	}
+	recorded := make(map[string]bool)
+	for _, v := range m.responses.DescribeNetworkInterfaces.NetworkInterfaces {
+		recorded[aws.StringValue(v.NetworkInterfaceId)] = true
+	}
+	for _, v := range result.NetworkInterfaces {
+		if !recorded[aws.StringValue(v.NetworkInterfaceId)] {
+			recorded[aws.StringValue(v.NetworkInterfaceId)] = true
+			m.responses.DescribeNetworkInterfaces.NetworkInterfaces = append(m.responses.DescribeNetworkInterfaces.NetworkInterfaces, v)
+		}
+	}
+	return result, nil
+}
+
+func (m *RecordingManager) FetchSecurityGroupRules(gids []*string) (*ec2.DescribeSecurityGroupRulesOutput, error) {
+	result, err := m.Manager.FetchSecurityGroupRules(gids)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses.DescribeSecurityGroupRules == nil {
+		m.responses.DescribeSecurityGroupRules = &ec2.DescribeSecurityGroupRulesOutput{}
+	}
+	recorded := make(map[string]bool)
+	for _, v := range m.responses.DescribeSecurityGroupRules.SecurityGroupRules {
+		recorded[aws.StringValue(v.SecurityGroupRuleId)] = true
+	}
+	for _, v := range result.SecurityGroupRules {
+		if !recorded[aws.StringValue(v.SecurityGroupRuleId)] {
+			recorded[aws.StringValue(v.SecurityGroupRuleId)] = true
+			m.responses.DescribeSecurityGroupRules.SecurityGroupRules = append(m.responses.DescribeSecurityGroupRules.SecurityGroupRules, v)
+		}
+	}
+	return result, nil
+}
+
+func (m *RecordingManager) FetchEc2Instances(iids []*string) (*ec2.DescribeInstancesOutput, error) {
+	result, err := m.Manager.FetchEc2Instances(iids)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses.DescribeInstances == nil {
+		m.responses.DescribeInstances = &ec2.DescribeInstancesOutput{}
+	}
+	recorded := make(map[string]bool)
+	for _, r := range m.responses.DescribeInstances.Reservations {
+		for _, v := range r.Instances {
+			recorded[aws.StringValue(v.InstanceId)] = true
+		}
+	}
+	for _, res := range result.Reservations {
+		instances := make([]*ec2.Instance, 0)
+		for _, v := range res.Instances {
+			if !recorded[aws.StringValue(v.InstanceId)] {
+				recorded[aws.StringValue(v.InstanceId)] = true
+				instances = append(instances, v)
+			}
+		}
+		if len(instances) == 0 {
+			continue
+		}
+		m.responses.DescribeInstances.Reservations = append(m.responses.DescribeInstances.Reservations, &ec2.Reservation{
+			ReservationId: res.ReservationId,
+			OwnerId:       res.OwnerId,
+			Instances:     instances,
+		})
+	}
+	return result, nil
+}
+
+func (m *RecordingManager) FetchPolicies() (*iam.ListPoliciesOutput, error) {
+	result, err := m.Manager.FetchPolicies()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.ListPolicies = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchPolicyVersion(arn, version *string) (*iam.GetPolicyVersionOutput, error) {
+	result, err := m.Manager.FetchPolicyVersion(arn, version)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses.GetPolicyVersion == nil {
+		m.responses.GetPolicyVersion = make(map[string]*iam.GetPolicyVersionOutput)
+	}
+	m.responses.GetPolicyVersion[aws.StringValue(arn)] = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchRoles() (*iam.ListRolesOutput, error) {
+	result, err := m.Manager.FetchRoles()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.ListRoles = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchRolePolicies(name *string) (*iam.ListRolePoliciesOutput, error) {
+	result, err := m.Manager.FetchRolePolicies(name)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses.ListRolePolicies == nil {
+		m.responses.ListRolePolicies = make(map[string]*iam.ListRolePoliciesOutput)
+	}
+	m.responses.ListRolePolicies[aws.StringValue(name)] = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchRoleManagedPolicies(name *string) (*iam.ListAttachedRolePoliciesOutput, error) {
+	result, err := m.Manager.FetchRoleManagedPolicies(name)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses.ListAttachedRolePolicies == nil {
+		m.responses.ListAttachedRolePolicies = make(map[string]*iam.ListAttachedRolePoliciesOutput)
+	}
+	m.responses.ListAttachedRolePolicies[aws.StringValue(name)] = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchGroups() (*iam.ListGroupsOutput, error) {
+	result, err := m.Manager.FetchGroups()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.ListGroups = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchGroupPolicies(name *string) (*iam.ListGroupPoliciesOutput, error) {
+	result, err := m.Manager.FetchGroupPolicies(name)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses.ListGroupPolicies == nil {
+		m.responses.ListGroupPolicies = make(map[string]*iam.ListGroupPoliciesOutput)
+	}
+	m.responses.ListGroupPolicies[aws.StringValue(name)] = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchGroupManagedPolicies(name *string) (*iam.ListAttachedGroupPoliciesOutput, error) {
+	result, err := m.Manager.FetchGroupManagedPolicies(name)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses.ListAttachedGroupPolicies == nil {
+		m.responses.ListAttachedGroupPolicies = make(map[string]*iam.ListAttachedGroupPoliciesOutput)
+	}
+	m.responses.ListAttachedGroupPolicies[aws.StringValue(name)] = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchUsers() (*iam.ListUsersOutput, error) {
+	result, err := m.Manager.FetchUsers()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.ListUsers = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchUserPolicies(name *string) (*iam.ListUserPoliciesOutput, error) {
+	result, err := m.Manager.FetchUserPolicies(name)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses.ListUserPolicies == nil {
+		m.responses.ListUserPolicies = make(map[string]*iam.ListUserPoliciesOutput)
+	}
+	m.responses.ListUserPolicies[aws.StringValue(name)] = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchUserManagedPolicies(name *string) (*iam.ListAttachedUserPoliciesOutput, error) {
+	result, err := m.Manager.FetchUserManagedPolicies(name)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses.ListAttachedUserPolicies == nil {
+		m.responses.ListAttachedUserPolicies = make(map[string]*iam.ListAttachedUserPoliciesOutput)
+	}
+	m.responses.ListAttachedUserPolicies[aws.StringValue(name)] = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchUserGroups(name *string) (*iam.ListGroupsForUserOutput, error) {
+	result, err := m.Manager.FetchUserGroups(name)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses.ListGroupsForUser == nil {
+		m.responses.ListGroupsForUser = make(map[string]*iam.ListGroupsForUserOutput)
+	}
+	m.responses.ListGroupsForUser[aws.StringValue(name)] = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchCallerIdentity() (*sts.GetCallerIdentityOutput, error) {
+	result, err := m.Manager.FetchCallerIdentity()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.GetCallerIdentity = result
+	return result, nil
+}
+
+func (m *RecordingManager) FetchAccountAliases() (*iam.ListAccountAliasesOutput, error) {
+	result, err := m.Manager.FetchAccountAliases()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses.ListAccountAliases = result
+	return result, nil
+}
@@ -0,0 +1,107 @@
+package svc
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+const fixtureDir = "../fixtures/sample"
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	fixtures, err := NewFixtureManager(fixtureDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := NewRecordingManager(fixtures)
+	vpcs, err := rec.FetchVpcs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rts, err := rec.FetchRouteTablesWithVpc("vpc-0a1b2c3d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies, err := rec.FetchPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policies.Policies) != 1 {
+		t.Fatalf("policies = %v", policies.Policies)
+	}
+	policy := policies.Policies[0]
+	version, err := rec.FetchPolicyVersion(policy.Arn, policy.DefaultVersionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := NewSnapshot()
+	snapshot.Collections = append(snapshot.Collections, &Collection{Account: "prod", Region: "ap-northeast-1", Responses: rec.Responses()})
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := snapshot.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Collections) != 1 || read.Collections[0].Account != "prod" || read.Collections[0].Region != "ap-northeast-1" {
+		t.Fatalf("collections = %v", read.Collections)
+	}
+	replay := NewResponsesManager(read.Collections[0].Responses)
+	if got, _ := replay.FetchVpcs(); !reflect.DeepEqual(got.Vpcs, vpcs.Vpcs) {
+		t.Errorf("vpcs = %v, want %v", got.Vpcs, vpcs.Vpcs)
+	}
+	if got, _ := replay.FetchRouteTablesWithVpc("vpc-0a1b2c3d"); !reflect.DeepEqual(got.RouteTables, rts.RouteTables) {
+		t.Errorf("route tables = %v, want %v", got.RouteTables, rts.RouteTables)
+	}
+	if got, _ := replay.FetchPolicies(); !reflect.DeepEqual(got.Policies, policies.Policies) {
+		t.Errorf("policies = %v, want %v", got.Policies, policies.Policies)
+	}
+	got, _ := replay.FetchPolicyVersion(policy.Arn, policy.DefaultVersionId)
+	doc := aws.StringValue(got.PolicyVersion.Document)
+	if doc == "" || doc != aws.StringValue(version.PolicyVersion.Document) {
+		t.Errorf("policy document = %q, want %q", doc, aws.StringValue(version.PolicyVersion.Document))
+	}
+}
+
+func TestFetchPolicyVersionVersion1(t *testing.T) {
+	// version 1 snapshots recorded the document as the policy Description
+	// and no GetPolicyVersion
+	responses := &Responses{
+		ListPolicies: &iam.ListPoliciesOutput{Policies: []*iam.Policy{{
+			PolicyName:       aws.String("ReadOnly"),
+			Arn:              aws.String("arn:aws:iam::123456789012:policy/ReadOnly"),
+			DefaultVersionId: aws.String("v1"),
+			Description:      aws.String("%7B%7D"),
+		}}},
+	}
+	m := NewResponsesManager(responses)
+	got, err := m.FetchPolicyVersion(aws.String("arn:aws:iam::123456789012:policy/ReadOnly"), aws.String("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc := aws.StringValue(got.PolicyVersion.Document); doc != "%7B%7D" {
+		t.Errorf("policy document = %q, want %q", doc, "%7B%7D")
+	}
+	got, _ = m.FetchPolicyVersion(aws.String("arn:aws:iam::123456789012:policy/Other"), aws.String("v1"))
+	if got.PolicyVersion.Document != nil {
+		t.Errorf("unknown policy document = %q, want none", aws.StringValue(got.PolicyVersion.Document))
+	}
+}
+
+func TestReadSnapshotVersion(t *testing.T) {
+	snapshot := NewSnapshot()
+	snapshot.Version = SnapshotVersion + 1
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := snapshot.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSnapshot(path); err == nil || !strings.Contains(err.Error(), "unsupported snapshot version") {
+		t.Errorf("err = %v", err)
+	}
+}
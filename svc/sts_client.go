@@ -0,0 +1,19 @@
+package svc
+
+import (
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+type STSClient struct {
+	*sts.STS
+	stats *pageStats
+}
+
+func (c *STSClient) FetchCallerIdentity() (*sts.GetCallerIdentityOutput, error) {
+	result, err := c.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, err
+	}
+	c.stats.add("GetCallerIdentity", 1, 1)
+	return result, nil
+}
@@ -2,49 +2,26 @@ package util
 
 import (
 	"fmt"
+	"io"
 	"os"
-
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/urfave/cli"
-)
-
-const (
-	accessKeyID     = "AWS_ACCESS_KEY_ID"
-	secretAccessKey = "AWS_SECRET_ACCESS_KEY"
-	defaultRegion   = "AWS_DEFAULT_REGION"
 )
 
-func ConfigAWS(c *cli.Context) error {
-	region := c.GlobalString("awsregion")
-	os.Setenv(defaultRegion, region)
-	name := c.GlobalString("awsconf")
-	if name == "" {
-		return nil
-	}
-	cred := credentials.NewSharedCredentials("", name)
-	credValue, err := cred.Get()
-	if err != nil {
-		return err
-	}
-	PrintlnGreen(fmt.Sprintf("AWS Profile Name: %s, Region: %s", name, region))
-	os.Setenv(accessKeyID, credValue.AccessKeyID)
-	os.Setenv(secretAccessKey, credValue.SecretAccessKey)
-	return nil
-}
+//Out where the Println functions write. Stderr when the report goes to stdout.
+var Out io.Writer = os.Stdout
 
 //PrintlnGreen Println in Green
 func PrintlnGreen(s string) {
-	fmt.Printf("\x1b[32m%s\x1b[0m\n", s)
+	fmt.Fprintf(Out, "\x1b[32m%s\x1b[0m\n", s)
 }
 
 //PrintlnRed Println in Red
 func PrintlnRed(s string) {
-	fmt.Printf("\x1b[31m%s\x1b[0m\n", s)
+	fmt.Fprintf(Out, "\x1b[31m%s\x1b[0m\n", s)
 }
 
 //PrintlnYellow Println in Yellow
 func PrintlnYellow(s string) {
-	fmt.Printf("\x1b[33m%s\x1b[0m\n", s)
+	fmt.Fprintf(Out, "\x1b[33m%s\x1b[0m\n", s)
 }
 
 //ErrorlnRed Error in Red